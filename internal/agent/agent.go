@@ -5,7 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"iter"
+	"log"
+	"maps"
+	"os"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"agent/internal/config"
@@ -20,13 +25,19 @@ type (
 		Content  string
 		IsError  bool
 		IsStream bool
+
+		// Structured details of a ToolMessage; Content holds the same information as text
+		ToolName   string
+		ToolArgs   map[string]interface{}
+		ToolResult string
 	}
 
 	// TokenUsage tracks token consumption for a conversation
 	TokenUsage struct {
-		InputTokens  int
-		OutputTokens int
-		TotalTokens  int
+		InputTokens   int
+		OutputTokens  int
+		TotalTokens   int
+		ContextTokens int // Size of the most recent prompt, i.e. how full the context window is
 	}
 
 	// StreamingCallback is called for each chunk of streaming content
@@ -35,30 +46,51 @@ type (
 	// ToolMessageCallback is called when a tool message is ready to display
 	ToolMessageCallback func(msg Message) error
 
-	// ThoughtMessageCallback is called when a thought message is ready to display
-	ThoughtMessageCallback func(msg Message) error
+	// ThoughtChunkCallback is called for each chunk of streaming thought text
+	ThoughtChunkCallback func(chunk string) error
 
 	// ToolConfirmationCallback is called to get user confirmation before executing a tool
-	// Returns true if the tool should be executed, false if it should be skipped
-	ToolConfirmationCallback func(toolName string, args map[string]interface{}) (bool, error)
+	// Returns true if the tool should be executed, false if it should be skipped, along
+	// with the arguments the user corrected the call to, or nil to run it as requested
+	ToolConfirmationCallback func(toolName string, args map[string]interface{}) (bool, map[string]interface{}, error)
 )
 
 const (
 	UserMessage MessageType = iota
 	AgentMessage
 	ToolMessage
-	StreamChunk
+	StreamChunk // Not returned by ProcessMessage; streamed text is delivered via StreamingCallback
 	ThoughtMessage
 )
 
 // AgentConfig holds configuration for the agent
 type AgentConfig struct {
-	MaxOutputTokens      int32
-	Temperature          float32
-	TopK                 float32  // Changed from int32 to float32
-	TopP                 float32
-	ThinkingBudget       int32 // -1 for unlimited
-	SupportedThinkingModels []string // Models that support thinking mode
+	MaxOutputTokens         int32
+	Temperature             float32
+	TopK                    float32 // Changed from int32 to float32
+	TopP                    float32
+	ThinkingBudget          int32          // -1 for unlimited
+	SupportedThinkingModels []string       // Models that support thinking mode
+	MaxToolResultChars      int            // Tool results longer than this are condensed before being sent to the model; 0 disables
+	ToolResultLimits        map[string]int // Per-tool overrides for MaxToolResultChars
+	DebugLog                *log.Logger    // When set, requests, tool calls and finish reasons are logged here with secrets redacted
+	PostEditCommand         string         // Shell command run after each successful file edit, e.g. "go build ./..."; empty disables
+	MaxToolIterations       int            // Model turns allowed per user message before the tool loop is stopped; 0 disables
+	SystemPromptOverride    string         // Replaces the embedded system prompt when set, e.g. from .code-agent/SYSTEM.md
+	TrimTokenThreshold      int            // Conversation size in tokens that triggers summarizing older turns; 0 uses 75% of the context window, negative disables
+	TrimKeepTurns           int            // Most recent user turns kept verbatim when trimming
+	SummaryModel            string         // Model used to summarize trimmed turns; empty uses the current model
+	DryRun                  bool           // Tools that change files describe the change instead of making it
+	MaxTotalTokens          int            // Session token budget; requests that would take TokenUsage.TotalTokens past it aren't sent. 0 disables
+}
+
+// clone returns a copy of the config that shares no slices or maps with it, so one
+// session changing its settings doesn't affect another
+func (c *AgentConfig) clone() *AgentConfig {
+	copied := *c
+	copied.SupportedThinkingModels = slices.Clone(c.SupportedThinkingModels)
+	copied.ToolResultLimits = maps.Clone(c.ToolResultLimits)
+	return &copied
 }
 
 // DefaultAgentConfig returns sensible defaults
@@ -66,7 +98,7 @@ func DefaultAgentConfig() *AgentConfig {
 	return &AgentConfig{
 		MaxOutputTokens: 8192, // Increased from 1024 for better responses
 		Temperature:     0.7,
-		TopK:            40,   // This is still valid as a float32
+		TopK:            40, // This is still valid as a float32
 		TopP:            0.95,
 		ThinkingBudget:  -1, // Unlimited by default
 		SupportedThinkingModels: []string{
@@ -75,26 +107,72 @@ func DefaultAgentConfig() *AgentConfig {
 			"gemini-2.5-flash-lite",
 			// Add new models here as they support thinking
 		},
+		MaxToolResultChars: 20000,
+		ToolResultLimits:   map[string]int{},
+		MaxToolIterations:  25,
+		TrimKeepTurns:      4,
+		SummaryModel:       "gemini-2.5-flash-lite",
 	}
 }
 
 // Agent represents the main AI agent that can execute tools
 type Agent struct {
 	client       *genai.Client
+	api          modelAPI // the client's Models, or a fake in tests
 	Model        string
 	tools        []ToolDefinition
 	Conversation []*genai.Content
 	TokenUsage   TokenUsage
 	functions    []*genai.FunctionDeclaration // Pre-computed function declarations
 	config       *AgentConfig
+	contextFiles []contextFile // Files injected into the conversation by the user
+	vars         SessionVars   // Values tools stash with set_var for the rest of the conversation
+
+	planMode          bool                         // Restricts the agent to read-only tools while it drafts a plan
+	readOnlyFunctions []*genai.FunctionDeclaration // Function declarations offered in plan mode
+
+	stopRequested atomic.Bool // Set by the user to end the tool loop after the current iteration
+
+	tokenLimits map[string]tokenLimits // Cached per-model token caps
+	tokenCache  tokenCache             // Token count of the conversation prefix counted so far
+	summarize   Summarizer             // Condenses old turns when trimming; nil uses the summary model
+	clampWarned map[string]bool        // Models already warned about output token clamping
+}
+
+// contextFile is a file the user added to the conversation as context
+type contextFile struct {
+	path    string
+	content *genai.Content
 }
 
 // ToolDefinition defines the structure for a tool that the agent can use
 type ToolDefinition struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"input_schema"`
-	Function    func(ctx context.Context, input json.RawMessage) (string, error)
+	Name        string                                                           `json:"name"`
+	Description string                                                           `json:"description"`
+	InputSchema map[string]interface{}                                           `json:"input_schema"`
+	Function    func(ctx context.Context, input json.RawMessage) (string, error) `json:"-"`
+
+	// SkipConfirmation marks tools that only interact with the user and need no approval
+	SkipConfirmation bool `json:"-"`
+
+	// ReadOnly marks tools that never modify files or run commands; only these are offered in plan mode
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// EditsFiles marks tools that change file contents; the post-edit check runs after them
+	EditsFiles bool `json:"edits_files,omitempty"`
+
+	// Precheck reports calls the tool will refuse, such as denied shell commands, so
+	// they fail without asking the user to confirm them
+	Precheck func(input json.RawMessage) error `json:"-"`
+}
+
+// modelAPI is the part of the Gemini API the agent calls; a genai.Client's Models
+// satisfies it
+type modelAPI interface {
+	Get(ctx context.Context, model string, config *genai.GetModelConfig) (*genai.Model, error)
+	CountTokens(ctx context.Context, model string, contents []*genai.Content, config *genai.CountTokensConfig) (*genai.CountTokensResponse, error)
+	GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+	GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error]
 }
 
 // New creates a new Agent instance
@@ -105,10 +183,15 @@ func New(client *genai.Client, model string, tools []ToolDefinition) *Agent {
 // NewWithConfig creates a new Agent instance with custom configuration
 func NewWithConfig(client *genai.Client, model string, tools []ToolDefinition, config *AgentConfig) *Agent {
 	agent := &Agent{
-		client: client,
-		Model:  model,
-		tools:  tools,
-		config: config,
+		client:      client,
+		Model:       model,
+		tools:       tools,
+		config:      config,
+		tokenLimits: make(map[string]tokenLimits),
+		clampWarned: make(map[string]bool),
+	}
+	if client != nil {
+		agent.api = client.Models
 	}
 
 	// Pre-compute function declarations for efficiency
@@ -120,9 +203,15 @@ func NewWithConfig(client *genai.Client, model string, tools []ToolDefinition, c
 	return agent
 }
 
+// NewSession creates a fresh Agent with an empty conversation that shares this
+// agent's client, model, tools and configuration
+func (a *Agent) NewSession() *Agent {
+	return NewWithConfig(a.client, a.Model, a.tools, a.config.clone())
+}
+
 // precomputeFunctionDeclarations converts tool definitions to Gemini function declarations once
 func (a *Agent) precomputeFunctionDeclarations() error {
-	var functions []*genai.FunctionDeclaration
+	var functions, readOnlyFunctions []*genai.FunctionDeclaration
 	for _, tool := range a.tools {
 		// Convert map[string]interface{} to genai.Schema
 		schemaBytes, err := json.Marshal(tool.InputSchema)
@@ -135,14 +224,25 @@ func (a *Agent) precomputeFunctionDeclarations() error {
 			return fmt.Errorf("failed to unmarshal schema for tool %s: %w", tool.Name, err)
 		}
 
-		functions = append(functions, &genai.FunctionDeclaration{
+		// Gemini rejects object schemas with no properties, so omit parameters entirely
+		var parameters *genai.Schema
+		if len(schema.Properties) > 0 {
+			parameters = &schema
+		}
+
+		declaration := &genai.FunctionDeclaration{
 			Name:        tool.Name,
 			Description: tool.Description,
-			Parameters:  &schema,
-		})
+			Parameters:  parameters,
+		}
+		functions = append(functions, declaration)
+		if tool.ReadOnly {
+			readOnlyFunctions = append(readOnlyFunctions, declaration)
+		}
 	}
 
 	a.functions = functions
+	a.readOnlyFunctions = readOnlyFunctions
 	return nil
 }
 
@@ -151,12 +251,49 @@ func ptr[T any](v T) *T {
 	return &v
 }
 
+// IsThinkingSupported reports whether the current model supports thinking mode
+func (a *Agent) IsThinkingSupported() bool {
+	return a.isThinkingSupported()
+}
+
+// systemNotePrefix marks notes the agent adds to the conversation as user turns
+const systemNotePrefix = "[System note: "
+
+// isSystemNote reports whether content is a note the agent added rather than the user
+func isSystemNote(content *genai.Content) bool {
+	return len(content.Parts) > 0 && strings.HasPrefix(content.Parts[0].Text, systemNotePrefix)
+}
+
+// SwitchModel changes the active model and records the switch in the conversation
+// so the new model knows its context came from a different model
+func (a *Agent) SwitchModel(model string) {
+	previous := a.Model
+	a.Model = model
+	if previous == model || len(a.Conversation) == 0 {
+		return
+	}
+
+	thinking := "does not support"
+	if a.isThinkingSupported() {
+		thinking = "supports"
+	}
+	note := fmt.Sprintf(systemNotePrefix+"the model was switched from %s to %s mid-conversation. The new model %s thinking mode. Continue from the existing context.]",
+		previous, model, thinking)
+
+	a.Conversation = append(a.Conversation, &genai.Content{
+		Role: "user",
+		Parts: []*genai.Part{
+			{Text: note},
+		},
+	})
+}
+
 // isThinkingSupported checks if the current model supports thinking mode
 func (a *Agent) isThinkingSupported() bool {
 	if a.Model == "" {
 		return false
 	}
-	
+
 	for _, model := range a.config.SupportedThinkingModels {
 		if strings.Contains(a.Model, model) {
 			return true
@@ -165,41 +302,101 @@ func (a *Agent) isThinkingSupported() bool {
 	return false
 }
 
+// tokenLimits are a model's input and output token caps; 0 when unknown
+type tokenLimits struct {
+	input  int32
+	output int32
+}
+
+// lookupTokenLimits returns the current model's token caps, looking them up once per model
+func (a *Agent) lookupTokenLimits(ctx context.Context) tokenLimits {
+	if limits, ok := a.tokenLimits[a.Model]; ok {
+		return limits
+	}
+
+	var limits tokenLimits
+	if info, err := a.api.Get(ctx, a.Model, nil); err == nil && info != nil {
+		limits = tokenLimits{input: info.InputTokenLimit, output: info.OutputTokenLimit}
+	}
+	a.tokenLimits[a.Model] = limits
+	return limits
+}
+
+// outputTokenLimit returns the current model's output token cap
+func (a *Agent) outputTokenLimit(ctx context.Context) int32 {
+	return a.lookupTokenLimits(ctx).output
+}
+
+// Client returns the Gemini client the agent sends requests with
+func (a *Agent) Client() *genai.Client {
+	return a.client
+}
+
+// ContextWindow returns the current model's input token limit if it has already been
+// looked up, or 0. It never makes a network call, so it is safe to use while rendering.
+func (a *Agent) ContextWindow() int {
+	return int(a.tokenLimits[a.Model].input)
+}
+
+// maxOutputTokens returns the configured output token budget clamped to the model's cap
+func (a *Agent) maxOutputTokens(ctx context.Context) int32 {
+	limit := a.outputTokenLimit(ctx)
+	if limit > 0 && a.config.MaxOutputTokens > limit {
+		return limit
+	}
+	return a.config.MaxOutputTokens
+}
+
 // runInferenceStream runs the model inference and handles streaming
 func (a *Agent) runInferenceStream(ctx context.Context, conversation []*genai.Content, enableThinking bool) iter.Seq2[*genai.GenerateContentResponse, error] {
 	// Determine thinking config if applicable
 	var thinkingConfig *genai.ThinkingConfig
 	if enableThinking && a.isThinkingSupported() {
 		thinkingConfig = &genai.ThinkingConfig{
-			IncludeThoughts: true,  // Use direct bool value
+			IncludeThoughts: true, // Use direct bool value
 			ThinkingBudget:  ptr(a.config.ThinkingBudget),
 		}
 	}
 
+	functions := a.functions
+	if a.planMode {
+		functions = a.readOnlyFunctions
+	}
+
 	config := &genai.GenerateContentConfig{
 		Tools: []*genai.Tool{
 			{
-				FunctionDeclarations: a.functions,
+				FunctionDeclarations: functions,
 			},
 		},
-		MaxOutputTokens:   a.config.MaxOutputTokens,
-		Temperature:       ptr(a.config.Temperature),
-		TopK:              ptr(a.config.TopK),
-		TopP:              ptr(a.config.TopP),
+		MaxOutputTokens: a.maxOutputTokens(ctx),
+		Temperature:     ptr(a.config.Temperature),
+		TopK:            ptr(a.config.TopK),
+		TopP:            ptr(a.config.TopP),
 		SystemInstruction: &genai.Content{
 			Role: "user",
 			Parts: []*genai.Part{
-				{Text: config.SystemPrompt},
+				{Text: a.SystemPrompt()},
 			},
 		},
-		ThinkingConfig:    thinkingConfig,
+		ThinkingConfig: thinkingConfig,
 	}
 
-	return a.client.Models.GenerateContentStream(ctx, a.Model, conversation, config)
+	a.debugf("request: model=%s contents=%d tools=%d thinking=%t plan=%t max_output_tokens=%d system_prompt_chars=%d",
+		a.Model, len(conversation), len(functions), thinkingConfig != nil, a.planMode, config.MaxOutputTokens, len(config.SystemInstruction.Parts[0].Text))
+
+	return a.api.GenerateContentStream(ctx, a.Model, conversation, config)
 }
 
-// ProcessMessage handles a single user message and streams the agent's response
-func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallback StreamingCallback, toolCallback ToolMessageCallback, thoughtCallback ThoughtMessageCallback, confirmationCallback ToolConfirmationCallback, enableThinking bool) ([]Message, error) {
+// ProcessMessage handles a single user message and streams the agent's response.
+//
+// Text is delivered incrementally through textCallback only, and thoughts through
+// thoughtCallback. The returned slice is the canonical transcript of the turn: thought,
+// tool, and notice messages in the order they occurred, with each run of streamed text
+// collapsed into a single AgentMessage and each run of thoughts into a single
+// ThoughtMessage. It never contains StreamChunk messages. Images, loaded with LoadImage,
+// are sent along with userInput.
+func (a *Agent) ProcessMessage(ctx context.Context, userInput string, images []*genai.Part, textCallback StreamingCallback, toolCallback ToolMessageCallback, thoughtCallback ThoughtChunkCallback, confirmationCallback ToolConfirmationCallback, enableThinking bool) ([]Message, error) {
 	// Ensure we have a deadline on the context
 	if _, ok := ctx.Deadline(); !ok {
 		// Set a reasonable timeout if none exists
@@ -209,36 +406,119 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 	}
 
 	messages := []Message{}
-	userMessageContent := &genai.Content{
-		Role: "user",
-		Parts: []*genai.Part{
-			{Text: userInput},
-		},
+	if trimmed, err := a.TrimConversation(ctx); err != nil {
+		a.debugf("conversation trim failed: %v", err)
+	} else if trimmed {
+		messages = append(messages, Message{
+			Type:    AgentMessage,
+			Content: "[Earlier turns were summarized to stay within the context window]",
+			IsError: true,
+		})
+	}
+	if a.planMode {
+		userInput = config.PlanModeInstruction + "\n\n" + userInput
+	}
+	a.Conversation = append(a.Conversation, userContent(userInput, images))
+
+	// Warn once per model when the configured output budget exceeds what it supports
+	if limit := a.outputTokenLimit(ctx); limit > 0 && a.config.MaxOutputTokens > limit && !a.clampWarned[a.Model] {
+		a.clampWarned[a.Model] = true
+		messages = append(messages, Message{
+			Type:    AgentMessage,
+			Content: fmt.Sprintf("[Warning: MaxOutputTokens %d exceeds the %s limit of %d; clamping to %d]", a.config.MaxOutputTokens, a.Model, limit, limit),
+			IsError: true,
+		})
 	}
-	a.Conversation = append(a.Conversation, userMessageContent)
 
-	for {
+	a.stopRequested.Store(false)
+	rateLimitRetries := 0
+	postEditRuns := 0
+	for iteration := 1; ; {
 		// Check context before proceeding
 		if err := ctx.Err(); err != nil {
 			return messages, fmt.Errorf("context cancelled: %w", err)
 		}
 
+		notifyIteration(ctx, iteration)
+
 		// Count input tokens and update internal tracking
-		if inputTokens, err := a.countTokens(ctx, a.Conversation); err == nil {
-			a.TokenUsage.InputTokens += inputTokens
-			a.TokenUsage.TotalTokens += inputTokens
+		inputTokens, countErr := a.conversationTokens(ctx)
+		if limit := a.config.MaxTotalTokens; limit > 0 {
+			// Estimate the request by its input, or the last known size if counting failed
+			estimate := inputTokens
+			if countErr != nil {
+				estimate = a.TokenUsage.ContextTokens
+			}
+			if a.TokenUsage.TotalTokens+estimate > limit {
+				a.debugf("token budget reached: %d used, %d more needed, limit %d", a.TokenUsage.TotalTokens, estimate, limit)
+				if iteration == 1 {
+					// The model never saw the message; don't leave it to be answered later
+					a.Conversation = a.Conversation[:len(a.Conversation)-1]
+				}
+				messages = append(messages, Message{
+					Type:    AgentMessage,
+					Content: fmt.Sprintf("[Token budget reached: %d of %d tokens used, and the next request needs about %d more. Start a new conversation or raise AGENT_MAX_TOTAL_TOKENS to continue.]", a.TokenUsage.TotalTokens, limit, estimate),
+					IsError: true,
+				})
+				return messages, nil
+			}
+		}
+		// countInput records the request's input tokens once it has been answered, so a
+		// rate-limited attempt isn't counted twice
+		countInput := func() {
+			if countErr == nil {
+				a.TokenUsage.InputTokens += inputTokens
+				a.TokenUsage.TotalTokens += inputTokens
+				a.TokenUsage.ContextTokens = inputTokens
+			}
 		}
 
 		streamResponse := a.runInferenceStream(ctx, a.Conversation, enableThinking)
 
-		var accumulatedText string
+		var accumulatedText, accumulatedThought string
 		var accumulatedParts []*genai.Part
 		var toolResults []*genai.Part
 		processedToolCalls := make(map[string]bool)
 
+		// flushText records streamed text so far as one message, keeping transcript order
+		flushText := func() {
+			if accumulatedText != "" {
+				messages = append(messages, Message{Type: AgentMessage, Content: accumulatedText})
+				accumulatedText = ""
+			}
+		}
+
+		// flushThought does the same for streamed thoughts
+		flushThought := func() {
+			if accumulatedThought != "" {
+				messages = append(messages, Message{Type: ThoughtMessage, Content: "💭 Thinking: " + accumulatedThought})
+				accumulatedThought = ""
+			}
+		}
+
 		// Process streaming response
+		var retryAfter time.Duration
 		for chunk, err := range streamResponse {
 			if err != nil {
+				if ctx.Err() != nil {
+					// Cancelled mid-response: keep what was said and which tools ran
+					countInput()
+					flushThought()
+					flushText()
+					a.recordInterruptedResponse(accumulatedParts, toolResults)
+					return messages, fmt.Errorf("context cancelled: %w", ctx.Err())
+				}
+				if rateLimitErr, ok := a.asRateLimitError(err); ok {
+					// Only retry if nothing from this response has been acted on yet
+					canRetry := len(accumulatedParts) == 0 && len(toolResults) == 0 &&
+						rateLimitRetries < maxRateLimitRetries &&
+						rateLimitErr.RetryAfter > 0 && rateLimitErr.RetryAfter <= maxAutoRetryWait
+					if canRetry {
+						retryAfter = rateLimitErr.RetryAfter
+						break
+					}
+					return messages, rateLimitErr
+				}
 				return messages, fmt.Errorf("streaming error: %w", err)
 			}
 
@@ -247,7 +527,11 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 			}
 
 			candidate := chunk.Candidates[0]
-			
+
+			if candidate.FinishReason != "" {
+				a.debugf("finish reason: %s", string(candidate.FinishReason))
+			}
+
 			// Check for finish reason
 			if candidate.FinishReason != "" && candidate.FinishReason != "STOP" {
 				// Handle specific finish reasons
@@ -271,49 +555,54 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 
 			// Process each part in the chunk
 			for _, part := range candidate.Content.Parts {
-				// Handle thought messages immediately
-				if part.Thought && part.Text != "" {
-					thoughtMsg := Message{
-						Type:    ThoughtMessage,
-						Content: fmt.Sprintf("💭 Thinking: %s", part.Text),
+				// Stream thoughts as they arrive
+				if part.Thought {
+					if part.Text == "" {
+						continue
 					}
+					flushText()
+					accumulatedThought += part.Text
 
-					messages = append(messages, thoughtMsg)
-
-					// Send thought message immediately via callback
 					if thoughtCallback != nil {
-						if err := thoughtCallback(thoughtMsg); err != nil {
+						if err := thoughtCallback(part.Text); err != nil {
 							// Log but don't fail on callback errors
 							fmt.Printf("Warning: thought callback error: %v\n", err)
 						}
 					}
 					continue // Don't process this as regular text
 				}
+				if part.Text != "" || part.FunctionCall != nil {
+					flushThought()
+				}
 
 				// Handle tool calls immediately
 				if part.FunctionCall != nil {
-					callKey := fmt.Sprintf("%s:%v", part.FunctionCall.Name, part.FunctionCall.Args)
+					callKey := functionCallKey(part.FunctionCall)
 					if !processedToolCalls[callKey] {
 						processedToolCalls[callKey] = true
 
 						// Get user confirmation if callback is provided
-						if confirmationCallback != nil {
-							confirmed, err := confirmationCallback(part.FunctionCall.Name, part.FunctionCall.Args)
+						if confirmationCallback != nil && !a.skipsConfirmation(part.FunctionCall.Name) && !a.refuses(part.FunctionCall.Name, part.FunctionCall.Args) {
+							confirmed, editedArgs, err := confirmationCallback(part.FunctionCall.Name, part.FunctionCall.Args)
 							if err != nil {
 								return messages, fmt.Errorf("confirmation error: %w", err)
 							}
 							if !confirmed {
+								a.debugf("tool call %s rejected by user", part.FunctionCall.Name)
 								// User rejected the tool call
 								argsJSON, _ := json.Marshal(part.FunctionCall.Args)
 								toolCallInfo := fmt.Sprintf("🚫 Tool Call Rejected: %s\nArguments: %s\nReason: User denied execution",
 									part.FunctionCall.Name, string(argsJSON))
 
 								toolMsg := Message{
-									Type:    ToolMessage,
-									Content: toolCallInfo,
-									IsError: true,
+									Type:     ToolMessage,
+									Content:  toolCallInfo,
+									IsError:  true,
+									ToolName: part.FunctionCall.Name,
+									ToolArgs: part.FunctionCall.Args,
 								}
 
+								flushText()
 								messages = append(messages, toolMsg)
 
 								// Send tool message immediately via callback
@@ -330,15 +619,26 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 								})
 								continue
 							}
+							if editedArgs != nil {
+								// Run, and record in the history, the call as the user corrected it
+								a.debugf("tool call %s args edited by user", part.FunctionCall.Name)
+								part.FunctionCall.Args = editedArgs
+							}
 						}
 
 						// Execute tool and create message
+						a.debugf("tool call %s args=%s", part.FunctionCall.Name, part.FunctionCall.Args)
 						result, err := a.executeTool(ctx, part.FunctionCall.Name, part.FunctionCall.Args)
-						
+						if err != nil {
+							a.debugf("tool %s error: %s", part.FunctionCall.Name, err.Error())
+						} else {
+							a.debugf("tool %s result (%d chars): %s", part.FunctionCall.Name, len(result), truncateForDebug(result))
+						}
+
 						argsJSON, _ := json.Marshal(part.FunctionCall.Args)
 						var toolCallInfo string
 						var isError bool
-						
+
 						if err != nil {
 							toolCallInfo = fmt.Sprintf("🔧 Tool Call: %s\nArguments: %s\nError: %v",
 								part.FunctionCall.Name, string(argsJSON), err)
@@ -351,11 +651,15 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 						}
 
 						toolMsg := Message{
-							Type:    ToolMessage,
-							Content: toolCallInfo,
-							IsError: isError,
+							Type:       ToolMessage,
+							Content:    toolCallInfo,
+							IsError:    isError,
+							ToolName:   part.FunctionCall.Name,
+							ToolArgs:   part.FunctionCall.Args,
+							ToolResult: result,
 						}
 
+						flushText()
 						messages = append(messages, toolMsg)
 
 						// Send tool message immediately via callback
@@ -363,11 +667,29 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 							toolCallback(toolMsg)
 						}
 
-						// Prepare tool result for conversation
+						// Prepare tool result for conversation; the user already saw the full result
+						modelResult := a.condenseToolResult(part.FunctionCall.Name, result)
+
+						// Verify successful edits so the model sees breakage in the same turn
+						if !isError && a.editsFiles(part.FunctionCall.Name) && a.config.PostEditCommand != "" && !a.config.DryRun {
+							hookMsg := a.runPostEditHook(ctx, &postEditRuns)
+							messages = append(messages, hookMsg)
+							if toolCallback != nil {
+								toolCallback(hookMsg)
+							}
+							modelResult += "\n\n" + a.condenseToolResult(postEditHookName, hookMsg.ToolResult)
+						}
+
+						// Failures go under "error", as rejections do, so they're told apart on reload
+						responseKey := "result"
+						if isError {
+							responseKey = "error"
+							modelResult = strings.TrimPrefix(modelResult, "Error: ")
+						}
 						toolResults = append(toolResults, &genai.Part{
 							FunctionResponse: &genai.FunctionResponse{
 								Name:     part.FunctionCall.Name,
-								Response: map[string]interface{}{"result": result},
+								Response: map[string]interface{}{responseKey: modelResult},
 							},
 						})
 					}
@@ -377,13 +699,6 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 				if part.Text != "" {
 					accumulatedText += part.Text
 
-					// Stream the text chunk
-					messages = append(messages, Message{
-						Type:     StreamChunk,
-						Content:  part.Text,
-						IsStream: true,
-					})
-
 					if textCallback != nil {
 						if err := textCallback(part.Text); err != nil {
 							// Log but don't fail on callback errors
@@ -394,6 +709,24 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 			}
 		}
 
+		if retryAfter > 0 {
+			rateLimitRetries++
+			messages = append(messages, Message{
+				Type:    AgentMessage,
+				Content: fmt.Sprintf("[Rate limited by %s; retried after waiting %s]", a.Model, retryAfter.Round(time.Second)),
+				IsError: true,
+			})
+			if !waitForRetry(ctx, retryAfter) {
+				return messages, fmt.Errorf("context cancelled: %w", ctx.Err())
+			}
+			// Retry the same iteration; the rejected request used no tokens
+			continue
+		}
+
+		countInput()
+		flushThought()
+		flushText()
+
 		// Add AI response to conversation
 		aiContent := &genai.Content{
 			Role:  "model",
@@ -403,6 +736,7 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 
 		// Count output tokens and update internal tracking
 		if outputTokens, err := a.countTokens(ctx, []*genai.Content{aiContent}); err == nil {
+			a.extendTokenCache(outputTokens)
 			a.TokenUsage.OutputTokens += outputTokens
 			a.TokenUsage.TotalTokens += outputTokens
 		}
@@ -414,23 +748,146 @@ func (a *Agent) ProcessMessage(ctx context.Context, userInput string, textCallba
 				Parts: toolResults,
 			}
 			a.Conversation = append(a.Conversation, toolContent)
+
+			if a.stopRequested.Load() {
+				messages = append(messages, Message{
+					Type:    AgentMessage,
+					Content: fmt.Sprintf("[Stopped after %d tool iteration(s) at your request. Send a message to continue.]", iteration),
+					IsError: true,
+				})
+				return messages, nil
+			}
+			if limit := a.config.MaxToolIterations; limit > 0 && iteration >= limit {
+				a.debugf("tool loop stopped at the %d iteration limit", limit)
+				messages = append(messages, Message{
+					Type:    AgentMessage,
+					Content: fmt.Sprintf("[Stopped after %d tool iterations, the limit per message. Send a message to continue.]", limit),
+					IsError: true,
+				})
+				return messages, nil
+			}
+			iteration++
 			continue
 		}
 
-		// Return final agent message
-		if accumulatedText != "" {
-			messages = append(messages, Message{Type: AgentMessage, Content: accumulatedText})
+		return messages, nil
+	}
+}
+
+// SystemPrompt returns the system prompt sent with each request: the override if set,
+// otherwise the embedded prompt
+func (a *Agent) SystemPrompt() string {
+	if a.config.SystemPromptOverride != "" {
+		return a.config.SystemPromptOverride
+	}
+	return config.SystemPrompt
+}
+
+// SetSystemPromptOverride replaces the embedded system prompt; empty restores it
+func (a *Agent) SetSystemPromptOverride(prompt string) {
+	a.config.SystemPromptOverride = prompt
+}
+
+// MaxToolIterations returns how many model turns one user message may take, or 0 for no limit
+func (a *Agent) MaxToolIterations() int {
+	return a.config.MaxToolIterations
+}
+
+// functionCallKey identifies a function call by name and arguments, so a call repeated
+// in a later chunk of the same response runs once. json.Marshal sorts map keys, making
+// the key canonical for any nesting of arguments.
+func functionCallKey(call *genai.FunctionCall) string {
+	args, err := json.Marshal(call.Args)
+	if err != nil {
+		return fmt.Sprintf("%s:%v", call.Name, call.Args)
+	}
+	return call.Name + ":" + string(args)
+}
+
+// recordInterruptedResponse adds the part of a cancelled response that already
+// streamed to the conversation, keeping only tool calls that have results so the
+// model knows what it said and which tools actually ran
+func (a *Agent) recordInterruptedResponse(parts, toolResults []*genai.Part) {
+	answered := make(map[string]int)
+	for _, result := range toolResults {
+		answered[result.FunctionResponse.Name]++
+	}
+
+	var kept []*genai.Part
+	for _, part := range parts {
+		if part.FunctionCall != nil {
+			if answered[part.FunctionCall.Name] == 0 {
+				continue
+			}
+			answered[part.FunctionCall.Name]--
 		}
+		kept = append(kept, part)
+	}
+	kept = append(kept, &genai.Part{Text: "\n\n[Response interrupted by the user]"})
 
-		return messages, nil
+	a.Conversation = append(a.Conversation, &genai.Content{Role: "model", Parts: kept})
+	if len(toolResults) > 0 {
+		a.Conversation = append(a.Conversation, &genai.Content{Role: "user", Parts: toolResults})
 	}
 }
 
+// tokenCache remembers the token count of a prefix of the conversation, so each
+// request only needs to count the content appended since the last one
+type tokenCache struct {
+	model  string
+	length int
+	head   *genai.Content
+	tail   *genai.Content
+	tokens int
+}
+
+// covers reports whether the cache still describes the start of conversation. Any
+// rewrite (clearing, loading a session, removing context files) or a model switch,
+// which changes the tokenizer, invalidates it.
+func (c *tokenCache) covers(model string, conversation []*genai.Content) bool {
+	return c.length > 0 && c.model == model && c.length <= len(conversation) &&
+		conversation[0] == c.head && conversation[c.length-1] == c.tail
+}
+
+// conversationTokens returns the size of the whole conversation in tokens, counting
+// only content appended since the last call when the cached prefix is still valid
+func (a *Agent) conversationTokens(ctx context.Context) (int, error) {
+	start, tokens := 0, 0
+	if a.tokenCache.covers(a.Model, a.Conversation) {
+		start, tokens = a.tokenCache.length, a.tokenCache.tokens
+	}
+	if start < len(a.Conversation) {
+		count, err := a.countTokens(ctx, a.Conversation[start:])
+		if err != nil {
+			return 0, err
+		}
+		tokens += count
+	}
+
+	a.tokenCache = tokenCache{model: a.Model, length: len(a.Conversation), tokens: tokens}
+	if len(a.Conversation) > 0 {
+		a.tokenCache.head, a.tokenCache.tail = a.Conversation[0], a.Conversation[len(a.Conversation)-1]
+	}
+	return tokens, nil
+}
+
+// extendTokenCache adds the just-appended last content, already counted as tokens,
+// to the cache so the next request doesn't count it again
+func (a *Agent) extendTokenCache(tokens int) {
+	n := len(a.Conversation)
+	if n < 2 || a.tokenCache.length != n-1 || !a.tokenCache.covers(a.Model, a.Conversation) {
+		return
+	}
+	a.tokenCache.length = n
+	a.tokenCache.tail = a.Conversation[n-1]
+	a.tokenCache.tokens += tokens
+}
+
 // countTokens counts the tokens in the given conversation
 func (a *Agent) countTokens(ctx context.Context, conversation []*genai.Content) (int, error) {
 	config := &genai.CountTokensConfig{}
 
-	response, err := a.client.Models.CountTokens(ctx, a.Model, conversation, config)
+	response, err := a.api.CountTokens(ctx, a.Model, conversation, config)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count tokens: %w", err)
 	}
@@ -438,21 +895,73 @@ func (a *Agent) countTokens(ctx context.Context, conversation []*genai.Content)
 	return int(response.TotalTokens), nil
 }
 
-// executeTool executes a specific tool by name with given arguments
-func (a *Agent) executeTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
-	var toolDef ToolDefinition
-	var found bool
+// CountTextTokens counts how many tokens text would consume for the current model
+func (a *Agent) CountTextTokens(ctx context.Context, text string) (int, error) {
+	return a.countTokens(ctx, []*genai.Content{genai.NewContentFromText(text, genai.RoleUser)})
+}
 
+// condenseToolResult shortens a large tool result before it enters the model's context,
+// keeping the head and tail where the most relevant output usually lives
+func (a *Agent) condenseToolResult(name, result string) string {
+	limit := a.config.MaxToolResultChars
+	if toolLimit, ok := a.config.ToolResultLimits[name]; ok {
+		limit = toolLimit
+	}
+	runes := []rune(result)
+	if limit <= 0 || len(runes) <= limit {
+		return result
+	}
+
+	// Cut by characters, not bytes, so multi-byte characters aren't split
+	head := string(runes[:limit*3/4])
+	tail := string(runes[len(runes)-limit/4:])
+	omitted := len(runes) - limit*3/4 - limit/4
+	return fmt.Sprintf("%s\n\n[... %d characters omitted to save context; the user can see the full output. Narrow the request if you need the missing part ...]\n\n%s",
+		head, omitted, tail)
+}
+
+// findTool looks up a tool definition by name
+func (a *Agent) findTool(name string) (ToolDefinition, bool) {
 	for _, tool := range a.tools {
 		if tool.Name == name {
-			toolDef = tool
-			found = true
-			break
+			return tool, true
 		}
 	}
+	return ToolDefinition{}, false
+}
+
+// IsReadOnlyTool reports whether the named tool never modifies the workspace
+func (a *Agent) IsReadOnlyTool(name string) bool {
+	tool, ok := a.findTool(name)
+	return ok && tool.ReadOnly
+}
+
+// skipsConfirmation reports whether the named tool runs without asking for approval
+func (a *Agent) skipsConfirmation(name string) bool {
+	tool, ok := a.findTool(name)
+	return ok && tool.SkipConfirmation
+}
+
+// refuses reports whether the named tool's precheck rejects args; the tool then fails
+// with the same error when it runs
+func (a *Agent) refuses(name string, args map[string]interface{}) bool {
+	tool, ok := a.findTool(name)
+	if !ok || tool.Precheck == nil {
+		return false
+	}
+	argsJSON, err := json.Marshal(args)
+	return err == nil && tool.Precheck(argsJSON) != nil
+}
+
+// executeTool executes a specific tool by name with given arguments
+func (a *Agent) executeTool(ctx context.Context, name string, args map[string]interface{}) (string, error) {
+	toolDef, found := a.findTool(name)
 	if !found {
 		return "", fmt.Errorf("tool %s not found", name)
 	}
+	if a.planMode && !toolDef.ReadOnly {
+		return "", fmt.Errorf("tool %s modifies the workspace and is disabled in plan mode", name)
+	}
 
 	// Convert args to JSON
 	argsJSON, err := json.Marshal(args)
@@ -461,6 +970,11 @@ func (a *Agent) executeTool(ctx context.Context, name string, args map[string]in
 	}
 
 	// Execute with context
+	ctx = WithTokenCounter(ctx, a.CountTextTokens)
+	ctx = WithSessionVars(ctx, &a.vars)
+	if a.config.DryRun {
+		ctx = WithDryRun(ctx)
+	}
 	result, err := toolDef.Function(ctx, argsJSON)
 	if err != nil {
 		return "", fmt.Errorf("tool execution failed: %w", err)
@@ -469,6 +983,24 @@ func (a *Agent) executeTool(ctx context.Context, name string, args map[string]in
 	return result, nil
 }
 
+// PreviewTool runs a tool that edits files in dry-run mode, returning its description
+// of the change, with a diff showing contextLines unchanged lines around each change
+func (a *Agent) PreviewTool(ctx context.Context, name string, args map[string]interface{}, contextLines int) (string, error) {
+	toolDef, found := a.findTool(name)
+	if !found {
+		return "", fmt.Errorf("tool %s not found", name)
+	}
+	if !toolDef.EditsFiles {
+		return "", fmt.Errorf("tool %s doesn't edit files", name)
+	}
+
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	return toolDef.Function(WithDiffContextLines(WithDryRun(ctx), contextLines), argsJSON)
+}
+
 // GetTokenUsage returns the current token usage statistics
 func (a *Agent) GetTokenUsage() TokenUsage {
 	return a.TokenUsage
@@ -479,12 +1011,148 @@ func (a *Agent) ResetTokenUsage() {
 	a.TokenUsage = TokenUsage{}
 }
 
-// ClearConversation clears the conversation history
+// ClearConversation clears the conversation history and the variables tools stored in it
 func (a *Agent) ClearConversation() {
 	a.Conversation = nil
+	a.contextFiles = nil
+	a.vars.Clear()
 	a.ResetTokenUsage()
 }
 
+// PopLastTurn removes the most recent user message and everything the model did in
+// response to it, returning the message and any images attached to it so they can be
+// sent again. Context files and system notes, such as a model switch, added since are
+// kept. It reports false when there is no user message to remove.
+func (a *Agent) PopLastTurn() (string, []*genai.Part, bool) {
+	injected := make(map[*genai.Content]bool, len(a.contextFiles))
+	for _, cf := range a.contextFiles {
+		injected[cf.content] = true
+	}
+	for _, content := range a.Conversation {
+		if isSystemNote(content) {
+			injected[content] = true
+		}
+	}
+
+	turn := -1
+	for i := len(a.Conversation) - 1; i >= 0; i-- {
+		content := a.Conversation[i]
+		if isUserTurn(content) && !injected[content] {
+			turn = i
+			break
+		}
+	}
+	if turn < 0 {
+		return "", nil, false
+	}
+
+	var text strings.Builder
+	var images []*genai.Part
+	for _, part := range a.Conversation[turn].Parts {
+		if part.InlineData != nil {
+			images = append(images, part)
+		}
+		text.WriteString(part.Text)
+	}
+	if strings.HasPrefix(text.String(), summaryPrefix) {
+		// The summary of trimmed turns isn't something the user can resend
+		return "", nil, false
+	}
+
+	conversation := a.Conversation[:turn]
+	for _, content := range a.Conversation[turn+1:] {
+		if injected[content] {
+			conversation = append(conversation, content)
+		}
+	}
+	a.Conversation = conversation
+
+	// ProcessMessage adds the plan mode instruction again if plan mode is still on
+	return strings.TrimPrefix(text.String(), config.PlanModeInstruction+"\n\n"), images, true
+}
+
+// AddContextFile reads a file and injects it into the conversation as a
+// user-provided context block, saving the model a read_file round trip
+func (a *Agent) AddContextFile(path string) error {
+	for _, cf := range a.contextFiles {
+		if cf.path == path {
+			return fmt.Errorf("%s is already in context", path)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read context file: %w", err)
+	}
+
+	block := fmt.Sprintf("[Context file: %s]\n```\n%s\n```\n[End of context file: %s]", path, string(data), path)
+	content := &genai.Content{
+		Role: "user",
+		Parts: []*genai.Part{
+			{Text: block},
+		},
+	}
+
+	a.Conversation = append(a.Conversation, content)
+	a.contextFiles = append(a.contextFiles, contextFile{path: path, content: content})
+	return nil
+}
+
+// ContextFiles returns the paths of files added as context, in the order they were added
+func (a *Agent) ContextFiles() []string {
+	paths := make([]string, 0, len(a.contextFiles))
+	for _, cf := range a.contextFiles {
+		paths = append(paths, cf.path)
+	}
+	return paths
+}
+
+// ClearContextFiles removes all user-added context files from the conversation
+func (a *Agent) ClearContextFiles() {
+	if len(a.contextFiles) == 0 {
+		return
+	}
+
+	injected := make(map[*genai.Content]bool, len(a.contextFiles))
+	for _, cf := range a.contextFiles {
+		injected[cf.content] = true
+	}
+
+	conversation := a.Conversation[:0]
+	for _, content := range a.Conversation {
+		if !injected[content] {
+			conversation = append(conversation, content)
+		}
+	}
+	a.Conversation = conversation
+	a.contextFiles = nil
+}
+
+// RequestStop asks the running tool loop to stop once the current iteration finishes
+func (a *Agent) RequestStop() {
+	a.stopRequested.Store(true)
+}
+
+// SetPlanMode restricts the agent to read-only tools and asks it to plan instead of edit
+func (a *Agent) SetPlanMode(enabled bool) {
+	a.planMode = enabled
+}
+
+// SetDryRun makes tools that change files describe the change instead of making it
+func (a *Agent) SetDryRun(enabled bool) {
+	a.config.DryRun = enabled
+}
+
+// DryRun reports whether tools only describe the changes they would make
+func (a *Agent) DryRun() bool {
+	return a.config.DryRun
+}
+
+// PlanMode reports whether the agent is currently in plan mode
+func (a *Agent) PlanMode() bool {
+	return a.planMode
+}
+
 // GetConfig returns the agent configuration
 func (a *Agent) GetConfig() *AgentConfig {
 	return a.config
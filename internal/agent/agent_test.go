@@ -0,0 +1,422 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// fakeAPI stands in for the Gemini API. Each stream request is answered with the next
+// of streams, repeating the last one, or fails with the matching entry of streamErrs.
+// Every counted content is worth 10 tokens.
+type fakeAPI struct {
+	streams    [][]*genai.GenerateContentResponse
+	streamErrs []error
+	requests   [][]*genai.Content
+	configs    []*genai.GenerateContentConfig
+	countCalls int
+	counted    int // contents counted across all CountTokens calls
+	streamed   int // chunks yielded across all streams
+}
+
+func (f *fakeAPI) Get(ctx context.Context, model string, config *genai.GetModelConfig) (*genai.Model, error) {
+	return &genai.Model{Name: model, InputTokenLimit: 1_000_000, OutputTokenLimit: 65536}, nil
+}
+
+func (f *fakeAPI) CountTokens(ctx context.Context, model string, contents []*genai.Content, config *genai.CountTokensConfig) (*genai.CountTokensResponse, error) {
+	f.countCalls++
+	f.counted += len(contents)
+	return &genai.CountTokensResponse{TotalTokens: int32(10 * len(contents))}, nil
+}
+
+func (f *fakeAPI) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	return response(&genai.Part{Text: "summary"}), nil
+}
+
+func (f *fakeAPI) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	f.requests = append(f.requests, append([]*genai.Content(nil), contents...))
+	f.configs = append(f.configs, config)
+	if n := len(f.requests); n <= len(f.streamErrs) && f.streamErrs[n-1] != nil {
+		err := f.streamErrs[n-1]
+		return func(yield func(*genai.GenerateContentResponse, error) bool) { yield(nil, err) }
+	}
+	chunks := f.streams[min(len(f.requests), len(f.streams))-1]
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		for _, chunk := range chunks {
+			f.streamed++
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+// response is a streamed chunk holding parts from the model
+func response(parts ...*genai.Part) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{
+		{Content: &genai.Content{Role: "model", Parts: parts}},
+	}}
+}
+
+// call is a function call part
+func call(name string, args map[string]interface{}) *genai.Part {
+	return &genai.Part{FunctionCall: &genai.FunctionCall{Name: name, Args: args}}
+}
+
+// newTestAgent returns an agent answering from api, with tools that record their calls
+func newTestAgent(api *fakeAPI, config *AgentConfig, tools ...ToolDefinition) *Agent {
+	if config == nil {
+		config = DefaultAgentConfig()
+	}
+	a := NewWithConfig(nil, "gemini-2.5-flash", tools, config)
+	a.api = api
+	return a
+}
+
+// echoTool returns a read-only tool that counts its calls and echoes its input
+func echoTool(name string, calls *int) ToolDefinition {
+	return ToolDefinition{
+		Name:        name,
+		Description: "Echo the input",
+		InputSchema: map[string]interface{}{"type": "object"},
+		ReadOnly:    true,
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			*calls++
+			return string(input), nil
+		},
+	}
+}
+
+func TestProcessMessageStopsAtIterationLimit(t *testing.T) {
+	var calls int
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{
+		{response(call("echo", map[string]interface{}{"again": true}))},
+	}}
+	config := DefaultAgentConfig()
+	config.MaxToolIterations = 3
+	a := newTestAgent(api, config, echoTool("echo", &calls))
+
+	messages, err := a.ProcessMessage(context.Background(), "loop forever", nil, nil, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if len(api.requests) != 3 || calls != 3 {
+		t.Errorf("got %d requests and %d tool calls, want 3 of each", len(api.requests), calls)
+	}
+	last := messages[len(messages)-1]
+	if !last.IsError || !strings.Contains(last.Content, "Stopped after 3 tool iterations") {
+		t.Errorf("last message = %+v, want the iteration limit notice", last)
+	}
+}
+
+func TestPopLastTurnRestoresConversation(t *testing.T) {
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{{response(&genai.Part{Text: "answer"})}}}
+	a := newTestAgent(api, nil)
+	ctx := context.Background()
+	if _, err := a.ProcessMessage(ctx, "first", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	t.Run("after a response", func(t *testing.T) {
+		before := len(a.Conversation)
+		if _, err := a.ProcessMessage(ctx, "second", nil, nil, nil, nil, nil, false); err != nil {
+			t.Fatalf("ProcessMessage: %v", err)
+		}
+		prompt, _, ok := a.PopLastTurn()
+		if !ok || prompt != "second" {
+			t.Fatalf("PopLastTurn() = %q, %v; want \"second\", true", prompt, ok)
+		}
+		if len(a.Conversation) != before {
+			t.Errorf("conversation has %d contents, want %d as before the response", len(a.Conversation), before)
+		}
+	})
+
+	t.Run("model switched before the response", func(t *testing.T) {
+		a.SwitchModel("gemini-2.5-pro")
+		before := len(a.Conversation)
+		if _, err := a.ProcessMessage(ctx, "third", nil, nil, nil, nil, nil, false); err != nil {
+			t.Fatalf("ProcessMessage: %v", err)
+		}
+		prompt, _, ok := a.PopLastTurn()
+		if !ok || prompt != "third" {
+			t.Fatalf("PopLastTurn() = %q, %v; want \"third\", true", prompt, ok)
+		}
+		if len(a.Conversation) != before {
+			t.Errorf("conversation has %d contents, want %d as before the response", len(a.Conversation), before)
+		}
+	})
+
+	t.Run("model switched after the response", func(t *testing.T) {
+		before := len(a.Conversation)
+		if _, err := a.ProcessMessage(ctx, "fourth", nil, nil, nil, nil, nil, false); err != nil {
+			t.Fatalf("ProcessMessage: %v", err)
+		}
+		a.SwitchModel("gemini-2.5-flash")
+		prompt, _, ok := a.PopLastTurn()
+		if !ok || prompt != "fourth" {
+			t.Fatalf("PopLastTurn() = %q, %v; want the user's message, not the switch note", prompt, ok)
+		}
+		// The switch note stays so the next model still knows about it
+		if len(a.Conversation) != before+1 || !isSystemNote(a.Conversation[before]) {
+			t.Errorf("conversation has %d contents, want %d plus the switch note", len(a.Conversation), before)
+		}
+	})
+}
+
+func TestCondenseToolResultKeepsCharactersWhole(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.MaxToolResultChars = 8
+	config.ToolResultLimits = map[string]int{"read_file": 100}
+	a := newTestAgent(&fakeAPI{}, config)
+
+	result := strings.Repeat("é", 20) + strings.Repeat("日", 20)
+	condensed := a.condenseToolResult("run_shell_command", result)
+	if !utf8.ValidString(condensed) {
+		t.Fatalf("condensed result isn't valid UTF-8: %q", condensed)
+	}
+	if !strings.HasPrefix(condensed, "éééééé\n") || !strings.HasSuffix(condensed, "\n日日") || !strings.Contains(condensed, "32 characters omitted") {
+		t.Errorf("condensed = %q, want 6 characters of head, 2 of tail and 32 omitted", condensed)
+	}
+
+	if got := a.condenseToolResult("read_file", result); got != result {
+		t.Errorf("read_file result was condensed under its 100 character limit: %q", got)
+	}
+}
+
+func TestProcessMessageRetriesRateLimitWithinIteration(t *testing.T) {
+	var calls int
+	rateLimited := genai.APIError{
+		Code:    429,
+		Status:  "RESOURCE_EXHAUSTED",
+		Details: []map[string]any{{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "10ms"}},
+	}
+	api := &fakeAPI{
+		streams: [][]*genai.GenerateContentResponse{
+			nil,
+			{response(call("echo", map[string]interface{}{"again": true}))},
+			{response(&genai.Part{Text: "done"})},
+		},
+		streamErrs: []error{rateLimited},
+	}
+	a := newTestAgent(api, nil, echoTool("echo", &calls))
+
+	var iterations []int
+	ctx := WithIterationObserver(context.Background(), func(iteration int) {
+		iterations = append(iterations, iteration)
+	})
+	messages, err := a.ProcessMessage(ctx, "hello", nil, nil, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if len(api.requests) != 3 || calls != 1 {
+		t.Errorf("got %d requests and %d tool calls, want 3 requests and 1 tool call", len(api.requests), calls)
+	}
+	// The retry repeats the first iteration rather than using up another
+	if !slices.Equal(iterations, []int{1, 1, 2}) {
+		t.Errorf("iterations = %v, want [1 1 2]", iterations)
+	}
+	if !strings.Contains(messages[0].Content, "Rate limited") {
+		t.Errorf("first message = %+v, want the retry notice", messages[0])
+	}
+	// Only the two answered requests count: one content, then three
+	if a.TokenUsage.InputTokens != 40 {
+		t.Errorf("InputTokens = %d, want 40", a.TokenUsage.InputTokens)
+	}
+}
+
+func TestNewSessionCopiesConfig(t *testing.T) {
+	a := newTestAgent(&fakeAPI{}, nil)
+	session := a.NewSession()
+
+	session.GetConfig().MaxToolResultChars = 10
+	session.GetConfig().ToolResultLimits["read_file"] = 100
+	session.GetConfig().SupportedThinkingModels[0] = "other-model"
+
+	config := a.GetConfig()
+	if config.MaxToolResultChars == 10 || config.ToolResultLimits["read_file"] != 0 || config.SupportedThinkingModels[0] == "other-model" {
+		t.Errorf("changing the new session's config changed the original: %+v", config)
+	}
+}
+
+func TestSessionVarsBelongToOneConversation(t *testing.T) {
+	stash := ToolDefinition{
+		Name:        "stash",
+		Description: "Store the test command",
+		InputSchema: map[string]interface{}{"type": "object"},
+		ReadOnly:    true,
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			SessionVarsFrom(ctx).Set("test_cmd", "go test ./...")
+			return "stored", nil
+		},
+	}
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{
+		{response(call("stash", nil))},
+		{response(&genai.Part{Text: "done"})},
+	}}
+	a := newTestAgent(api, nil, stash)
+	other := a.NewSession()
+	if _, err := a.ProcessMessage(context.Background(), "remember the test command", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	if value, ok := a.vars.Get("test_cmd"); !ok || value != "go test ./..." {
+		t.Fatalf("test_cmd = %q, %v; want the stored command", value, ok)
+	}
+	if _, ok := other.vars.Get("test_cmd"); ok {
+		t.Error("a variable stored in one session is visible in another")
+	}
+	other.ClearConversation()
+	if _, ok := a.vars.Get("test_cmd"); !ok {
+		t.Error("clearing another session's conversation cleared this one's variables")
+	}
+	a.ClearConversation()
+	if _, ok := a.vars.Get("test_cmd"); ok {
+		t.Error("clearing the conversation kept its variables")
+	}
+}
+
+func TestProcessMessageStopsAtTokenBudget(t *testing.T) {
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{{response(&genai.Part{Text: "answer"})}}}
+	config := DefaultAgentConfig()
+	config.MaxTotalTokens = 35
+	a := newTestAgent(api, config)
+	ctx := context.Background()
+
+	// The first turn fits: 10 tokens in, 10 out
+	if _, err := a.ProcessMessage(ctx, "first", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if a.TokenUsage.TotalTokens != 20 || len(api.requests) != 1 {
+		t.Fatalf("after the first turn: %d tokens and %d requests, want 20 and 1", a.TokenUsage.TotalTokens, len(api.requests))
+	}
+
+	// The second would need 30 more, past the budget of 35
+	before := len(a.Conversation)
+	messages, err := a.ProcessMessage(ctx, "second", nil, nil, nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if len(api.requests) != 1 {
+		t.Errorf("sent %d requests, want the second not sent", len(api.requests))
+	}
+	last := messages[len(messages)-1]
+	if !last.IsError || !strings.Contains(last.Content, "Token budget reached: 20 of 35 tokens used") {
+		t.Errorf("last message = %+v, want the budget notice", last)
+	}
+	if len(a.Conversation) != before {
+		t.Errorf("conversation has %d contents, want %d: the unanswered message removed", len(a.Conversation), before)
+	}
+}
+
+func TestTokenCacheCountsOnlyNewContent(t *testing.T) {
+	var calls int
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{
+		{response(call("echo", map[string]interface{}{"n": 1}))},
+		{response(&genai.Part{Text: "done"})},
+	}}
+	a := newTestAgent(api, nil, echoTool("echo", &calls))
+	if _, err := a.ProcessMessage(context.Background(), "go", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	// Each request counts the content added since the last, and each response is
+	// counted once: the prompt, the tool call, the tool result, the answer
+	if api.countCalls != 4 || api.counted != 4 {
+		t.Errorf("CountTokens called %d times for %d contents, want 4 calls for 4", api.countCalls, api.counted)
+	}
+	// The second request is still charged for the whole conversation
+	if a.TokenUsage.InputTokens != 40 || a.TokenUsage.ContextTokens != 30 {
+		t.Errorf("token usage = %+v, want 40 input tokens over two requests, 30 in context", a.TokenUsage)
+	}
+}
+
+func TestRepeatedFunctionCallRunsOnce(t *testing.T) {
+	var calls int
+	args := func() map[string]interface{} {
+		return map[string]interface{}{"path": "main.go", "range": map[string]interface{}{"start": 1, "end": 5}}
+	}
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{
+		// The same call arrives in two chunks of one response
+		{response(call("echo", args())), response(call("echo", args()))},
+		{response(&genai.Part{Text: "done"})},
+	}}
+	a := newTestAgent(api, nil, echoTool("echo", &calls))
+	if _, err := a.ProcessMessage(context.Background(), "go", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("tool ran %d times, want once", calls)
+	}
+
+	if functionCallKey(call("echo", args()).FunctionCall) == functionCallKey(call("echo", map[string]interface{}{"path": "other.go"}).FunctionCall) {
+		t.Error("calls with different arguments share a key")
+	}
+}
+
+func TestSystemPromptOverrideIsSent(t *testing.T) {
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{{response(&genai.Part{Text: "ok"})}}}
+	config := DefaultAgentConfig()
+	config.SystemPromptOverride = "You only answer in haiku."
+	a := newTestAgent(api, config)
+	if _, err := a.ProcessMessage(context.Background(), "hi", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	instruction := api.configs[0].SystemInstruction
+	if instruction == nil || len(instruction.Parts) == 0 || !strings.HasPrefix(instruction.Parts[0].Text, "You only answer in haiku.") {
+		t.Errorf("system instruction = %+v, want the override", instruction)
+	}
+
+	// Clearing the override restores the embedded prompt
+	a.SetSystemPromptOverride("")
+	if _, err := a.ProcessMessage(context.Background(), "hi again", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	if text := api.configs[1].SystemInstruction.Parts[0].Text; strings.Contains(text, "haiku") || !strings.HasPrefix(text, a.SystemPrompt()) {
+		t.Errorf("system instruction after clearing = %q, want the embedded prompt", text)
+	}
+}
+
+func TestThoughtsStreamAsTheyArrive(t *testing.T) {
+	thought := func(text string) *genai.Part { return &genai.Part{Text: text, Thought: true} }
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{{
+		response(thought("Let me look ")),
+		response(thought("at the file.")),
+		response(&genai.Part{Text: "It's fine."}),
+	}}}
+	a := newTestAgent(api, nil)
+
+	var chunks []string
+	var streamedAt []int
+	thoughtCallback := func(text string) error {
+		chunks = append(chunks, text)
+		streamedAt = append(streamedAt, api.streamed)
+		return nil
+	}
+	messages, err := a.ProcessMessage(context.Background(), "check it", nil, nil, nil, thoughtCallback, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each chunk is delivered before the next one has been received
+	if len(chunks) != 2 || chunks[0] != "Let me look " || chunks[1] != "at the file." {
+		t.Fatalf("thought chunks = %q, want each streamed piece", chunks)
+	}
+	if streamedAt[0] != 1 || streamedAt[1] != 2 {
+		t.Errorf("thought chunks delivered after %v stream chunks, want 1 then 2", streamedAt)
+	}
+
+	// The transcript still records the whole thought once, before the answer
+	if len(messages) != 2 || messages[0].Type != ThoughtMessage || messages[0].Content != "💭 Thinking: Let me look at the file." {
+		t.Fatalf("messages = %+v, want the complete thought then the answer", messages)
+	}
+	if messages[1].Type != AgentMessage || messages[1].Content != "It's fine." {
+		t.Errorf("answer = %+v", messages[1])
+	}
+}
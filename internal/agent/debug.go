@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// debugResultChars caps how much of each tool result is written to the debug log
+const debugResultChars = 500
+
+// secretPatterns match values that must never reach the debug log
+var secretPatterns = []*regexp.Regexp{
+	// "api_key": "...", password=..., Authorization: Bearer ...
+	regexp.MustCompile(`(?i)("?[\w-]*(?:api[_-]?key|token|secret|password|passwd|authorization|credential)[\w-]*"?\s*[:=]\s*"?)(?:bearer\s+)?[^\s",}]+`),
+	// Google API keys
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),
+	// Private key blocks
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`),
+}
+
+// redactSecrets masks likely credentials in text bound for the debug log
+func redactSecrets(text string) string {
+	text = secretPatterns[0].ReplaceAllString(text, "${1}[REDACTED]")
+	for _, pattern := range secretPatterns[1:] {
+		text = pattern.ReplaceAllString(text, "[REDACTED]")
+	}
+	return text
+}
+
+// debugf writes a redacted line to the debug log, if one is configured
+func (a *Agent) debugf(format string, args ...interface{}) {
+	if a.config.DebugLog == nil {
+		return
+	}
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			args[i] = redactSecrets(v)
+		case map[string]interface{}:
+			data, _ := json.Marshal(v)
+			args[i] = redactSecrets(string(data))
+		}
+	}
+	a.config.DebugLog.Printf(format, args...)
+}
+
+// truncateForDebug shortens a tool result for the debug log
+func truncateForDebug(text string) string {
+	runes := []rune(text)
+	if len(runes) <= debugResultChars {
+		return text
+	}
+	return string(runes[:debugResultChars]) + "… (truncated)"
+}
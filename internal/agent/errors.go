@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// maxRateLimitRetries is how many times a turn waits out a short rate limit before giving up
+	maxRateLimitRetries = 3
+	// maxAutoRetryWait is the longest retry-after the agent waits automatically
+	maxAutoRetryWait = 30 * time.Second
+)
+
+// RateLimitError is returned when the API rejects a request for exceeding a rate limit or quota
+type RateLimitError struct {
+	Model      string
+	Message    string
+	RetryAfter time.Duration // Zero when the API didn't say how long to wait
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("Rate limit or quota exceeded for %s.", e.Model)
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(" Try again in %s,", e.RetryAfter.Round(time.Second))
+	} else {
+		msg += " Wait a moment and try again,"
+	}
+	msg += " or switch models with F2."
+	if e.Message != "" {
+		msg += "\nDetails: " + e.Message
+	}
+	return msg
+}
+
+// asRateLimitError converts a 429 / RESOURCE_EXHAUSTED API error into a RateLimitError
+func (a *Agent) asRateLimitError(err error) (*RateLimitError, bool) {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return nil, false
+	}
+	if apiErr.Code != http.StatusTooManyRequests && !strings.Contains(apiErr.Status, "RESOURCE_EXHAUSTED") {
+		return nil, false
+	}
+
+	rateLimitErr := &RateLimitError{Model: a.Model, Message: apiErr.Message}
+	for _, detail := range apiErr.Details {
+		if typ, _ := detail["@type"].(string); !strings.HasSuffix(typ, "google.rpc.RetryInfo") {
+			continue
+		}
+		if delay, ok := detail["retryDelay"].(string); ok {
+			if d, err := time.ParseDuration(delay); err == nil {
+				rateLimitErr.RetryAfter = d
+			}
+		}
+	}
+	return rateLimitErr, true
+}
+
+// waitForRetry sleeps for d or until ctx is cancelled
+func waitForRetry(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
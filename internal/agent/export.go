@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExportMarkdown renders the conversation as Markdown, with a header per turn and
+// tool arguments and results in fenced code blocks
+func (a *Agent) ExportMarkdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Conversation with %s\n", a.Model)
+
+	for _, msg := range a.Transcript() {
+		switch msg.Type {
+		case UserMessage:
+			fmt.Fprintf(&b, "\n## User\n\n%s\n", strings.TrimSpace(msg.Content))
+		case AgentMessage:
+			fmt.Fprintf(&b, "\n## Assistant\n\n%s\n", strings.TrimSpace(msg.Content))
+		case ToolMessage:
+			fmt.Fprintf(&b, "\n### Tool: %s\n\n", msg.ToolName)
+			args, _ := json.MarshalIndent(msg.ToolArgs, "", "  ")
+			b.WriteString(fencedBlock("json", string(args)))
+			if msg.IsError {
+				b.WriteString("\nError:\n\n")
+				b.WriteString(fencedBlock("", strings.TrimPrefix(msg.ToolResult, "Error: ")))
+			} else {
+				b.WriteString("\nResult:\n\n")
+				b.WriteString(fencedBlock("", msg.ToolResult))
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "\n---\n\nTokens: %d input, %d output, %d total\n",
+		a.TokenUsage.InputTokens, a.TokenUsage.OutputTokens, a.TokenUsage.TotalTokens)
+	return b.String()
+}
+
+// fencedBlock wraps content in a code fence longer than any backtick run inside it
+func fencedBlock(lang, content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fmt.Sprintf("%s%s\n%s\n%s\n", fence, lang, strings.TrimRight(content, "\n"), fence)
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// postEditHookName labels post-edit check results in the transcript
+	postEditHookName = "post_edit_check"
+
+	// postEditHookTimeout bounds a single post-edit check
+	postEditHookTimeout = 2 * time.Minute
+
+	// maxPostEditRuns caps post-edit checks per turn so a long edit loop can't
+	// spend unbounded time rebuilding
+	maxPostEditRuns = 20
+)
+
+// editsFiles reports whether the named tool changes file contents
+func (a *Agent) editsFiles(name string) bool {
+	tool, ok := a.findTool(name)
+	return ok && tool.EditsFiles
+}
+
+// PostEditCommand returns the command run after each successful edit, or "" if disabled
+func (a *Agent) PostEditCommand() string {
+	return a.config.PostEditCommand
+}
+
+// SetPostEditCommand changes the command run after each successful edit; empty disables it
+func (a *Agent) SetPostEditCommand(command string) {
+	a.config.PostEditCommand = command
+}
+
+// runPostEditHook runs the configured post-edit command and returns its result as a tool message
+func (a *Agent) runPostEditHook(ctx context.Context, runs *int) Message {
+	command := a.config.PostEditCommand
+	argsJSON, _ := json.Marshal(map[string]string{"command": command})
+
+	var result string
+	var failed bool
+	if *runs >= maxPostEditRuns {
+		result = fmt.Sprintf("Skipped: the post-edit check already ran %d times this turn.", maxPostEditRuns)
+	} else {
+		*runs++
+		result, failed = runPostEditCommand(ctx, command)
+	}
+	a.debugf("post-edit check %s: %s", command, truncateForDebug(result))
+
+	return Message{
+		Type:       ToolMessage,
+		Content:    fmt.Sprintf("🔧 Tool Call: %s\nArguments: %s\nResult: %s", postEditHookName, argsJSON, result),
+		IsError:    failed,
+		ToolName:   postEditHookName,
+		ToolArgs:   map[string]interface{}{"command": command},
+		ToolResult: result,
+	}
+}
+
+// runPostEditCommand runs command in the shell and describes the outcome
+func runPostEditCommand(ctx context.Context, command string) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, postEditHookTimeout)
+	defer cancel()
+
+	shell, shellArg := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, shellArg = "cmd", "/c"
+	}
+
+	output, err := exec.CommandContext(ctx, shell, shellArg, command).CombinedOutput()
+	trimmed := strings.TrimSpace(string(output))
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Sprintf("Post-edit check `%s` timed out after %s.\n%s", command, postEditHookTimeout, trimmed), true
+	case err != nil:
+		return fmt.Sprintf("Post-edit check `%s` FAILED after this edit:\n%s", command, trimmed), true
+	}
+	return fmt.Sprintf("Post-edit check `%s` passed.", command), false
+}
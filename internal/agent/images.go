@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// maxImageBytes caps an attached image; Gemini rejects requests over 20MB of inline data
+const maxImageBytes = 20 << 20
+
+// imageMIMETypes are the image formats Gemini accepts, by file extension
+var imageMIMETypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+}
+
+// LoadImage reads an image file into a prompt part. The MIME type comes from the
+// extension, or from the file's contents when the extension isn't a known image type.
+func LoadImage(path string) (*genai.Part, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxImageBytes {
+		return nil, fmt.Errorf("%s is %d bytes; images are limited to %d", path, info.Size(), maxImageBytes)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mimeType, ok := imageMIMETypes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		mimeType = http.DetectContentType(data)
+		if !isSupportedImage(mimeType) {
+			return nil, fmt.Errorf("%s is not a PNG, JPEG, WebP, HEIC or HEIF image (%s)", path, mimeType)
+		}
+	}
+	return genai.NewPartFromBytes(data, mimeType), nil
+}
+
+// isSupportedImage reports whether Gemini accepts images of the MIME type
+func isSupportedImage(mimeType string) bool {
+	for _, supported := range imageMIMETypes {
+		if mimeType == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// userContent builds the user's turn from their text followed by any attached images
+func userContent(text string, images []*genai.Part) *genai.Content {
+	var parts []*genai.Part
+	if text != "" || len(images) == 0 {
+		parts = append(parts, &genai.Part{Text: text})
+	}
+	return &genai.Content{Role: "user", Parts: append(parts, images...)}
+}
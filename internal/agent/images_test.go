@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func writeImage(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pngHeader, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUserContentWithImages(t *testing.T) {
+	image, err := LoadImage(writeImage(t, "screenshot"))
+	if err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if image.InlineData == nil || image.InlineData.MIMEType != "image/png" {
+		t.Fatalf("LoadImage part = %+v, want inline image/png data", image)
+	}
+
+	content := userContent("what is this?", []*genai.Part{image})
+	if content.Role != "user" || len(content.Parts) != 2 {
+		t.Fatalf("content = %+v, want a user turn of text and image", content)
+	}
+	if content.Parts[0].Text != "what is this?" || content.Parts[1] != image {
+		t.Errorf("parts = %+v, want the text followed by the image", content.Parts)
+	}
+
+	imageOnly := userContent("", []*genai.Part{image})
+	if len(imageOnly.Parts) != 1 || imageOnly.Parts[0] != image {
+		t.Errorf("image-only parts = %+v, want just the image", imageOnly.Parts)
+	}
+}
+
+func TestPopLastTurnKeepsImages(t *testing.T) {
+	image, err := LoadImage(writeImage(t, "diagram.png"))
+	if err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{{response(&genai.Part{Text: "a diagram"})}}}
+	a := newTestAgent(api, nil)
+	if _, err := a.ProcessMessage(context.Background(), "", []*genai.Part{image}, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	prompt, images, ok := a.PopLastTurn()
+	if !ok || prompt != "" || len(images) != 1 || images[0] != image {
+		t.Fatalf("PopLastTurn() = %q, %v, %v; want the image back", prompt, images, ok)
+	}
+
+	// Resending restores the same multimodal turn
+	if _, err := a.ProcessMessage(context.Background(), prompt, images, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	sent := api.requests[len(api.requests)-1]
+	last := sent[len(sent)-1]
+	if len(last.Parts) != 1 || last.Parts[0].InlineData == nil {
+		t.Errorf("regenerated turn = %+v, want the image alone", last.Parts)
+	}
+}
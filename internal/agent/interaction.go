@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+type (
+	// ProcessRunner runs an interactive process that needs the user's terminal,
+	// such as an editor, and returns once the process exits
+	ProcessRunner func(cmd *exec.Cmd) error
+
+	processRunnerKey struct{}
+)
+
+// WithProcessRunner returns a context carrying the UI's process runner for tools
+func WithProcessRunner(ctx context.Context, runner ProcessRunner) context.Context {
+	return context.WithValue(ctx, processRunnerKey{}, runner)
+}
+
+// RunInteractiveProcess runs cmd through the UI's process runner if one is set,
+// otherwise it attaches the process directly to the current terminal
+func RunInteractiveProcess(ctx context.Context, cmd *exec.Cmd) error {
+	if runner, ok := ctx.Value(processRunnerKey{}).(ProcessRunner); ok && runner != nil {
+		return runner(cmd)
+	}
+
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+type (
+	// UserQuestioner asks the user a question mid-task and returns their typed reply
+	UserQuestioner func(question string) (string, error)
+
+	userQuestionerKey struct{}
+)
+
+// WithUserQuestioner returns a context carrying the UI's question handler for tools
+func WithUserQuestioner(ctx context.Context, questioner UserQuestioner) context.Context {
+	return context.WithValue(ctx, userQuestionerKey{}, questioner)
+}
+
+// AskUser surfaces a question to the user through the UI and waits for the reply
+func AskUser(ctx context.Context, question string) (string, error) {
+	questioner, ok := ctx.Value(userQuestionerKey{}).(UserQuestioner)
+	if !ok || questioner == nil {
+		return "", fmt.Errorf("no user interface is available to ask questions")
+	}
+	return questioner(question)
+}
+
+type (
+	// IterationObserver is told when each model/tool iteration of a turn begins
+	IterationObserver func(iteration int)
+
+	iterationObserverKey struct{}
+)
+
+// WithIterationObserver returns a context carrying the UI's iteration observer
+func WithIterationObserver(ctx context.Context, observer IterationObserver) context.Context {
+	return context.WithValue(ctx, iterationObserverKey{}, observer)
+}
+
+// notifyIteration reports the current iteration to the observer in ctx, if any
+func notifyIteration(ctx context.Context, iteration int) {
+	if observer, ok := ctx.Value(iterationObserverKey{}).(IterationObserver); ok && observer != nil {
+		observer(iteration)
+	}
+}
+
+type (
+	// OutputObserver receives each line of output from a tool as it's produced, such as
+	// a shell command's progress; it may be called from several goroutines, one at a time
+	OutputObserver func(line string)
+
+	outputObserverKey struct{}
+)
+
+// WithOutputObserver returns a context carrying the UI's observer for tool output
+func WithOutputObserver(ctx context.Context, observer OutputObserver) context.Context {
+	return context.WithValue(ctx, outputObserverKey{}, observer)
+}
+
+// ObservedOutput returns the output observer in ctx, if any, for tools that stream output
+func ObservedOutput(ctx context.Context) (OutputObserver, bool) {
+	observer, ok := ctx.Value(outputObserverKey{}).(OutputObserver)
+	return observer, ok && observer != nil
+}
+
+type (
+	// TokenCounter counts how many tokens text would consume for the current model
+	TokenCounter func(ctx context.Context, text string) (int, error)
+
+	tokenCounterKey struct{}
+)
+
+// WithTokenCounter returns a context carrying the agent's token counter for tools
+func WithTokenCounter(ctx context.Context, counter TokenCounter) context.Context {
+	return context.WithValue(ctx, tokenCounterKey{}, counter)
+}
+
+// CountTokens counts text with the token counter in ctx
+func CountTokens(ctx context.Context, text string) (int, error) {
+	counter, ok := ctx.Value(tokenCounterKey{}).(TokenCounter)
+	if !ok || counter == nil {
+		return 0, fmt.Errorf("no model is available to count tokens")
+	}
+	return counter(ctx, text)
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context telling tools to describe changes instead of making them
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether tools running with ctx must leave the workspace unchanged
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+type diffContextKey struct{}
+
+// WithDiffContextLines returns a context setting how many unchanged lines surround
+// each change in the diffs tools preview
+func WithDiffContextLines(ctx context.Context, lines int) context.Context {
+	return context.WithValue(ctx, diffContextKey{}, lines)
+}
+
+// DiffContextLines returns the diff context set on ctx, or def when unset
+func DiffContextLines(ctx context.Context, def int) int {
+	if lines, ok := ctx.Value(diffContextKey{}).(int); ok && lines >= 0 {
+		return lines
+	}
+	return def
+}
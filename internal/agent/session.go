@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// sessionVersion is bumped whenever the saved session format changes incompatibly
+const sessionVersion = 1
+
+// savedSession is the on-disk form of a conversation
+type savedSession struct {
+	Version      int              `json:"version"`
+	Model        string           `json:"model"`
+	SavedAt      time.Time        `json:"saved_at"`
+	TokenUsage   TokenUsage       `json:"token_usage"`
+	Conversation []*genai.Content `json:"conversation"`
+}
+
+// SaveSession writes the conversation and token usage to path as JSON, replacing
+// any existing file only once the new one is fully written
+func (a *Agent) SaveSession(path string) error {
+	data, err := json.MarshalIndent(savedSession{
+		Version:      sessionVersion,
+		Model:        a.Model,
+		SavedAt:      time.Now(),
+		TokenUsage:   a.TokenUsage,
+		Conversation: a.Conversation,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	// Conversations can contain file contents and command output, so keep them private
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// LoadSession replaces the conversation and token usage with those saved at path.
+// The current model is kept; variables stored by tools are cleared.
+func (a *Agent) LoadSession(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse session %s: %w", path, err)
+	}
+	if saved.Version != sessionVersion {
+		return fmt.Errorf("unsupported session version %d in %s (expected %d)", saved.Version, path, sessionVersion)
+	}
+
+	a.Conversation = saved.Conversation
+	a.TokenUsage = saved.TokenUsage
+	a.contextFiles = nil
+	a.vars.Clear()
+	return nil
+}
+
+// Transcript rebuilds displayable messages from the conversation, e.g. after
+// LoadSession. Thought parts are omitted.
+func (a *Agent) Transcript() []Message {
+	var messages []Message
+	var pending []*genai.FunctionCall
+	for _, content := range a.Conversation {
+		var text strings.Builder
+		for _, part := range content.Parts {
+			switch {
+			case part.Thought:
+				continue
+			case part.Text != "":
+				text.WriteString(part.Text)
+			case part.InlineData != nil:
+				fmt.Fprintf(&text, "\n\n🖼 Attached image (%s)", part.InlineData.MIMEType)
+			case part.FunctionCall != nil:
+				pending = append(pending, part.FunctionCall)
+			case part.FunctionResponse != nil:
+				var call *genai.FunctionCall
+				if len(pending) > 0 {
+					call, pending = pending[0], pending[1:]
+				}
+				messages = append(messages, transcriptToolMessage(call, part.FunctionResponse))
+			}
+		}
+
+		if text.Len() == 0 {
+			continue
+		}
+		msgType := UserMessage
+		if content.Role == "model" {
+			msgType = AgentMessage
+		}
+		messages = append(messages, Message{Type: msgType, Content: text.String()})
+	}
+	return messages
+}
+
+// transcriptToolMessage formats a saved tool call and its response like a live tool message
+func transcriptToolMessage(call *genai.FunctionCall, response *genai.FunctionResponse) Message {
+	var args map[string]interface{}
+	if call != nil {
+		args = call.Args
+	}
+	argsJSON, _ := json.Marshal(args)
+
+	if errText, ok := response.Response["error"]; ok {
+		return Message{
+			Type:       ToolMessage,
+			Content:    fmt.Sprintf("🔧 Tool Call: %s\nArguments: %s\nError: %v", response.Name, string(argsJSON), errText),
+			IsError:    true,
+			ToolName:   response.Name,
+			ToolArgs:   args,
+			ToolResult: fmt.Sprintf("Error: %v", errText),
+		}
+	}
+
+	result := fmt.Sprint(response.Response["result"])
+	return Message{
+		Type:       ToolMessage,
+		Content:    fmt.Sprintf("🔧 Tool Call: %s\nArguments: %s\nResult: %s", response.Name, string(argsJSON), result),
+		ToolName:   response.Name,
+		ToolArgs:   args,
+		ToolResult: result,
+	}
+}
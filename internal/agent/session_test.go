@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestTranscriptMarksFailedToolCalls(t *testing.T) {
+	var calls int
+	broken := ToolDefinition{
+		Name:        "broken",
+		Description: "Always fails",
+		InputSchema: map[string]interface{}{"type": "object"},
+		ReadOnly:    true,
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			return "", errors.New("disk on fire")
+		},
+	}
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{
+		{response(call("echo", map[string]interface{}{"n": 1}), call("broken", nil))},
+		{response(&genai.Part{Text: "one worked"})},
+	}}
+	a := newTestAgent(api, nil, echoTool("echo", &calls), broken)
+	if _, err := a.ProcessMessage(context.Background(), "try both", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	transcript := a.Transcript()
+	if len(transcript) != 4 {
+		t.Fatalf("transcript has %d messages, want user, two tool calls and the answer: %+v", len(transcript), transcript)
+	}
+	user, echo, failed, answer := transcript[0], transcript[1], transcript[2], transcript[3]
+	if user.Type != UserMessage || user.Content != "try both" {
+		t.Errorf("first message = %+v, want the user's prompt", user)
+	}
+	if echo.Type != ToolMessage || echo.IsError || echo.ToolName != "echo" || echo.ToolResult != `{"n":1}` {
+		t.Errorf("echo message = %+v, want a successful echo of the arguments", echo)
+	}
+	if failed.Type != ToolMessage || !failed.IsError || !strings.Contains(failed.ToolResult, "disk on fire") || !strings.Contains(failed.Content, "Error: ") {
+		t.Errorf("broken message = %+v, want it marked as an error", failed)
+	}
+	if answer.Type != AgentMessage || answer.Content != "one worked" {
+		t.Errorf("last message = %+v, want the model's answer", answer)
+	}
+}
+
+func TestSaveSessionRoundTrip(t *testing.T) {
+	var calls int
+	api := &fakeAPI{streams: [][]*genai.GenerateContentResponse{
+		{response(call("echo", map[string]interface{}{"path": "main.go"}))},
+		{response(&genai.Part{Text: "done"})},
+	}}
+	a := newTestAgent(api, nil, echoTool("echo", &calls))
+	if _, err := a.ProcessMessage(context.Background(), "read main.go", nil, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "sessions", "session.json")
+	if err := a.SaveSession(path); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("saved session stat = %v, %v; want a private file", info, err)
+	}
+
+	loaded := newTestAgent(&fakeAPI{}, nil)
+	if err := loaded.LoadSession(path); err != nil {
+		t.Fatalf("LoadSession: %v", err)
+	}
+	if len(loaded.Conversation) != len(a.Conversation) {
+		t.Fatalf("loaded %d contents, want %d", len(loaded.Conversation), len(a.Conversation))
+	}
+	if loaded.TokenUsage != a.TokenUsage {
+		t.Errorf("token usage = %+v, want %+v", loaded.TokenUsage, a.TokenUsage)
+	}
+
+	// user prompt, model call, tool response, model answer
+	functionCall := loaded.Conversation[1].Parts[0].FunctionCall
+	if functionCall == nil || functionCall.Name != "echo" || functionCall.Args["path"] != "main.go" {
+		t.Errorf("function call = %+v, want echo with its arguments", functionCall)
+	}
+	functionResponse := loaded.Conversation[2].Parts[0].FunctionResponse
+	if functionResponse == nil || functionResponse.Name != "echo" || functionResponse.Response["result"] != `{"path":"main.go"}` {
+		t.Errorf("function response = %+v, want echo's result", functionResponse)
+	}
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// defaultTrimPercent is how full the context window may get before older turns
+	// are summarized, when TrimTokenThreshold isn't set
+	defaultTrimPercent = 75
+
+	// summaryPrefix marks the content that stands in for trimmed turns
+	summaryPrefix = "[Summary of the earlier conversation, which was trimmed to fit the context window]"
+
+	// summaryInstruction asks the summarization model for a summary the agent can continue from
+	summaryInstruction = `Summarize the conversation below between a user and a coding agent so the agent can continue the work without it.
+Keep: the user's goals and constraints, decisions made, files read or changed and what changed, commands run and their outcomes, and any open questions or next steps.
+Be concise and factual. Use short bullet points. Do not address the user.`
+)
+
+// Summarizer condenses conversation turns into text that replaces them
+type Summarizer func(ctx context.Context, contents []*genai.Content) (string, error)
+
+// SetSummarizer replaces the model call used to summarize trimmed turns
+func (a *Agent) SetSummarizer(summarize Summarizer) {
+	a.summarize = summarize
+}
+
+// trimThreshold returns the conversation size in tokens above which it is trimmed, or 0
+func (a *Agent) trimThreshold() int {
+	if a.config.TrimTokenThreshold != 0 {
+		return max(a.config.TrimTokenThreshold, 0)
+	}
+	return a.ContextWindow() * defaultTrimPercent / 100
+}
+
+// TrimConversation replaces the oldest turns with a summary once the conversation
+// has grown past the trim threshold, keeping the most recent TrimKeepTurns user turns
+// and any context files verbatim. It reports whether anything was trimmed.
+func (a *Agent) TrimConversation(ctx context.Context) (bool, error) {
+	threshold := a.trimThreshold()
+	if threshold <= 0 || a.TokenUsage.ContextTokens < threshold {
+		return false, nil
+	}
+
+	// Only cut where a user turn starts, so tool calls stay paired with their results
+	var turns []int
+	for i, content := range a.Conversation {
+		if isUserTurn(content) {
+			turns = append(turns, i)
+		}
+	}
+	keep := max(a.config.TrimKeepTurns, 1)
+	if len(turns) <= keep {
+		return false, nil
+	}
+	cut := turns[len(turns)-keep]
+
+	contextContents := make(map[*genai.Content]bool, len(a.contextFiles))
+	for _, cf := range a.contextFiles {
+		contextContents[cf.content] = true
+	}
+	var older, kept []*genai.Content
+	for _, content := range a.Conversation[:cut] {
+		if contextContents[content] {
+			kept = append(kept, content)
+		} else {
+			older = append(older, content)
+		}
+	}
+	if len(older) == 0 {
+		return false, nil
+	}
+
+	summarize := a.summarize
+	if summarize == nil {
+		summarize = a.summarizeWithModel
+	}
+	summary, err := summarize(ctx, older)
+	if err != nil {
+		return false, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+
+	trimmed := []*genai.Content{genai.NewContentFromText(summaryPrefix+"\n\n"+strings.TrimSpace(summary), genai.RoleUser)}
+	trimmed = append(trimmed, kept...)
+	trimmed = append(trimmed, a.Conversation[cut:]...)
+	a.debugf("trimmed %d of %d contents into a %d char summary", len(older), len(a.Conversation), len(summary))
+	a.Conversation = trimmed
+	return true, nil
+}
+
+// isUserTurn reports whether content is a message typed by the user rather than tool results
+func isUserTurn(content *genai.Content) bool {
+	if content.Role != genai.RoleUser {
+		return false
+	}
+	for _, part := range content.Parts {
+		if part.FunctionResponse != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// summarizeWithModel asks the summary model, or the current model, to summarize contents
+func (a *Agent) summarizeWithModel(ctx context.Context, contents []*genai.Content) (string, error) {
+	model := a.config.SummaryModel
+	if model == "" {
+		model = a.Model
+	}
+
+	response, err := a.api.GenerateContent(ctx, model,
+		[]*genai.Content{genai.NewContentFromText(transcriptText(contents), genai.RoleUser)},
+		&genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(summaryInstruction, genai.RoleUser),
+			Temperature:       ptr(float32(0.2)),
+		})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(response.Text())
+	if summary == "" {
+		return "", fmt.Errorf("%s returned an empty summary", model)
+	}
+	return summary, nil
+}
+
+// transcriptText flattens contents into plain text for summarization, shortening
+// long tool results since their gist is what matters
+func transcriptText(contents []*genai.Content) string {
+	const maxToolText = 2000
+	var b strings.Builder
+	for _, content := range contents {
+		for _, part := range content.Parts {
+			switch {
+			case part.Thought:
+				continue
+			case part.Text != "":
+				fmt.Fprintf(&b, "%s: %s\n\n", content.Role, part.Text)
+			case part.InlineData != nil:
+				fmt.Fprintf(&b, "%s: [%s image]\n\n", content.Role, part.InlineData.MIMEType)
+			case part.FunctionCall != nil:
+				fmt.Fprintf(&b, "tool call: %s %v\n\n", part.FunctionCall.Name, part.FunctionCall.Args)
+			case part.FunctionResponse != nil:
+				text := fmt.Sprint(part.FunctionResponse.Response)
+				if len(text) > maxToolText {
+					text = text[:maxToolText] + " ..."
+				}
+				fmt.Fprintf(&b, "tool result: %s %s\n\n", part.FunctionResponse.Name, text)
+			}
+		}
+	}
+	return b.String()
+}
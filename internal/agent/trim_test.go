@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestTrimConversationSummarizesOlderTurns(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.TrimTokenThreshold = 100
+	config.TrimKeepTurns = 2
+	a := newTestAgent(&fakeAPI{}, config)
+	for i := 1; i <= 5; i++ {
+		a.Conversation = append(a.Conversation,
+			genai.NewContentFromText(fmt.Sprintf("question %d", i), genai.RoleUser),
+			genai.NewContentFromText(fmt.Sprintf("answer %d", i), genai.RoleModel))
+	}
+	older := append([]*genai.Content(nil), a.Conversation[:6]...)
+	recent := append([]*genai.Content(nil), a.Conversation[6:]...)
+
+	var summarized []*genai.Content
+	a.SetSummarizer(func(ctx context.Context, contents []*genai.Content) (string, error) {
+		summarized = contents
+		return "  the user asked three questions  ", nil
+	})
+
+	// Below the threshold nothing happens
+	a.TokenUsage.ContextTokens = 99
+	if trimmed, err := a.TrimConversation(context.Background()); err != nil || trimmed {
+		t.Fatalf("TrimConversation under the threshold = %v, %v; want no trim", trimmed, err)
+	}
+
+	a.TokenUsage.ContextTokens = 150
+	trimmed, err := a.TrimConversation(context.Background())
+	if err != nil || !trimmed {
+		t.Fatalf("TrimConversation = %v, %v; want a trim", trimmed, err)
+	}
+	if len(summarized) != len(older) || summarized[0] != older[0] || summarized[5] != older[5] {
+		t.Errorf("summarized %d contents, want the first three turns", len(summarized))
+	}
+	if len(a.Conversation) != 5 {
+		t.Fatalf("conversation has %d contents, want the summary and the last two turns", len(a.Conversation))
+	}
+	if want := summaryPrefix + "\n\nthe user asked three questions"; a.Conversation[0].Parts[0].Text != want {
+		t.Errorf("first content = %q, want %q", a.Conversation[0].Parts[0].Text, want)
+	}
+	for i, content := range recent {
+		if a.Conversation[i+1] != content {
+			t.Errorf("content %d after the summary isn't the original turn", i+1)
+		}
+	}
+}
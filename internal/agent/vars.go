@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// SessionVars holds values tools stash for the rest of a conversation, such as the
+// project's test command. Each agent has its own, so tabs don't share them.
+type SessionVars struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// Set stores value under name, replacing any earlier value
+func (v *SessionVars) Set(name, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.values == nil {
+		v.values = make(map[string]string)
+	}
+	v.values[name] = value
+}
+
+// Get returns the value stored under name
+func (v *SessionVars) Get(name string) (string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	value, ok := v.values[name]
+	return value, ok
+}
+
+// All returns a copy of every stored value, by name
+func (v *SessionVars) All() map[string]string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return maps.Clone(v.values)
+}
+
+// Clear forgets every stored value
+func (v *SessionVars) Clear() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values = nil
+}
+
+type sessionVarsKey struct{}
+
+// WithSessionVars returns a context carrying the conversation's variables for tools
+func WithSessionVars(ctx context.Context, vars *SessionVars) context.Context {
+	return context.WithValue(ctx, sessionVarsKey{}, vars)
+}
+
+// SessionVarsFrom returns the conversation's variables carried by ctx, or nil if none
+func SessionVarsFrom(ctx context.Context) *SessionVars {
+	vars, _ := ctx.Value(sessionVarsKey{}).(*SessionVars)
+	return vars
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// pathArgumentKeys are tool argument names that hold file system paths, or lists of them
+var pathArgumentKeys = []string{"path", "paths", "package_dir", "directory", "file"}
+
+// ReadOutsideWorkspace returns the first path argument of a read-only tool call that
+// resolves outside the working directory, so the UI can ask before allowing it
+func (a *Agent) ReadOutsideWorkspace(toolName string, args map[string]interface{}) (string, bool) {
+	tool, ok := a.findTool(toolName)
+	if !ok || !tool.ReadOnly {
+		return "", false
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	for _, key := range pathArgumentKeys {
+		for _, path := range pathArguments(args[key]) {
+			if !isWithinDir(root, path) {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// pathArguments returns the non-empty paths in a path argument's value, which is a
+// single path or, for tools taking several, a list
+func pathArguments(value interface{}) []string {
+	var paths []string
+	switch value := value.(type) {
+	case string:
+		paths = append(paths, value)
+	case []interface{}:
+		for _, item := range value {
+			if path, ok := item.(string); ok {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return slices.DeleteFunc(paths, func(path string) bool { return path == "" })
+}
+
+// isWithinDir reports whether path resolves to root or somewhere beneath it
+func isWithinDir(root, path string) bool {
+	if strings.HasPrefix(path, "~") {
+		return false
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	// Resolve symlinks so a link inside the workspace can't point outside it
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
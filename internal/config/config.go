@@ -3,7 +3,9 @@ package config
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 	"google.golang.org/genai"
@@ -11,8 +13,20 @@ import (
 
 // Config holds the application configuration
 type Config struct {
-	APIKey string
-	Model  string
+	APIKey     string
+	Model      string
+	Generation GenerationParams
+}
+
+// GenerationParams holds generation settings overridden by environment variables;
+// nil fields keep the agent's defaults
+type GenerationParams struct {
+	Temperature     *float32
+	TopP            *float32
+	TopK            *float32
+	MaxOutputTokens *int32
+	ThinkingBudget  *int32
+	MaxTotalTokens  *int32
 }
 
 const (
@@ -30,6 +44,8 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("GOOGLE_API_KEY environment variable is required")
 	}
 
+	ensureSystemPrompt()
+
 	// Optional: Model Name (with default)
 	model := os.Getenv("GOOGLE_MODEL")
 	if model == "" {
@@ -37,18 +53,92 @@ func Load() (*Config, error) {
 	}
 
 	// Check for saved user preferences
+	generation := loadGenerationParams()
 	prefs, err := LoadPreferences()
 	if err == nil && prefs.SelectedModel != "" {
 		// User preference takes precedence over environment variable
 		model = prefs.SelectedModel
 	}
+	if err == nil {
+		prefs.overrideGeneration(&generation)
+	}
 
 	return &Config{
-		APIKey: apiKey,
-		Model:  model,
+		APIKey:     apiKey,
+		Model:      model,
+		Generation: generation,
 	}, nil
 }
 
+// loadGenerationParams reads the optional GOOGLE_TEMPERATURE, GOOGLE_TOP_P, GOOGLE_TOP_K,
+// GOOGLE_MAX_OUTPUT_TOKENS, GOOGLE_THINKING_BUDGET and AGENT_MAX_TOTAL_TOKENS variables.
+// Values that don't parse are ignored and values out of range are clamped, with a
+// warning either way.
+func loadGenerationParams() GenerationParams {
+	return GenerationParams{
+		Temperature:     envFloat("GOOGLE_TEMPERATURE", 0, 2),
+		TopP:            envFloat("GOOGLE_TOP_P", 0, 1),
+		TopK:            envFloat("GOOGLE_TOP_K", 1, 1000),
+		MaxOutputTokens: envInt("GOOGLE_MAX_OUTPUT_TOKENS", 1, 1<<20),
+		ThinkingBudget:  envInt("GOOGLE_THINKING_BUDGET", -1, 1<<20),
+		MaxTotalTokens:  envInt("AGENT_MAX_TOTAL_TOKENS", 0, math.MaxInt32),
+	}
+}
+
+// envFloat parses an optional float variable, clamped to [lo, hi]
+func envFloat(name string, lo, hi float64) *float32 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 32)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: ignoring %s=%q: not a number\n", name, raw)
+		return nil
+	}
+	if clamped := clamp(value, lo, hi); clamped != value {
+		fmt.Fprintf(os.Stderr, "WARNING: %s=%s is outside %g to %g; using %g\n", name, raw, lo, hi, clamped)
+		value = clamped
+	}
+	result := float32(value)
+	return &result
+}
+
+// envInt parses an optional integer variable, clamped to [lo, hi]
+func envInt(name string, lo, hi int64) *int32 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: ignoring %s=%q: not an integer\n", name, raw)
+		return nil
+	}
+	if clamped := int64(clamp(float64(value), float64(lo), float64(hi))); clamped != value {
+		fmt.Fprintf(os.Stderr, "WARNING: %s=%s is outside %d to %d; using %d\n", name, raw, lo, hi, clamped)
+		value = clamped
+	}
+	result := int32(value)
+	return &result
+}
+
+// ptr returns a pointer to v
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// clamp limits value to [lo, hi]
+func clamp(value, lo, hi float64) float64 {
+	if value < lo {
+		return lo
+	}
+	if value > hi {
+		return hi
+	}
+	return value
+}
+
 // CreateClient creates a new Gemini client using the configuration
 func (c *Config) CreateClient(ctx context.Context) (*genai.Client, error) {
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestLoadGenerationParams(t *testing.T) {
+	t.Setenv("GOOGLE_TEMPERATURE", "0.4")
+	t.Setenv("GOOGLE_TOP_P", "1.5")           // clamped to 1
+	t.Setenv("GOOGLE_TOP_K", "warm")          // ignored
+	t.Setenv("GOOGLE_MAX_OUTPUT_TOKENS", "0") // clamped to 1
+	t.Setenv("GOOGLE_THINKING_BUDGET", "-5")  // clamped to -1, unlimited
+	t.Setenv("AGENT_MAX_TOTAL_TOKENS", "")
+
+	params := loadGenerationParams()
+	if params.Temperature == nil || *params.Temperature != 0.4 {
+		t.Errorf("Temperature = %v, want 0.4", params.Temperature)
+	}
+	if params.TopP == nil || *params.TopP != 1 {
+		t.Errorf("TopP = %v, want 1.5 clamped to 1", params.TopP)
+	}
+	if params.TopK != nil {
+		t.Errorf("TopK = %v, want an unparseable value ignored", *params.TopK)
+	}
+	if params.MaxOutputTokens == nil || *params.MaxOutputTokens != 1 {
+		t.Errorf("MaxOutputTokens = %v, want 0 clamped to 1", params.MaxOutputTokens)
+	}
+	if params.ThinkingBudget == nil || *params.ThinkingBudget != -1 {
+		t.Errorf("ThinkingBudget = %v, want -5 clamped to -1", params.ThinkingBudget)
+	}
+	if params.MaxTotalTokens != nil {
+		t.Errorf("MaxTotalTokens = %v, want unset", *params.MaxTotalTokens)
+	}
+}
+
+func TestEnvIntRejectsFractions(t *testing.T) {
+	t.Setenv("GOOGLE_MAX_OUTPUT_TOKENS", "1024.5")
+	if value := envInt("GOOGLE_MAX_OUTPUT_TOKENS", 1, 1<<20); value != nil {
+		t.Errorf("envInt = %d, want a fractional value ignored", *value)
+	}
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	_ "embed"
+	"fmt"
+	"os"
+	"strings"
 )
 
 // SystemPrompt is loaded from sys.md at compile time
@@ -9,8 +12,81 @@ import (
 //go:embed SYSTEM.md
 var SystemPrompt string
 
+// SystemPromptFallback is set when SYSTEM.md was empty at build time and
+// DefaultSystemPrompt is being used instead
+var SystemPromptFallback bool
+
+// DefaultSystemPrompt is a minimal prompt used if SYSTEM.md is empty, so a packaging
+// mistake degrades the agent rather than leaving it with no instructions at all
+const DefaultSystemPrompt = `You are a coding assistant running in the user's terminal, working in their current directory.
+Use the available tools to read, search, and edit files and to run commands; don't guess at file contents.
+Make focused changes, verify them where you can, and explain briefly what you did.`
+
+// ensureSystemPrompt falls back to DefaultSystemPrompt when the embedded prompt is empty
+func ensureSystemPrompt() {
+	if strings.TrimSpace(SystemPrompt) != "" {
+		return
+	}
+	SystemPrompt = DefaultSystemPrompt
+	SystemPromptFallback = true
+	fmt.Fprintln(os.Stderr, "WARNING: the embedded SYSTEM.md is empty; using a minimal built-in system prompt. Rebuild with a populated internal/config/SYSTEM.md.")
+}
+
+// DefaultSessionFile is where /save, /load and auto-save keep the conversation, relative to the working directory
+const DefaultSessionFile = ".code-agent/session.json"
+
+// DefaultSystemPromptFile is the per-project system prompt, relative to the working directory
+const DefaultSystemPromptFile = ".code-agent/SYSTEM.md"
+
+// LoadSystemPromptOverride reads the project system prompt named by prefs, or
+// DefaultSystemPromptFile, and returns the prompt to use instead of SystemPrompt along
+// with the file it came from. Both are empty when there is no such file.
+func LoadSystemPromptOverride(prefs *UserPreferences) (prompt, path string, err error) {
+	path = DefaultSystemPromptFile
+	if prefs != nil && prefs.SystemPromptFile != "" {
+		path = prefs.SystemPromptFile
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read system prompt %s: %w", path, err)
+	}
+
+	prompt = strings.TrimSpace(string(content))
+	if prompt == "" {
+		return "", "", nil
+	}
+	if prefs != nil && prefs.AppendSystemPrompt {
+		prompt = strings.TrimRight(SystemPrompt, "\n") + "\n\n" + prompt
+	}
+	return prompt, path, nil
+}
+
+// PlanModeInstruction is prepended to user messages while the agent is in plan mode
+const PlanModeInstruction = `[Plan mode] Do not modify any files or run commands. Investigate with read-only tools as needed, then reply with a numbered plan of the file changes you intend to make: for each step give the file path and a short description of the change. The user will review the plan and reply with /apply to proceed.`
+
 // WelcomeMessage is the initial greeting shown to users
-const WelcomeMessage = `Type your request below or use:
-• F2: Select model  • F3: Toggle tool confirm  • F4: Toggle thinking mode
+const WelcomeMessage = "Type your request below or use:\n" + CommandHelp + `
 
 System prompt loaded (%d chars)`
+
+// CommandHelp lists the keybindings and slash commands, shown on welcome and by /help
+const CommandHelp = `• F2: Select model  • F3: Toggle tool confirm  • F4: Toggle thinking mode
+• F5: Toggle status bar  • F6: Toggle compact mode  • F7: New tab  • F8: Next tab  • F10: Next theme
+• F12: Toggle dry run (preview file changes and shell commands without making them)
+• /context add <path>: Add a file as context  • /context list  • /context clear
+• /new: Start a new conversation with the same settings  • /reload: Reload preferences
+• /settings: Adjust temperature, top P and max output tokens
+• /plan <request>: Draft a plan without editing  • /apply: Carry out the plan
+• /ref [n]: Quote tool call #n (default: the latest) into your next message
+• /image <path>: Attach a PNG, JPEG, WebP or HEIC image to your next message  • /image clear
+• /tab new | /tab close | /tab <n>: Manage conversation tabs  • /count <text>: Count tokens
+• /save [path]: Save the conversation  • /load [path]: Resume a saved conversation
+• /export [path] or F9: Export the conversation to Markdown  • / [query]: Search (n/N: next/previous)
+• /clear: Clear the conversation, transcript and session state  • /help: Show this help
+• Ctrl+T: Expand/collapse messages  • Ctrl+S: Stop after the current tool step  • Ctrl+Y: Copy the last response
+• Ctrl+R: Regenerate the last response  • ↑/↓ on the first/last input line: Recall earlier inputs
+• Esc: Cancel the response (or quit when idle)  • Ctrl+C: Interrupt the response, press again to quit`
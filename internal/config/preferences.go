@@ -12,6 +12,124 @@ type UserPreferences struct {
 	SelectedModel           string `json:"selected_model,omitempty"`
 	RequireToolConfirmation bool   `json:"require_tool_confirmation"`
 	EnableThinkingMode      bool   `json:"enable_thinking_mode"`
+	HideStatusBar           bool   `json:"hide_status_bar,omitempty"`
+	CompactMode             bool   `json:"compact_mode,omitempty"`
+	SelectedTheme           string `json:"selected_theme,omitempty"`
+	DiffContextLines        *int   `json:"diff_context_lines,omitempty"`
+	AllowOutsideReads       bool   `json:"allow_outside_reads,omitempty"`
+	DefaultIncludeHidden    bool   `json:"default_include_hidden,omitempty"`
+	ExpandThoughts          bool   `json:"expand_thoughts,omitempty"`
+	ExpandToolMessages      bool   `json:"expand_tool_messages,omitempty"`
+
+	// MaxStreamedMessageChars splits a long streamed response into a new message past this many characters (0 disables)
+	MaxStreamedMessageChars int `json:"max_streamed_message_chars,omitempty"`
+
+	// AutoSaveSession saves the conversation to DefaultSessionFile on quit and before /new
+	AutoSaveSession bool `json:"auto_save_session,omitempty"`
+
+	// SystemPromptFile overrides where the project system prompt is read from (default .code-agent/SYSTEM.md)
+	SystemPromptFile string `json:"system_prompt_file,omitempty"`
+	// AppendSystemPrompt adds the project prompt after the built-in one instead of replacing it
+	AppendSystemPrompt bool `json:"append_system_prompt,omitempty"`
+
+	// PostEditCommand runs after each successful file edit, with its result shown to the model (empty disables)
+	PostEditCommand string `json:"post_edit_command,omitempty"`
+
+	// GeneratedFilePatterns replaces the default list of files edit tools refuse to modify
+	GeneratedFilePatterns []string `json:"generated_file_patterns,omitempty"`
+
+	// Shell command patterns: a plain pattern matches commands starting with its words and
+	// /.../ is a regular expression. Denied commands always fail; when AllowedCommands is
+	// set, run_shell_command only runs commands it matches.
+	AllowedCommands []string `json:"allowed_commands,omitempty"`
+	DeniedCommands  []string `json:"denied_commands,omitempty"`
+
+	// PersistAutoApprovals opts in to remembering "always allow" choices for read-only tools
+	PersistAutoApprovals bool     `json:"persist_auto_approvals,omitempty"`
+	AutoApprovedTools    []string `json:"auto_approved_tools,omitempty"`
+
+	// ToolConfirmationPolicy overrides RequireToolConfirmation for the tools it lists
+	ToolConfirmationPolicy ToolConfirmationPolicy `json:"tool_confirmation_policy,omitempty"`
+
+	// Token warning thresholds as a percentage of the model's context window (0 uses the default)
+	TokenWarningPercent  int `json:"token_warning_percent,omitempty"`
+	TokenCriticalPercent int `json:"token_critical_percent,omitempty"`
+
+	// Generation settings saved from /settings; like the model, they take precedence over GOOGLE_* variables
+	Temperature     *float32 `json:"temperature,omitempty"`
+	TopP            *float32 `json:"top_p,omitempty"`
+	MaxOutputTokens *int32   `json:"max_output_tokens,omitempty"`
+
+	// MaxToolResultChars condenses longer tool results before they reach the model (0 disables);
+	// ToolResultLimits overrides it for the tools it lists
+	MaxToolResultChars *int           `json:"max_tool_result_chars,omitempty"`
+	ToolResultLimits   map[string]int `json:"tool_result_limits,omitempty"`
+}
+
+// DefaultDiffContextLines is the number of unchanged lines shown around each change in diff previews
+const DefaultDiffContextLines = 3
+
+// GetDiffContextLines returns the configured diff context, falling back to the default
+func (p *UserPreferences) GetDiffContextLines() int {
+	if p == nil || p.DiffContextLines == nil || *p.DiffContextLines < 0 {
+		return DefaultDiffContextLines
+	}
+	return *p.DiffContextLines
+}
+
+// ToolConfirmationPolicy maps tool names to whether they must be confirmed before
+// running, e.g. to auto-approve read_file but always confirm run_shell_command
+type ToolConfirmationPolicy map[string]bool
+
+// RequiresConfirmation reports whether toolName must be confirmed, falling back to
+// requireAll for tools the policy doesn't list
+func (p ToolConfirmationPolicy) RequiresConfirmation(toolName string, requireAll bool) bool {
+	if confirm, ok := p[toolName]; ok {
+		return confirm
+	}
+	return requireAll
+}
+
+// Default token warning thresholds, as a percentage of the context window
+const (
+	DefaultTokenWarningPercent  = 50
+	DefaultTokenCriticalPercent = 80
+)
+
+// GetTokenThresholds returns the warning and critical context-usage percentages,
+// falling back to the defaults when unset or out of order
+func (p *UserPreferences) GetTokenThresholds() (warning, critical int) {
+	warning, critical = DefaultTokenWarningPercent, DefaultTokenCriticalPercent
+	if p == nil {
+		return warning, critical
+	}
+	if p.TokenWarningPercent > 0 {
+		warning = p.TokenWarningPercent
+	}
+	if p.TokenCriticalPercent > 0 {
+		critical = p.TokenCriticalPercent
+	}
+	if warning > critical {
+		warning = critical
+	}
+	return warning, critical
+}
+
+// overrideGeneration replaces params with the generation settings saved in preferences,
+// clamped to the same ranges as the environment variables
+func (p *UserPreferences) overrideGeneration(params *GenerationParams) {
+	if p == nil {
+		return
+	}
+	if p.Temperature != nil {
+		params.Temperature = ptr(float32(clamp(float64(*p.Temperature), 0, 2)))
+	}
+	if p.TopP != nil {
+		params.TopP = ptr(float32(clamp(float64(*p.TopP), 0, 1)))
+	}
+	if p.MaxOutputTokens != nil {
+		params.MaxOutputTokens = ptr(int32(clamp(float64(*p.MaxOutputTokens), 1, 1<<20)))
+	}
 }
 
 // GetPreferencesPath returns the path to the preferences file
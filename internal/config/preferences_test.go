@@ -0,0 +1,64 @@
+package config
+
+import "testing"
+
+func TestSavedGenerationSettingsOverrideEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GOOGLE_TEMPERATURE", "1.2")
+	t.Setenv("GOOGLE_TOP_P", "0.5")
+	t.Setenv("GOOGLE_MAX_OUTPUT_TOKENS", "4096")
+
+	saved := &UserPreferences{
+		SelectedModel: "gemini-2.5-pro",
+		Temperature:   ptr(float32(0.3)),
+		TopP:          ptr(float32(3)), // out of range, clamped on load
+	}
+	if err := SavePreferences(saved); err != nil {
+		t.Fatalf("SavePreferences: %v", err)
+	}
+	prefs, err := LoadPreferences()
+	if err != nil {
+		t.Fatalf("LoadPreferences: %v", err)
+	}
+	if prefs.SelectedModel != "gemini-2.5-pro" || prefs.Temperature == nil || *prefs.Temperature != 0.3 || prefs.MaxOutputTokens != nil {
+		t.Fatalf("loaded preferences = %+v, want what was saved", prefs)
+	}
+
+	params := loadGenerationParams()
+	prefs.overrideGeneration(&params)
+	if *params.Temperature != 0.3 {
+		t.Errorf("Temperature = %v, want the saved 0.3 over the environment's 1.2", *params.Temperature)
+	}
+	if *params.TopP != 1 {
+		t.Errorf("TopP = %v, want the saved 3 clamped to 1", *params.TopP)
+	}
+	if *params.MaxOutputTokens != 4096 {
+		t.Errorf("MaxOutputTokens = %v, want the environment's 4096 with nothing saved", *params.MaxOutputTokens)
+	}
+}
+
+func TestRequiresConfirmation(t *testing.T) {
+	policy := ToolConfirmationPolicy{"read_file": false, "run_shell_command": true}
+
+	tests := []struct {
+		tool       string
+		requireAll bool
+		want       bool
+	}{
+		{"read_file", true, false},
+		{"run_shell_command", false, true},
+		{"write_file", true, true},   // not listed: follows the toggle
+		{"write_file", false, false}, // not listed: follows the toggle
+		{"no_such_tool", true, true},
+	}
+	for _, tt := range tests {
+		if got := policy.RequiresConfirmation(tt.tool, tt.requireAll); got != tt.want {
+			t.Errorf("RequiresConfirmation(%q, %v) = %v, want %v", tt.tool, tt.requireAll, got, tt.want)
+		}
+	}
+
+	var none ToolConfirmationPolicy
+	if !none.RequiresConfirmation("read_file", true) {
+		t.Error("a nil policy didn't fall back to the toggle")
+	}
+}
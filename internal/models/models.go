@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"agent/internal/agent"
+
+	"google.golang.org/genai"
+)
+
+// Model is a selectable Gemini model and its standard pricing in USD per million
+// tokens, for prompts up to 200K tokens
+type Model struct {
+	ID              string
+	CostPer1MInput  float64
+	CostPer1MOutput float64
+}
+
+// Models lists the models offered in the model selector, in display order
+var Models = []Model{
+	{ID: "gemini-2.5-pro", CostPer1MInput: 1.25, CostPer1MOutput: 10.00},
+	{ID: "gemini-2.5-flash", CostPer1MInput: 0.30, CostPer1MOutput: 2.50},
+	{ID: "gemini-2.5-flash-lite", CostPer1MInput: 0.10, CostPer1MOutput: 0.40},
+	{ID: "gemini-2.0-flash", CostPer1MInput: 0.10, CostPer1MOutput: 0.40},
+	{ID: "gemini-2.0-flash-lite", CostPer1MInput: 0.075, CostPer1MOutput: 0.30},
+	{ID: "gemini-1.5-pro", CostPer1MInput: 1.25, CostPer1MOutput: 5.00},
+	{ID: "gemini-1.5-flash", CostPer1MInput: 0.075, CostPer1MOutput: 0.30},
+}
+
+// IDs returns the IDs of models, in order
+func IDs(models []Model) []string {
+	ids := make([]string, len(models))
+	for i, model := range models {
+		ids[i] = model.ID
+	}
+	return ids
+}
+
+// Lookup returns the model with the given ID
+func Lookup(modelID string) (Model, bool) {
+	for _, model := range Models {
+		if model.ID == modelID {
+			return model, true
+		}
+	}
+	return Model{}, false
+}
+
+// EstimateCost prices usage at modelID's rates, returning 0 for unknown models.
+// Usage accumulated across a model switch is priced entirely at the given model.
+func EstimateCost(usage agent.TokenUsage, modelID string) float64 {
+	model, ok := Lookup(modelID)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.InputTokens)*model.CostPer1MInput + float64(usage.OutputTokens)*model.CostPer1MOutput) / 1e6
+}
+
+// Lister lists the models available to an API key; a genai.Client's Models satisfies it
+type Lister interface {
+	All(ctx context.Context) iter.Seq2[*genai.Model, error]
+}
+
+// unsupportedVariants mark models that can generate content but not hold a chat,
+// such as speech, image and live audio models
+var unsupportedVariants = []string{"tts", "image", "audio", "live", "embedding"}
+
+// isChatModel reports whether the model ID names a Gemini chat model
+func isChatModel(modelID string) bool {
+	return strings.HasPrefix(modelID, "gemini") &&
+		!slices.ContainsFunc(unsupportedVariants, func(variant string) bool { return strings.Contains(modelID, variant) })
+}
+
+// SupportsVision reports whether a model accepts images in prompts, as every Gemini
+// chat model does
+func SupportsVision(modelID string) bool {
+	return isChatModel(modelID)
+}
+
+// FetchAvailable lists the Gemini chat models the API offers. Known models come first,
+// in the order of Models and with their pricing, followed by the rest by name. On error,
+// or if the API returns no usable models, it returns the static list along with the error.
+func FetchAvailable(ctx context.Context, lister Lister) ([]Model, error) {
+	var known, other []Model
+	for info, err := range lister.All(ctx) {
+		if err != nil {
+			return Models, fmt.Errorf("failed to list models: %w", err)
+		}
+		id := strings.TrimPrefix(info.Name, "models/")
+		if !isChatModel(id) || !slices.Contains(info.SupportedActions, "generateContent") {
+			continue
+		}
+		if model, ok := Lookup(id); ok {
+			known = append(known, model)
+		} else {
+			other = append(other, Model{ID: id})
+		}
+	}
+	if len(known)+len(other) == 0 {
+		return Models, fmt.Errorf("no chat models returned")
+	}
+
+	slices.SortFunc(known, func(a, b Model) int {
+		return slices.IndexFunc(Models, func(m Model) bool { return m.ID == a.ID }) -
+			slices.IndexFunc(Models, func(m Model) bool { return m.ID == b.ID })
+	})
+	slices.SortFunc(other, func(a, b Model) int { return strings.Compare(a.ID, b.ID) })
+	return append(known, other...), nil
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"math"
+	"slices"
+	"testing"
+
+	"agent/internal/agent"
+
+	"google.golang.org/genai"
+)
+
+func TestEstimateCost(t *testing.T) {
+	usage := agent.TokenUsage{InputTokens: 2_000_000, OutputTokens: 500_000}
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"gemini-2.5-pro", 2*1.25 + 0.5*10.00},
+		{"gemini-2.5-flash", 2*0.30 + 0.5*2.50},
+		{"gemini-2.0-flash-lite", 2*0.075 + 0.5*0.30},
+		{"not-a-model", 0},
+	}
+	for _, tt := range tests {
+		if got := EstimateCost(usage, tt.model); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EstimateCost(%s) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+
+	if got := EstimateCost(agent.TokenUsage{InputTokens: 1234}, "gemini-2.5-flash"); math.Abs(got-0.0003702) > 1e-12 {
+		t.Errorf("EstimateCost of 1234 flash input tokens = %v, want 0.0003702", got)
+	}
+}
+
+// fakeLister answers All with models, then err if it's set
+type fakeLister struct {
+	models []*genai.Model
+	err    error
+}
+
+func (f fakeLister) All(ctx context.Context) iter.Seq2[*genai.Model, error] {
+	return func(yield func(*genai.Model, error) bool) {
+		for _, model := range f.models {
+			if !yield(model, nil) {
+				return
+			}
+		}
+		if f.err != nil {
+			yield(nil, f.err)
+		}
+	}
+}
+
+// chatModel is a listed model that can generate content
+func chatModel(name string) *genai.Model {
+	return &genai.Model{Name: name, SupportedActions: []string{"generateContent", "countTokens"}}
+}
+
+func TestFetchAvailable(t *testing.T) {
+	lister := fakeLister{models: []*genai.Model{
+		chatModel("models/gemini-exp-1206"),
+		chatModel("models/gemini-2.5-flash"),
+		chatModel("models/gemini-2.5-flash-preview-tts"),
+		{Name: "models/text-embedding-004", SupportedActions: []string{"embedContent"}},
+		{Name: "models/gemini-2.0-flash-001-tuning", SupportedActions: []string{"createTunedModel"}},
+		chatModel("models/gemini-2.5-pro"),
+		chatModel("models/gemini-2.5-flash-lite-preview"),
+	}}
+
+	models, err := FetchAvailable(context.Background(), lister)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Known models keep their selector order and pricing; the rest follow by name
+	want := []string{"gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.5-flash-lite-preview", "gemini-exp-1206"}
+	if got := IDs(models); !slices.Equal(got, want) {
+		t.Errorf("FetchAvailable = %v, want %v", got, want)
+	}
+	if models[0].CostPer1MInput != 1.25 || models[2].CostPer1MInput != 0 {
+		t.Errorf("pricing = %+v, want known models priced and others unpriced", models)
+	}
+}
+
+func TestFetchAvailableFallsBackToStaticList(t *testing.T) {
+	failing := fakeLister{models: []*genai.Model{chatModel("models/gemini-2.5-pro")}, err: errors.New("permission denied")}
+	models, err := FetchAvailable(context.Background(), failing)
+	if err == nil || !slices.Equal(IDs(models), IDs(Models)) {
+		t.Errorf("on a list error FetchAvailable = %v, %v, want the static list and the error", IDs(models), err)
+	}
+
+	empty := fakeLister{models: []*genai.Model{{Name: "models/text-embedding-004", SupportedActions: []string{"embedContent"}}}}
+	models, err = FetchAvailable(context.Background(), empty)
+	if err == nil || !slices.Equal(IDs(models), IDs(Models)) {
+		t.Errorf("with no chat models FetchAvailable = %v, %v, want the static list and an error", IDs(models), err)
+	}
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// AskUserInput defines the input parameters for the ask_user tool
+type AskUserInput struct {
+	Question string `json:"question" jsonschema_description:"The question to ask the user. Be specific and offer options when possible."`
+}
+
+// AskUserDefinition provides the ask_user tool definition
+var AskUserDefinition = agent.ToolDefinition{
+	Name: "ask_user",
+	Description: `Pause and ask the user a clarifying question, then wait for their typed reply.
+Use this when the request is ambiguous (e.g. which of several files was meant) instead of guessing or ending the turn.
+Returns the user's answer.`,
+	InputSchema:      schema.GenerateSchema[AskUserInput](),
+	Function:         AskUser,
+	ReadOnly:         true,
+	SkipConfirmation: true,
+}
+
+// AskUser asks the user a question through the UI and returns the reply
+func AskUser(ctx context.Context, input json.RawMessage) (string, error) {
+	var askUserInput AskUserInput
+	err := json.Unmarshal(input, &askUserInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if strings.TrimSpace(askUserInput.Question) == "" {
+		return "", fmt.Errorf("question cannot be empty")
+	}
+
+	answer, err := agent.AskUser(ctx, askUserInput.Question)
+	if err != nil {
+		return "", fmt.Errorf("failed to get an answer: %w", err)
+	}
+
+	if strings.TrimSpace(answer) == "" {
+		return "The user did not provide an answer.", nil
+	}
+	return answer, nil
+}
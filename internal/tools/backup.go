@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// backupDir holds pre-edit copies of files, relative to the working directory
+const backupDir = ".code-agent/backups"
+
+// backupTimeLayout suffixes each backup so they sort oldest to newest by name
+const backupTimeLayout = "20060102-150405.000000"
+
+// backupFile copies path's current contents into backupDir before it is overwritten.
+// It returns the backup path, or "" if the file doesn't exist yet.
+func backupFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s for backup: %w", path, err)
+	}
+
+	backupPath := backupBase(path) + "." + time.Now().Format(backupTimeLayout)
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.WriteFile(backupPath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backupPath, err)
+	}
+	return backupPath, nil
+}
+
+// backupBase mirrors path's location under backupDir, so backups of same-named files
+// in different directories don't collide
+func backupBase(path string) string {
+	rel := filepath.Clean(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		if cwd, err := os.Getwd(); err == nil {
+			if r, err := filepath.Rel(cwd, abs); err == nil && filepath.IsLocal(r) {
+				rel = r
+			} else {
+				rel = strings.TrimPrefix(abs, filepath.VolumeName(abs))
+			}
+		}
+	}
+	return filepath.Join(backupDir, strings.TrimLeft(rel, `/\`))
+}
+
+// latestBackup returns the newest backup of path, or "" if there is none
+func latestBackup(path string) (string, error) {
+	base := backupBase(path)
+	entries, err := os.ReadDir(filepath.Dir(base))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	// Entries are sorted by name, and the timestamp suffix sorts chronologically
+	prefix := filepath.Base(base) + "."
+	for i := len(entries) - 1; i >= 0; i-- {
+		name := entries[i].Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || entries[i].IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupTimeLayout, stamp); err == nil {
+			return filepath.Join(filepath.Dir(base), name), nil
+		}
+	}
+	return "", nil
+}
+
+// backupNote describes a backup for a tool result
+func backupNote(backupPath string) string {
+	if backupPath == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nBackup of the previous contents: %s", backupPath)
+}
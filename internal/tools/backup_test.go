@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupFileKeepsPreEditContent(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "pkg/config.txt", "version = 1\n")
+
+	result := mustRunTool(t, EditFile, EditFileInput{Path: "pkg/config.txt", OldStr: "1", NewStr: "2"})
+	backupPath, err := latestBackup("pkg/config.txt")
+	if err != nil || backupPath == "" {
+		t.Fatalf("latestBackup = %q, %v; want the edit's backup", backupPath, err)
+	}
+	if !strings.HasPrefix(backupPath, filepath.Join(backupDir, "pkg", "config.txt")+".") {
+		t.Errorf("backup path = %q, want it under %s mirroring the file's path", backupPath, backupDir)
+	}
+	if !strings.Contains(result, backupPath) {
+		t.Errorf("result = %q, want it to name the backup", result)
+	}
+	if got := readFile(t, backupPath); got != "version = 1\n" {
+		t.Errorf("backup = %q, want the content from before the edit", got)
+	}
+	if got := readFile(t, "pkg/config.txt"); got != "version = 2\n" {
+		t.Errorf("file = %q, want the edit applied", got)
+	}
+
+	// A file that doesn't exist yet has nothing to back up
+	if path, err := backupFile("new.txt"); err != nil || path != "" {
+		t.Errorf("backupFile(new.txt) = %q, %v; want no backup", path, err)
+	}
+}
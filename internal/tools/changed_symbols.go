@@ -0,0 +1,249 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// ChangedSymbolsInput defines the input parameters for the changed_symbols tool
+type ChangedSymbolsInput struct {
+	BaseRev string `json:"base_rev" jsonschema_description:"The base git revision (branch, tag, or commit)."`
+	HeadRev string `json:"head_rev,omitempty" jsonschema_description:"The head git revision. Defaults to HEAD."`
+	Path    string `json:"path,omitempty" jsonschema_description:"Optional path to limit the comparison to. Defaults to the whole repository."`
+}
+
+// ChangedSymbolsFile lists the Go declarations changed in one file
+type ChangedSymbolsFile struct {
+	File     string   `json:"file"`
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+// ChangedSymbolsDefinition provides the changed_symbols tool definition
+var ChangedSymbolsDefinition = agent.ToolDefinition{
+	Name: "changed_symbols",
+	Description: `List the Go functions, methods, and types that were added, removed, or modified between two git revisions.
+Maps diff hunks to their enclosing declarations, giving the semantic surface of a change for review.
+Non-Go files are listed by name only.`,
+	InputSchema: schema.GenerateSchema[ChangedSymbolsInput](),
+	Function:    ChangedSymbols,
+	ReadOnly:    true,
+}
+
+// hunkHeader matches unified diff hunk headers, e.g. "@@ -12,3 +12,5 @@"
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// lineSpan is an inclusive range of line numbers
+type lineSpan struct{ start, end int }
+
+// ChangedSymbols reports Go declarations changed between two revisions
+func ChangedSymbols(ctx context.Context, input json.RawMessage) (string, error) {
+	var changedSymbolsInput ChangedSymbolsInput
+	err := json.Unmarshal(input, &changedSymbolsInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if changedSymbolsInput.BaseRev == "" {
+		return "", fmt.Errorf("base_rev must be provided")
+	}
+	headRev := changedSymbolsInput.HeadRev
+	if headRev == "" {
+		headRev = "HEAD"
+	}
+	path := changedSymbolsInput.Path
+	if path == "" {
+		path = "."
+	}
+
+	nameStatus, err := runGit(ctx, "diff", "--name-status", "--no-renames", changedSymbolsInput.BaseRev, headRev, "--", path)
+	if err != nil {
+		return "", err
+	}
+
+	var results []ChangedSymbolsFile
+	var otherFiles []string
+	for _, line := range strings.Split(strings.TrimSpace(nameStatus), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		status, file := fields[0], fields[1]
+		if !strings.HasSuffix(file, ".go") {
+			otherFiles = append(otherFiles, fmt.Sprintf("%s %s", status, file))
+			continue
+		}
+
+		changed, err := changedSymbolsInFile(ctx, changedSymbolsInput.BaseRev, headRev, file, status)
+		if err != nil {
+			return "", err
+		}
+		if len(changed.Added)+len(changed.Removed)+len(changed.Modified) > 0 {
+			results = append(results, changed)
+		}
+	}
+
+	if len(results) == 0 && len(otherFiles) == 0 {
+		return fmt.Sprintf("No changes between %s and %s.", changedSymbolsInput.BaseRev, headRev), nil
+	}
+
+	output := struct {
+		GoFiles    []ChangedSymbolsFile `json:"go_files"`
+		OtherFiles []string             `json:"other_files,omitempty"`
+	}{GoFiles: results, OtherFiles: otherFiles}
+
+	resultJSON, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal changed symbols: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// changedSymbolsInFile maps the diff hunks of one Go file to the declarations they touch
+func changedSymbolsInFile(ctx context.Context, baseRev, headRev, file, status string) (ChangedSymbolsFile, error) {
+	result := ChangedSymbolsFile{File: file}
+
+	var baseDecls, headDecls map[string]lineSpan
+	var err error
+	if status != "A" {
+		if baseDecls, err = declarationsAt(ctx, baseRev, file); err != nil {
+			return result, err
+		}
+	}
+	if status != "D" {
+		if headDecls, err = declarationsAt(ctx, headRev, file); err != nil {
+			return result, err
+		}
+	}
+
+	diff, err := runGit(ctx, "diff", "-U0", baseRev, headRev, "--", file)
+	if err != nil {
+		return result, err
+	}
+	baseHunks, headHunks := parseHunks(diff)
+
+	for name, span := range headDecls {
+		if _, ok := baseDecls[name]; !ok {
+			result.Added = append(result.Added, name)
+		} else if overlapsAny(span, headHunks) || overlapsAny(baseDecls[name], baseHunks) {
+			result.Modified = append(result.Modified, name)
+		}
+	}
+	for name := range baseDecls {
+		if _, ok := headDecls[name]; !ok {
+			result.Removed = append(result.Removed, name)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Modified)
+	return result, nil
+}
+
+// declarationsAt parses a Go file at a revision and returns each top-level declaration's line span
+func declarationsAt(ctx context.Context, rev, file string) (map[string]lineSpan, error) {
+	source, err := runGit(ctx, "show", rev+":"+file)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, source, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s at %s: %w", file, rev, err)
+	}
+
+	decls := make(map[string]lineSpan)
+	add := func(name string, node ast.Node) {
+		decls[name] = lineSpan{fset.Position(node.Pos()).Line, fset.Position(node.End()).Line}
+	}
+	for _, decl := range parsed.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name := "func " + d.Name.Name
+			if d.Recv != nil {
+				name = fmt.Sprintf("func (%s) %s", receiverTypeName(d.Recv), d.Name.Name)
+			}
+			add(name, d)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add("type "+s.Name.Name, s)
+				case *ast.ValueSpec:
+					for _, ident := range s.Names {
+						add(fmt.Sprintf("%s %s", d.Tok, ident.Name), s)
+					}
+				}
+			}
+		}
+	}
+	return decls, nil
+}
+
+// parseHunks returns the changed line spans on the base and head sides of a -U0 diff
+func parseHunks(diff string) (base, head []lineSpan) {
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		m := hunkHeader.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		base = append(base, hunkSpan(m[1], m[2]))
+		head = append(head, hunkSpan(m[3], m[4]))
+	}
+	return base, head
+}
+
+// hunkSpan converts a hunk start and optional count into a line span; pure insertions
+// and deletions have a zero count and touch the line they sit next to
+func hunkSpan(startStr, countStr string) lineSpan {
+	start, _ := strconv.Atoi(startStr)
+	count := 1
+	if countStr != "" {
+		count, _ = strconv.Atoi(countStr)
+	}
+	if count == 0 {
+		return lineSpan{start, start + 1}
+	}
+	return lineSpan{start, start + count - 1}
+}
+
+// overlapsAny reports whether span intersects any of the hunks
+func overlapsAny(span lineSpan, hunks []lineSpan) bool {
+	for _, h := range hunks {
+		if h.start <= span.end && span.start <= h.end {
+			return true
+		}
+	}
+	return false
+}
+
+// runGit runs a git command and returns its stdout, including stderr in errors
+func runGit(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to run git: %w", err)
+	}
+	return string(output), nil
+}
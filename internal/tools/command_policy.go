@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"agent/internal/config"
+)
+
+// commandSeparators split a shell command line into the commands it runs
+var commandSeparators = regexp.MustCompile(`&&|\|\||[;&|\n]`)
+
+// checkShellCommand refuses commands matching the user's DeniedCommands preference and,
+// when AllowedCommands is set, any command it doesn't match. Unreadable preferences
+// refuse every command, since the lists in them can't be checked.
+func checkShellCommand(command string) error {
+	prefs, err := config.LoadPreferences()
+	if err != nil {
+		return fmt.Errorf("can't check command policy: %w", err)
+	}
+	return checkCommand(command, prefs.AllowedCommands, prefs.DeniedCommands)
+}
+
+// checkCommand applies allowed and denied patterns to each command in a command line,
+// so a permitted command can't be chained with a forbidden one. Commands hidden in
+// substitutions or scripts aren't seen, so the lists limit mistakes rather than sandbox.
+func checkCommand(command string, allowed, denied []string) error {
+	segments := commandSegments(command)
+	for _, segment := range segments {
+		for _, pattern := range denied {
+			matched, err := matchesCommand(segment, pattern)
+			if err != nil {
+				return err
+			}
+			if matched {
+				return fmt.Errorf("refused to run %q: it matches the denied command pattern %q", segment, pattern)
+			}
+		}
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, segment := range segments {
+		permitted := false
+		for _, pattern := range allowed {
+			matched, err := matchesCommand(segment, pattern)
+			if err != nil {
+				return err
+			}
+			if matched {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			return fmt.Errorf("refused to run %q: it matches none of the allowed command patterns", segment)
+		}
+	}
+	return nil
+}
+
+// commandSegments splits a command line at its separators, normalizing whitespace
+func commandSegments(command string) []string {
+	var segments []string
+	for _, part := range commandSeparators.Split(command, -1) {
+		if segment := strings.Join(strings.Fields(part), " "); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// matchesCommand reports whether a command matches a pattern: /.../ is a regular
+// expression, anything else matches commands that start with the pattern's words
+func matchesCommand(segment, pattern string) (bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false, fmt.Errorf("invalid command pattern %q: %w", pattern, err)
+		}
+		return re.MatchString(segment), nil
+	}
+
+	prefix := strings.Join(strings.Fields(pattern), " ")
+	return prefix != "" && (segment == prefix || strings.HasPrefix(segment, prefix+" ")), nil
+}
+
+// precheckShellCommand refuses denied run_shell_command calls before they're confirmed
+func precheckShellCommand(input json.RawMessage) error {
+	var runShellCommandInput RunShellCommandInput
+	if err := json.Unmarshal(input, &runShellCommandInput); err != nil {
+		return nil // RunShellCommand reports malformed input
+	}
+	return checkShellCommand(runShellCommandInput.Command)
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCommand(t *testing.T) {
+	allowed := []string{"go test", "go build", "/^git (status|diff)\\b/"}
+	denied := []string{"rm -rf"}
+
+	tests := []struct {
+		command string
+		refused string // part of the expected error, or "" if the command may run
+	}{
+		{"go test ./...", ""},
+		{"go   test  -run Foo", ""},
+		{"git status --short", ""},
+		{"git diff HEAD~1", ""},
+		{"rm -rf /tmp/build", `matches the denied command pattern "rm -rf"`},
+		{"go test && rm -rf /", `refused to run "rm -rf /"`},
+		{"go build; go test", ""},
+		{"go testing", "matches none of the allowed command patterns"},
+		{"git push", "matches none of the allowed command patterns"},
+		{"go test | tee out.txt", `refused to run "tee out.txt"`},
+	}
+	for _, tt := range tests {
+		err := checkCommand(tt.command, allowed, denied)
+		switch {
+		case tt.refused == "" && err != nil:
+			t.Errorf("checkCommand(%q) = %v, want it allowed", tt.command, err)
+		case tt.refused != "" && (err == nil || !strings.Contains(err.Error(), tt.refused)):
+			t.Errorf("checkCommand(%q) = %v, want an error containing %q", tt.command, err, tt.refused)
+		}
+	}
+}
+
+func TestCheckCommandDeniedRegex(t *testing.T) {
+	// With no allowlist, only denied commands are refused
+	denied := []string{"/^rm\\s+-(rf|fr)\\b/"}
+	for _, command := range []string{"rm -fr build", "make && rm  -rf /"} {
+		if err := checkCommand(command, nil, denied); err == nil {
+			t.Errorf("checkCommand(%q) allowed it, want it refused", command)
+		}
+	}
+	for _, command := range []string{"rm build/out.txt", "echo rm -rf"} {
+		if err := checkCommand(command, nil, denied); err != nil {
+			t.Errorf("checkCommand(%q) = %v, want it allowed", command, err)
+		}
+	}
+
+	if err := checkCommand("ls", nil, []string{"/[/"}); err == nil || !strings.Contains(err.Error(), "invalid command pattern") {
+		t.Errorf("invalid pattern error = %v, want it reported", err)
+	}
+}
+
+func TestCheckShellCommandFailsClosedOnBadPreferences(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeFile(t, filepath.Join(home, ".code-agent", "config.json"), `{"denied_commands": ["rm -rf"]`)
+
+	for _, command := range []string{"ls", "rm -rf /"} {
+		if err := checkShellCommand(command); err == nil || !strings.Contains(err.Error(), "can't check command policy") {
+			t.Errorf("checkShellCommand(%q) with malformed preferences = %v, want it refused", command, err)
+		}
+	}
+
+	// Without a preferences file there is no policy to apply
+	if err := os.Remove(filepath.Join(home, ".code-agent", "config.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkShellCommand("ls"); err != nil {
+		t.Errorf("checkShellCommand without preferences = %v, want it allowed", err)
+	}
+}
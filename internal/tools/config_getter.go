@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigGetInput defines the input parameters for the config_get tool
+type ConfigGetInput struct {
+	Path           string `json:"path" jsonschema_description:"The relative path of a .json, .yaml or .yml file."`
+	Key            string `json:"key" jsonschema_description:"Dotted key path, e.g. 'scripts.build' or 'jobs.test.steps.0.name'. Numeric segments index into arrays. Leave empty to return the whole document."`
+	AllowSensitive bool   `json:"allow_sensitive,omitempty" jsonschema_description:"Set to true to read a file that looks like it holds secrets (.env, keys, credentials). Only do this when the user explicitly asks."`
+}
+
+// ConfigGetDefinition provides the config_get tool definition
+var ConfigGetDefinition = agent.ToolDefinition{
+	Name:        "config_get",
+	Description: "Read a single value from a JSON or YAML config file (package.json, tsconfig.json, CI workflows, ...) by dotted key path, without reading the whole file. Scalars are returned as plain text; objects and arrays are returned in the file's own format.",
+	InputSchema: schema.GenerateSchema[ConfigGetInput](),
+	Function:    ConfigGet,
+	ReadOnly:    true,
+}
+
+// ConfigGet returns the value at a key path in a JSON or YAML file
+func ConfigGet(ctx context.Context, input json.RawMessage) (string, error) {
+	var configGetInput ConfigGetInput
+	err := json.Unmarshal(input, &configGetInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if err := checkSensitiveFile(configGetInput.Path, configGetInput.AllowSensitive); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(configGetInput.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	isYAML := false
+	switch strings.ToLower(filepath.Ext(configGetInput.Path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		isYAML = true
+	default:
+		return "", fmt.Errorf("unsupported config format %q: expected .json, .yaml or .yml", filepath.Ext(configGetInput.Path))
+	}
+
+	var document interface{}
+	if isYAML {
+		err = yaml.Unmarshal(content, &document)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber() // keep numbers exactly as written
+		err = decoder.Decode(&document)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", configGetInput.Path, err)
+	}
+
+	value, err := lookupKeyPath(document, configGetInput.Key)
+	if err != nil {
+		return "", err
+	}
+
+	return formatConfigValue(value, isYAML)
+}
+
+// lookupKeyPath walks a decoded document along a dotted key path
+func lookupKeyPath(document interface{}, keyPath string) (interface{}, error) {
+	if keyPath == "" {
+		return document, nil
+	}
+
+	current := document
+	walked := ""
+	for _, segment := range strings.Split(keyPath, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", joinKeyPath(walked, segment))
+			}
+			current = value
+		case map[interface{}]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", joinKeyPath(walked, segment))
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("index %q out of range at %q (%d elements)", segment, walked, len(node))
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("cannot look up %q: %q is a scalar", segment, walked)
+		}
+		walked = joinKeyPath(walked, segment)
+	}
+
+	return current, nil
+}
+
+// joinKeyPath appends a segment to a dotted key path
+func joinKeyPath(prefix, segment string) string {
+	if prefix == "" {
+		return segment
+	}
+	return prefix + "." + segment
+}
+
+// formatConfigValue renders scalars as plain text and collections in the source format
+func formatConfigValue(value interface{}, isYAML bool) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return v, nil
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		var out []byte
+		var err error
+		if isYAML {
+			out, err = yaml.Marshal(v)
+		} else {
+			out, err = json.MarshalIndent(v, "", "  ")
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to format value: %w", err)
+		}
+		return strings.TrimRight(string(out), "\n"), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
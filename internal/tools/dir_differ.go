@@ -0,0 +1,173 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// DiffDirsInput defines the input parameters for the diff_dirs tool
+type DiffDirsInput struct {
+	DirA          string `json:"dir_a" jsonschema_description:"The relative path of the first directory."`
+	DirB          string `json:"dir_b" jsonschema_description:"The relative path of the second directory."`
+	IncludeHidden *bool  `json:"include_hidden,omitempty" jsonschema_description:"Whether to compare hidden files and directories (those starting with a dot). Defaults to the user's preference (usually false)."`
+}
+
+// DiffDirsOutput defines the output of the diff_dirs tool
+type DiffDirsOutput struct {
+	OnlyInA   []string `json:"only_in_a"`
+	OnlyInB   []string `json:"only_in_b"`
+	Differ    []string `json:"differ"`
+	Identical int      `json:"identical"`
+}
+
+// DiffDirsDefinition provides the diff_dirs tool definition
+var DiffDirsDefinition = agent.ToolDefinition{
+	Name:        "diff_dirs",
+	Description: "Compare two directory trees. Returns files only in dir_a, files only in dir_b, and files present in both whose contents differ (by size, then SHA-256). Use this to check generated output against a reference layout in one call.",
+	InputSchema: schema.GenerateSchema[DiffDirsInput](),
+	Function:    DiffDirs,
+	ReadOnly:    true,
+}
+
+// DiffDirs compares the files in two directory trees
+func DiffDirs(ctx context.Context, input json.RawMessage) (string, error) {
+	var diffDirsInput DiffDirsInput
+	err := json.Unmarshal(input, &diffDirsInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if diffDirsInput.DirA == "" || diffDirsInput.DirB == "" {
+		return "", fmt.Errorf("dir_a and dir_b must be provided")
+	}
+
+	includeHidden := resolveIncludeHidden(diffDirsInput.IncludeHidden)
+	filesA, err := collectFiles(diffDirsInput.DirA, includeHidden)
+	if err != nil {
+		return "", err
+	}
+	filesB, err := collectFiles(diffDirsInput.DirB, includeHidden)
+	if err != nil {
+		return "", err
+	}
+
+	output := DiffDirsOutput{OnlyInA: []string{}, OnlyInB: []string{}, Differ: []string{}}
+	for rel, sizeA := range filesA {
+		sizeB, ok := filesB[rel]
+		if !ok {
+			output.OnlyInA = append(output.OnlyInA, rel)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		same := sizeA == sizeB
+		if same {
+			same, err = sameContent(filepath.Join(diffDirsInput.DirA, rel), filepath.Join(diffDirsInput.DirB, rel))
+			if err != nil {
+				return "", err
+			}
+		}
+		if same {
+			output.Identical++
+		} else {
+			output.Differ = append(output.Differ, rel)
+		}
+	}
+	for rel := range filesB {
+		if _, ok := filesA[rel]; !ok {
+			output.OnlyInB = append(output.OnlyInB, rel)
+		}
+	}
+
+	sort.Strings(output.OnlyInA)
+	sort.Strings(output.OnlyInB)
+	sort.Strings(output.Differ)
+
+	resultJSON, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal directory diff: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// collectFiles maps every regular file under root (by slash-separated relative path) to its size
+func collectFiles(root string, includeHidden bool) (map[string]int64, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("path is not a directory: %s", root)
+	}
+
+	files := make(map[string]int64)
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if path != root && !includeHidden && isHiddenName(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// sameContent reports whether two files have the same SHA-256 hash
+func sameContent(pathA, pathB string) (bool, error) {
+	hashA, err := hashFile(pathA)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(pathB)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB, nil
+}
+
+// hashFile returns the hex SHA-256 digest of a file's contents
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file %s: %w", path, err)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"agent/internal/agent"
+)
+
+// dryRunPrefix marks the results of tools that described a change instead of making it
+const dryRunPrefix = "[DRY RUN] "
+
+// dryRunContextLines is how many unchanged lines surround the change in a preview diff,
+// unless the context sets another amount
+const dryRunContextLines = 3
+
+// dryRunNote describes an action a tool would have taken
+func dryRunNote(format string, args ...interface{}) string {
+	return dryRunPrefix + fmt.Sprintf(format, args...) + " No files were changed."
+}
+
+// dryRunEdit describes a change to path's content with a summary and a preview diff
+func dryRunEdit(ctx context.Context, path, oldContent, newContent, format string, args ...interface{}) string {
+	if oldContent == newContent {
+		return dryRunNote(format, args...) + " The content would be unchanged."
+	}
+	return dryRunNote(format, args...) + "\n\n" + previewDiff(path, oldContent, newContent, agent.DiffContextLines(ctx, dryRunContextLines))
+}
+
+// previewDiff renders the change from oldContent to newContent as a unified diff with
+// one hunk spanning everything between the lines they share at the start and end.
+// That's coarser than a minimal diff, but cheap and exact for previewing an edit.
+func previewDiff(path, oldContent, newContent string, contextLines int) string {
+	oldLines, newLines := diffLines(oldContent), diffLines(newContent)
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	start := max(prefix-contextLines, 0)
+	oldEnd := min(len(oldLines)-suffix+contextLines, len(oldLines))
+	newEnd := min(len(newLines)-suffix+contextLines, len(newLines))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+	fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(start, oldEnd-start), hunkRange(start, newEnd-start))
+	for _, line := range oldLines[start:prefix] {
+		b.WriteString(" " + line + "\n")
+	}
+	for _, line := range oldLines[prefix : len(oldLines)-suffix] {
+		b.WriteString("-" + line + "\n")
+	}
+	for _, line := range newLines[prefix : len(newLines)-suffix] {
+		b.WriteString("+" + line + "\n")
+	}
+	for _, line := range oldLines[len(oldLines)-suffix : oldEnd] {
+		b.WriteString(" " + line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// diffLines splits content into lines, without an empty last line for a trailing newline
+func diffLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// hunkRange formats a hunk header range from a 0-based start and a line count
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent/internal/agent"
+)
+
+// snapshot returns the content of every file under the working directory, by path
+func snapshot(t *testing.T) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		files[path] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestDryRunLeavesFilesUntouched(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "notes.txt", numberedLines(5))
+	writeFile(t, "build/out.txt", "artifact\n")
+	before := snapshot(t)
+	ctx := agent.WithDryRun(context.Background())
+
+	calls := []struct {
+		name  string
+		tool  func(context.Context, json.RawMessage) (string, error)
+		input any
+	}{
+		{"write new file", WriteFile, WriteFileInput{Path: "new.txt", Content: "hello\n"}},
+		{"overwrite", WriteFile, WriteFileInput{Path: "notes.txt", Content: "replaced\n"}},
+		{"append", WriteFile, WriteFileInput{Path: "notes.txt", Content: "more\n", Append: true}},
+		{"edit", EditFile, EditFileInput{Path: "notes.txt", OldStr: "line 3", NewStr: "line three"}},
+		{"patch", ApplyPatch, ApplyPatchInput{Path: "notes.txt", Patch: "@@ -1,2 +1,2 @@\n-line 1\n+line one\n line 2\n"}},
+		{"delete file", DeleteFile, DeleteFileInput{Path: "notes.txt"}},
+		{"delete directory", DeleteFile, DeleteFileInput{Path: "build", Recursive: true}},
+		{"shell command", RunShellCommand, RunShellCommandInput{Command: "rm -rf build"}},
+	}
+	for _, call := range calls {
+		result, err := runTool(t, ctx, call.tool, call.input)
+		if err != nil {
+			t.Errorf("%s: %v", call.name, err)
+			continue
+		}
+		if !strings.HasPrefix(result, dryRunPrefix) || !strings.Contains(result, "No files were changed.") {
+			t.Errorf("%s: result = %q, want it marked as a dry run", call.name, result)
+		}
+		if after := snapshot(t); !maps.Equal(after, before) {
+			t.Fatalf("%s changed the workspace in a dry run: %v", call.name, after)
+		}
+	}
+	if _, err := os.Stat(backupDir); !os.IsNotExist(err) {
+		t.Errorf("dry run created %s (stat err %v)", backupDir, err)
+	}
+}
+
+func TestPreviewDiff(t *testing.T) {
+	oldContent := numberedLines(10)
+	newContent := strings.Replace(oldContent, "line 5\nline 6\n", "line five\n", 1)
+
+	want := `--- a/notes.txt
++++ b/notes.txt
+@@ -3,6 +3,5 @@
+ line 3
+ line 4
+-line 5
+-line 6
++line five
+ line 7
+ line 8`
+	if got := previewDiff("notes.txt", oldContent, newContent, 2); got != want {
+		t.Errorf("previewDiff =\n%s\nwant\n%s", got, want)
+	}
+
+	// A new file is all additions, with an empty old range
+	want = "--- a/new.txt\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+one\n+two"
+	if got := previewDiff("new.txt", "", "one\ntwo\n", 3); got != want {
+		t.Errorf("previewDiff of a new file =\n%s\nwant\n%s", got, want)
+	}
+
+	// Context stops at the start and end of the file
+	want = "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-a\n+A\n b"
+	if got := previewDiff("f", "a\nb\n", "A\nb\n", 3); got != want {
+		t.Errorf("previewDiff at the top =\n%s\nwant\n%s", got, want)
+	}
+}
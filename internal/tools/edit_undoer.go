@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// UndoEditInput defines the input parameters for the undo_edit tool
+type UndoEditInput struct {
+	Path string `json:"path" jsonschema_description:"The path of the file whose last edit_file or write_file change should be undone."`
+}
+
+// UndoEditDefinition provides the undo_edit tool definition
+var UndoEditDefinition = agent.ToolDefinition{
+	Name:        "undo_edit",
+	Description: "Restore a file to its contents before the most recent edit_file or write_file change, using the automatic backup. Call it repeatedly to step further back.",
+	InputSchema: schema.GenerateSchema[UndoEditInput](),
+	Function:    UndoEdit,
+	EditsFiles:  true,
+}
+
+// UndoEdit restores the newest backup of a file and removes that backup
+func UndoEdit(ctx context.Context, input json.RawMessage) (string, error) {
+	var undoEditInput UndoEditInput
+	err := json.Unmarshal(input, &undoEditInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if undoEditInput.Path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	backupPath, err := latestBackup(undoEditInput.Path)
+	if err != nil {
+		return "", err
+	}
+	if backupPath == "" {
+		return fmt.Sprintf("Nothing to undo for %s: no backup found.", undoEditInput.Path), nil
+	}
+
+	content, err := os.ReadFile(backupPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup %s: %w", backupPath, err)
+	}
+	if agent.IsDryRun(ctx) {
+		current, _ := os.ReadFile(undoEditInput.Path)
+		return dryRunEdit(ctx, undoEditInput.Path, string(current), string(content),
+			"Would restore %s from backup %s.", undoEditInput.Path, backupPath), nil
+	}
+	if err := writeFileAtomic(undoEditInput.Path, content); err != nil {
+		return "", fmt.Errorf("failed to restore %s: %w", undoEditInput.Path, err)
+	}
+	if err := os.Remove(backupPath); err != nil {
+		return "", fmt.Errorf("restored %s but failed to remove backup %s: %w", undoEditInput.Path, backupPath, err)
+	}
+
+	return fmt.Sprintf("Restored %s from backup %s.", undoEditInput.Path, backupPath), nil
+}
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"os"
+	"testing"
+)
+
+func TestUndoEditRestoresOriginal(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	if err := os.Chmod("main.go", 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mustRunTool(t, EditFile, EditFileInput{Path: "main.go", OldStr: "main() {}", NewStr: "main() { panic(1) }"})
+	if got := readFile(t, "main.go"); got != "package main\n\nfunc main() { panic(1) }\n" {
+		t.Fatalf("after edit = %q", got)
+	}
+
+	mustRunTool(t, UndoEdit, UndoEditInput{Path: "main.go"})
+	if got := readFile(t, "main.go"); got != "package main\n\nfunc main() {}\n" {
+		t.Errorf("after undo = %q, want the original", got)
+	}
+	info, err := os.Stat("main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode after undo = %v, want 0600 kept", info.Mode().Perm())
+	}
+
+	if result := mustRunTool(t, UndoEdit, UndoEditInput{Path: "main.go"}); result != "Nothing to undo for main.go: no backup found." {
+		t.Errorf("second undo = %q, want nothing left to undo", result)
+	}
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// OpenInEditorInput defines the input parameters for the open_in_editor tool
+type OpenInEditorInput struct {
+	Path string `json:"path" jsonschema_description:"The relative path of the file to open."`
+	Line int    `json:"line,omitempty" jsonschema_description:"The line number to place the cursor on (1-indexed). Defaults to 1."`
+}
+
+// OpenInEditorDefinition provides the open_in_editor tool definition
+var OpenInEditorDefinition = agent.ToolDefinition{
+	Name: "open_in_editor",
+	Description: `Open a file at a specific line in the user's editor ($VISUAL or $EDITOR).
+Use this to hand off to the user for edits they should review or make themselves, e.g. after locating a bug.
+The tool returns once the user closes the editor.`,
+	InputSchema: schema.GenerateSchema[OpenInEditorInput](),
+	Function:    OpenInEditor,
+}
+
+// OpenInEditor launches the user's editor on a file at the given line
+func OpenInEditor(ctx context.Context, input json.RawMessage) (string, error) {
+	var openInEditorInput OpenInEditorInput
+	err := json.Unmarshal(input, &openInEditorInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if openInEditorInput.Path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	if _, err := os.Stat(openInEditorInput.Path); err != nil {
+		return "", fmt.Errorf("failed to stat file %s: %w", openInEditorInput.Path, err)
+	}
+
+	if agent.IsDryRun(ctx) {
+		return dryRunNote("Would open %s in the editor.", openInEditorInput.Path), nil
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		return "", fmt.Errorf("neither $VISUAL nor $EDITOR is set")
+	}
+
+	line := openInEditorInput.Line
+	if line <= 0 {
+		line = 1
+	}
+
+	// The editor variable may include flags, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	args := append(fields[1:], editorLineArgs(fields[0], openInEditorInput.Path, line)...)
+	cmd := exec.CommandContext(ctx, fields[0], args...)
+
+	if err := agent.RunInteractiveProcess(ctx, cmd); err != nil {
+		return "", fmt.Errorf("editor exited with error: %w", err)
+	}
+
+	return fmt.Sprintf("Opened %s at line %d in %s. The user has closed the editor; re-read the file to see any changes.", openInEditorInput.Path, line, fields[0]), nil
+}
+
+// editorLineArgs returns the arguments that open path at line for the given editor
+func editorLineArgs(editor, path string, line int) []string {
+	switch strings.TrimSuffix(filepath.Base(editor), ".exe") {
+	case "code", "code-insiders", "cursor", "codium":
+		return []string{"-g", fmt.Sprintf("%s:%d", path, line)}
+	case "subl", "zed":
+		return []string{fmt.Sprintf("%s:%d", path, line)}
+	default:
+		// vi, vim, nvim, nano, emacs, micro, and most terminal editors accept +line
+		return []string{fmt.Sprintf("+%d", line), path}
+	}
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// DeleteFileInput defines the input parameters for the delete_file tool
+type DeleteFileInput struct {
+	Path      string `json:"path" jsonschema_description:"The relative path of the file or directory to delete."`
+	Recursive bool   `json:"recursive,omitempty" jsonschema_description:"Set to true to delete a directory and everything in it. Required for directories."`
+}
+
+// DeleteFileDefinition provides the delete_file tool definition
+var DeleteFileDefinition = agent.ToolDefinition{
+	Name:        "delete_file",
+	Description: "Delete a file, or a directory and its contents when recursive is true. The current directory itself can't be deleted.",
+	InputSchema: schema.GenerateSchema[DeleteFileInput](),
+	Function:    DeleteFile,
+	EditsFiles:  true,
+}
+
+// DeleteFile removes a file, or a directory tree when recursive is set
+func DeleteFile(ctx context.Context, input json.RawMessage) (string, error) {
+	var deleteFileInput DeleteFileInput
+	err := json.Unmarshal(input, &deleteFileInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if deleteFileInput.Path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	// Refuse to delete the working directory or anything that contains it
+	target, err := filepath.Abs(deleteFileInput.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", deleteFileInput.Path, err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	if rel, err := filepath.Rel(target, cwd); err == nil && filepath.IsLocal(rel) {
+		return "", fmt.Errorf("refusing to delete %s: it is or contains the current directory", deleteFileInput.Path)
+	}
+
+	info, err := os.Lstat(deleteFileInput.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", deleteFileInput.Path, err)
+	}
+
+	if info.IsDir() {
+		if !deleteFileInput.Recursive {
+			return "", fmt.Errorf("%s is a directory; pass recursive=true to delete it and its contents", deleteFileInput.Path)
+		}
+		if agent.IsDryRun(ctx) {
+			return dryRunNote("Would delete directory %s and its contents.", deleteFileInput.Path), nil
+		}
+		if err := os.RemoveAll(deleteFileInput.Path); err != nil {
+			return "", fmt.Errorf("failed to delete directory %s: %w", deleteFileInput.Path, err)
+		}
+		return fmt.Sprintf("Deleted directory %s and its contents", deleteFileInput.Path), nil
+	}
+
+	if agent.IsDryRun(ctx) {
+		return dryRunNote("Would delete %s (%d bytes).", deleteFileInput.Path, info.Size()), nil
+	}
+	if err := os.Remove(deleteFileInput.Path); err != nil {
+		return "", fmt.Errorf("failed to delete %s: %w", deleteFileInput.Path, err)
+	}
+	return fmt.Sprintf("Deleted path %s", deleteFileInput.Path), nil
+}
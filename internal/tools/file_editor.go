@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"agent/internal/agent"
@@ -14,8 +15,15 @@ import (
 // EditFileInput defines the input parameters for the edit_file tool
 type EditFileInput struct {
 	Path   string `json:"path" jsonschema_description:"The path to the file"`
-	OldStr string `json:"old_str" jsonschema_description:"Text to search for. All occurrences will be replaced."`
+	OldStr string `json:"old_str" jsonschema_description:"Text to search for. All occurrences will be replaced unless count is set."`
 	NewStr string `json:"new_str" jsonschema_description:"Text to replace old_str with"`
+	Count  int    `json:"count,omitempty" jsonschema_description:"Replace only the first count occurrences. 0 (the default) replaces all of them."`
+
+	IsRegex       bool `json:"is_regex,omitempty" jsonschema_description:"Treat old_str as a Go regular expression; new_str may then reference capture groups as $1 or ${name}."`
+	CaseSensitive bool `json:"case_sensitive,omitempty" jsonschema_description:"With is_regex, match case-sensitively. Regex matching is case-insensitive by default; literal matching is always case-sensitive."`
+
+	NoBackup       bool `json:"no_backup,omitempty" jsonschema_description:"Skip saving the previous contents under .code-agent/backups before editing."`
+	AllowGenerated bool `json:"allow_generated,omitempty" jsonschema_description:"Set to true to modify a generated file (go.sum, *.pb.go, lock files, generated/ directories). Only do this when the user explicitly asks; otherwise regenerate the file."`
 }
 
 // EditFileDefinition provides the edit_file tool definition
@@ -23,12 +31,13 @@ var EditFileDefinition = agent.ToolDefinition{
 	Name: "edit_file",
 	Description: `Make edits to a text file.
 
-Replaces ALL occurrences of 'old_str' with 'new_str' in the given file. 'old_str' and 'new_str' MUST be different from each other.
+Replaces ALL occurrences of 'old_str' with 'new_str' in the given file, or only the first 'count' of them when count is set. 'old_str' and 'new_str' MUST be different from each other.
 
 The file MUST exist. This tool cannot be used to create new files.
 `,
 	InputSchema: schema.GenerateSchema[EditFileInput](),
 	Function:    EditFile,
+	EditsFiles:  true,
 }
 
 // EditFile edits a file by replacing old_str with new_str
@@ -43,23 +52,94 @@ func EditFile(ctx context.Context, input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("invalid input parameters: path and old_str must be non-empty, and old_str must be different from new_str")
 	}
 
+	if editFileInput.Count < 0 {
+		return "", fmt.Errorf("count cannot be negative")
+	}
+
+	if err := checkGeneratedFile(editFileInput.Path, editFileInput.AllowGenerated); err != nil {
+		return "", err
+	}
+
 	content, err := os.ReadFile(editFileInput.Path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
 	oldContent := string(content)
-	replacements := strings.Count(oldContent, editFileInput.OldStr)
-	if replacements == 0 {
+	var newContent string
+	var occurrences, replacements int
+	if editFileInput.IsRegex {
+		pattern := editFileInput.OldStr
+		if !editFileInput.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid regular expression: %w", err)
+		}
+		newContent, occurrences, replacements = regexReplace(re, oldContent, editFileInput.NewStr, editFileInput.Count)
+	} else {
+		occurrences = strings.Count(oldContent, editFileInput.OldStr)
+		replacements = occurrences
+		if editFileInput.Count > 0 {
+			replacements = min(editFileInput.Count, occurrences)
+		}
+		newContent = strings.Replace(oldContent, editFileInput.OldStr, editFileInput.NewStr, replacements)
+	}
+
+	if occurrences == 0 {
 		return "No occurrences of `old_str` found. No changes made to the file.", nil
 	}
 
-	newContent := strings.ReplaceAll(oldContent, editFileInput.OldStr, editFileInput.NewStr)
+	if agent.IsDryRun(ctx) {
+		return dryRunEdit(ctx, editFileInput.Path, oldContent, newContent, "Would make %d replacement(s) in %s.", replacements, editFileInput.Path), nil
+	}
+
+	// Remember whether the file parsed before the edit so we only warn about new breakage
+	errorsBefore, _, _ := syntaxErrors(ctx, editFileInput.Path)
+
+	var backupPath string
+	if !editFileInput.NoBackup {
+		if backupPath, err = backupFile(editFileInput.Path); err != nil {
+			return "", err
+		}
+	}
 
 	err = os.WriteFile(editFileInput.Path, []byte(newContent), 0644)
 	if err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return fmt.Sprintf("OK. Edited file successfully. Made %d replacement(s).", replacements), nil
+	result := fmt.Sprintf("OK. Edited file successfully. Made %d replacement(s).", replacements)
+	if editFileInput.Count > 0 {
+		result = fmt.Sprintf("OK. Edited file successfully. Made %d replacement(s) of %d requested; %d occurrence(s) left unchanged.",
+			replacements, editFileInput.Count, occurrences-replacements)
+	}
+	result += backupNote(backupPath)
+	if errorsBefore == "" {
+		if errorsAfter, supported, err := syntaxErrors(ctx, editFileInput.Path); err == nil && supported && errorsAfter != "" {
+			result += "\nWarning: this edit introduced syntax errors:\n" + errorsAfter
+		}
+	}
+	return result, nil
+}
+
+// regexReplace replaces the first limit matches of re (all when limit is 0), expanding
+// capture group references in replacement, and reports matches found and replaced
+func regexReplace(re *regexp.Regexp, content, replacement string, limit int) (string, int, int) {
+	matches := re.FindAllStringSubmatchIndex(content, -1)
+	replaced := len(matches)
+	if limit > 0 {
+		replaced = min(limit, len(matches))
+	}
+
+	var result []byte
+	last := 0
+	for _, match := range matches[:replaced] {
+		result = append(result, content[last:match[0]]...)
+		result = re.ExpandString(result, replacement, content, match)
+		last = match[1]
+	}
+	result = append(result, content[last:]...)
+	return string(result), len(matches), replaced
 }
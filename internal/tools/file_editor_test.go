@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditFileReplacesFirstOccurrences(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "list.txt", "item\nitem\nitem\nitem\nitem\n")
+
+	result := mustRunTool(t, EditFile, EditFileInput{Path: "list.txt", OldStr: "item", NewStr: "done", Count: 2, NoBackup: true})
+	if got := readFile(t, "list.txt"); got != "done\ndone\nitem\nitem\nitem\n" {
+		t.Errorf("file = %q, want only the first two replaced", got)
+	}
+	if !strings.Contains(result, "Made 2 replacement(s) of 2 requested; 3 occurrence(s) left unchanged") {
+		t.Errorf("result = %q, want the replaced and remaining occurrences counted", result)
+	}
+}
+
+func TestEditFileRegexCaptureGroups(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "calls.txt", "Log(\"a\", 1)\nlog(\"b\", 2)\nLogf(\"c\")\n")
+
+	// Case-insensitive by default, so both Log and log calls are rewritten
+	mustRunTool(t, EditFile, EditFileInput{
+		Path:     "calls.txt",
+		OldStr:   `log\("(?P<msg>\w+)", (\d+)\)`,
+		NewStr:   `logger.Info("${msg}", "n", $2)`,
+		IsRegex:  true,
+		NoBackup: true,
+	})
+	want := "logger.Info(\"a\", \"n\", 1)\nlogger.Info(\"b\", \"n\", 2)\nLogf(\"c\")\n"
+	if got := readFile(t, "calls.txt"); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+
+	// Case-sensitive matching leaves the capitalized call alone
+	writeFile(t, "calls.txt", "Log(\"a\", 1)\nlog(\"b\", 2)\n")
+	mustRunTool(t, EditFile, EditFileInput{
+		Path:          "calls.txt",
+		OldStr:        `log\("(\w+)", (\d+)\)`,
+		NewStr:        `log("$1")`,
+		IsRegex:       true,
+		CaseSensitive: true,
+		NoBackup:      true,
+	})
+	if got := readFile(t, "calls.txt"); got != "Log(\"a\", 1)\nlog(\"b\")\n" {
+		t.Errorf("case-sensitive edit = %q", got)
+	}
+}
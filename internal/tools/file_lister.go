@@ -16,10 +16,14 @@ import (
 
 // ListFilesInput defines the input parameters for the list_files tool
 type ListFilesInput struct {
-	Path          string `json:"path,omitempty" jsonschema_description:"Optional relative path to list files from. Defaults to current directory if not provided."`
-	Recursive     bool   `json:"recursive,omitempty" jsonschema_description:"Whether to list files recursively. Defaults to false."`
-	MaxDepth      int    `json:"max_depth,omitempty" jsonschema_description:"Maximum recursion depth. Only used if recursive is true. Defaults to 3."`
-	IncludeHidden bool   `json:"include_hidden,omitempty" jsonschema_description:"Whether to include hidden files and directories (those starting with a dot). Defaults to false."`
+	Path            string   `json:"path,omitempty" jsonschema_description:"Optional relative path to list files from. Defaults to current directory if not provided."`
+	Recursive       bool     `json:"recursive,omitempty" jsonschema_description:"Whether to list files recursively. Defaults to false."`
+	MaxDepth        int      `json:"max_depth,omitempty" jsonschema_description:"Maximum recursion depth. Only used if recursive is true. Defaults to 3."`
+	IncludeHidden   *bool    `json:"include_hidden,omitempty" jsonschema_description:"Whether to include hidden files and directories (those starting with a dot). Defaults to the user's preference (usually false)."`
+	MaxDirEntries   int      `json:"max_dir_entries,omitempty" jsonschema_description:"Subdirectories with more entries than this are summarized instead of expanded. Defaults to 500."`
+	ExtensionFilter []string `json:"extension_filter,omitempty" jsonschema_description:"Only list files with these extensions, e.g. ['go', 'md']. Directories are still descended into, and those with no matching files are left out."`
+	HumanReadable   bool     `json:"human_readable,omitempty" jsonschema_description:"Also report sizes in human-readable form, e.g. '4.2 KB'. Defaults to false."`
+	NameGlob        string   `json:"name_glob,omitempty" jsonschema_description:"Only list files whose name matches this glob, e.g. '*_test.go' or 'README*'. Combined with extension_filter, files must match both."`
 }
 
 // FileNode represents a single file or directory entry in a tree structure.
@@ -27,16 +31,48 @@ type FileNode struct {
 	Path         string      `json:"path"`
 	IsDir        bool        `json:"is_dir"`
 	Size         int64       `json:"size,omitempty"`
+	SizeHuman    string      `json:"size_human,omitempty"`
+	TotalSize    int64       `json:"total_size,omitempty"`
+	FileCount    int         `json:"file_count,omitempty"`
 	LastModified string      `json:"last_modified,omitempty"`
+	Summary      string      `json:"summary,omitempty"`
 	Children     []*FileNode `json:"children,omitempty"`
 }
 
+// listOptions controls how listFilesRecursive walks the tree
+type listOptions struct {
+	maxDepth      int
+	includeHidden bool
+	maxDirEntries int
+	extensions    map[string]bool
+	nameGlob      string
+}
+
+// filtering reports whether only some files should be listed
+func (o listOptions) filtering() bool {
+	return len(o.extensions) > 0 || o.nameGlob != ""
+}
+
+// matchesFile reports whether a file name passes the extension and name filters
+func (o listOptions) matchesFile(name string) bool {
+	if len(o.extensions) > 0 && !o.extensions[strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))] {
+		return false
+	}
+	if o.nameGlob != "" {
+		if matched, _ := filepath.Match(o.nameGlob, name); !matched {
+			return false
+		}
+	}
+	return true
+}
+
 // ListFilesDefinition provides the list_files tool definition
 var ListFilesDefinition = agent.ToolDefinition{
 	Name:        "list_files",
 	Description: "List files and directories in a tree-like structure for a given relative directory path. Use this to see the contents of a directory. By default, it lists the current directory non-recursively.",
 	InputSchema: schema.GenerateSchema[ListFilesInput](),
 	Function:    ListFiles,
+	ReadOnly:    true,
 }
 
 // ListFiles lists files and directories as a tree
@@ -72,17 +108,44 @@ func ListFiles(ctx context.Context, input json.RawMessage) (string, error) {
 		}
 	}
 
+	maxDirEntries := listFilesInput.MaxDirEntries
+	if maxDirEntries <= 0 {
+		maxDirEntries = 500 // Default large directory threshold
+	}
+
+	opts := listOptions{
+		maxDepth:      maxDepth,
+		includeHidden: resolveIncludeHidden(listFilesInput.IncludeHidden),
+		maxDirEntries: maxDirEntries,
+		nameGlob:      listFilesInput.NameGlob,
+	}
+	if opts.nameGlob != "" {
+		if _, err := filepath.Match(opts.nameGlob, ""); err != nil {
+			return "", fmt.Errorf("invalid name_glob %q: %w", opts.nameGlob, err)
+		}
+	}
+	if len(listFilesInput.ExtensionFilter) > 0 {
+		opts.extensions = make(map[string]bool)
+		for _, ext := range listFilesInput.ExtensionFilter {
+			opts.extensions[strings.ToLower(strings.TrimPrefix(ext, "."))] = true
+		}
+	}
+
 	root := &FileNode{
 		Path:         dir,
 		IsDir:        true,
 		LastModified: info.ModTime().Format(time.RFC3339),
 	}
 
-	children, err := listFilesRecursive(dir, 0, maxDepth, listFilesInput.IncludeHidden)
+	children, err := listFilesRecursive(dir, 0, opts)
 	if err != nil {
 		return "", fmt.Errorf("failed to list files: %w", err)
 	}
 	root.Children = children
+	root.TotalSize, root.FileCount = treeTotals(children)
+	if listFilesInput.HumanReadable {
+		addHumanSizes(root)
+	}
 
 	result, err := json.MarshalIndent(root, "", "  ")
 	if err != nil {
@@ -92,8 +155,8 @@ func ListFiles(ctx context.Context, input json.RawMessage) (string, error) {
 }
 
 // listFilesRecursive recursively builds a tree of files and directories.
-func listFilesRecursive(currentPath string, depth, maxDepth int, includeHidden bool) ([]*FileNode, error) {
-	if depth >= maxDepth {
+func listFilesRecursive(currentPath string, depth int, opts listOptions) ([]*FileNode, error) {
+	if depth >= opts.maxDepth {
 		return nil, nil
 	}
 
@@ -105,7 +168,7 @@ func listFilesRecursive(currentPath string, depth, maxDepth int, includeHidden b
 	var nodes []*FileNode
 	for _, entry := range entries {
 		name := entry.Name()
-		if !includeHidden && strings.HasPrefix(name, ".") {
+		if !opts.includeHidden && isHiddenName(name) {
 			continue // skip hidden files/dirs
 		}
 
@@ -122,16 +185,30 @@ func listFilesRecursive(currentPath string, depth, maxDepth int, includeHidden b
 		}
 
 		if !entry.IsDir() {
+			if !opts.matchesFile(name) {
+				continue
+			}
 			node.Size = info.Size()
 		}
 
-		if entry.IsDir() {
-			children, err := listFilesRecursive(filepath.Join(currentPath, name), depth+1, maxDepth, includeHidden)
+		if entry.IsDir() && depth+1 < opts.maxDepth {
+			childPath := filepath.Join(currentPath, name)
+
+			// Summarize huge directories like node_modules instead of expanding them
+			if count, err := countDirEntries(childPath); err == nil && count > opts.maxDirEntries {
+				node.Summary = fmt.Sprintf("%d entries, not expanded", count)
+				nodes = append(nodes, node)
+				continue
+			}
+
+			children, err := listFilesRecursive(childPath, depth+1, opts)
 			if err != nil {
 				return nil, err
 			}
 			if children != nil {
 				node.Children = children
+			} else if opts.filtering() {
+				continue // nothing under this directory matched the filters
 			}
 		}
 		nodes = append(nodes, node)
@@ -147,3 +224,64 @@ func listFilesRecursive(currentPath string, depth, maxDepth int, includeHidden b
 
 	return nodes, nil
 }
+
+// countDirEntries returns the number of entries in a directory without stat-ing them
+func countDirEntries(dir string) (int, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	return len(names), nil
+}
+
+// treeTotals sums the sizes and counts the files listed in a tree
+func treeTotals(nodes []*FileNode) (int64, int) {
+	var size int64
+	count := 0
+	for _, node := range nodes {
+		if node.IsDir {
+			childSize, childCount := treeTotals(node.Children)
+			size += childSize
+			count += childCount
+			continue
+		}
+		size += node.Size
+		count++
+	}
+	return size, count
+}
+
+// addHumanSizes fills in size_human for every file and for the root's total
+func addHumanSizes(node *FileNode) {
+	switch {
+	case !node.IsDir:
+		node.SizeHuman = formatBytes(node.Size)
+	case node.FileCount > 0:
+		node.SizeHuman = formatBytes(node.TotalSize)
+	}
+	for _, child := range node.Children {
+		addHumanSizes(child)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "512 B" or "4.2 KB"
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	for _, suffix := range []string{"KB", "MB", "GB", "TB"} {
+		value /= unit
+		if value < unit || suffix == "TB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// listedFiles runs list_files and returns its tree with the slash-separated paths of
+// the files and directories in it
+func listedFiles(t *testing.T, input ListFilesInput) (*FileNode, []string) {
+	t.Helper()
+	var root FileNode
+	if err := json.Unmarshal([]byte(mustRunTool(t, ListFiles, input)), &root); err != nil {
+		t.Fatalf("list_files output isn't JSON: %v", err)
+	}
+	var paths []string
+	var walk func(prefix string, nodes []*FileNode)
+	walk = func(prefix string, nodes []*FileNode) {
+		for _, node := range nodes {
+			paths = append(paths, prefix+node.Path)
+			walk(prefix+node.Path+"/", node.Children)
+		}
+	}
+	walk("", root.Children)
+	return &root, paths
+}
+
+func TestListFilesExtensionFilter(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "main.go", "package main\n")
+	writeFile(t, "README.md", "# app\n")
+	writeFile(t, "internal/app/app.go", "package app\n")
+	writeFile(t, "internal/app/app.yaml", "name: app\n")
+	writeFile(t, "docs/guide.md", "# guide\n")
+
+	_, paths := listedFiles(t, ListFilesInput{Recursive: true, ExtensionFilter: []string{".go"}})
+	want := []string{"internal", "internal/app", "internal/app/app.go", "main.go"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("listed %q, want %q: only .go files and the directories holding them", paths, want)
+	}
+}
+
+func TestListFilesHumanReadableSizes(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "small.txt", strings.Repeat("a", 512))
+	writeFile(t, "data/large.bin", strings.Repeat("b", 3*1024+512))
+
+	root, _ := listedFiles(t, ListFilesInput{Recursive: true, HumanReadable: true})
+	if root.TotalSize != 4096 || root.FileCount != 2 || root.SizeHuman != "4.0 KB" {
+		t.Errorf("root = %d bytes in %d files (%q), want 4096 in 2 (4.0 KB)", root.TotalSize, root.FileCount, root.SizeHuman)
+	}
+	sizes := map[string]string{}
+	for _, node := range root.Children {
+		sizes[node.Path] = node.SizeHuman
+		for _, child := range node.Children {
+			sizes[child.Path] = child.SizeHuman
+		}
+	}
+	if sizes["small.txt"] != "512 B" || sizes["large.bin"] != "3.5 KB" {
+		t.Errorf("human sizes = %v, want 512 B and 3.5 KB", sizes)
+	}
+
+	for size, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KB", 5 << 20: "5.0 MB", 3 << 40: "3.0 TB", 2048 << 40: "2048.0 TB"} {
+		if got := formatBytes(size); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
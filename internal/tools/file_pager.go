@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// ReadMoreInput defines the input parameters for the read_more tool
+type ReadMoreInput struct {
+	Path            string `json:"path" jsonschema_description:"The relative path of a file previously read with read_file."`
+	MaxLines        int    `json:"max_lines,omitempty" jsonschema_description:"The number of lines to read. Defaults to 200."`
+	AllowSensitive  bool   `json:"allow_sensitive,omitempty" jsonschema_description:"Allow reading files that likely contain secrets (.env, *.pem, id_rsa, credentials). Defaults to false."`
+	WithLineNumbers bool   `json:"with_line_numbers,omitempty" jsonschema_description:"Prefix each line with its 1-indexed line number, as read_file does."`
+}
+
+// ReadMoreDefinition provides the read_more tool definition
+var ReadMoreDefinition = agent.ToolDefinition{
+	Name:        "read_more",
+	Description: "Continue reading a file from where the last read_file or read_more call on the same path stopped. Use this to page through a large file without tracking line numbers. Starts at line 1 if the file has not been read yet.",
+	InputSchema: schema.GenerateSchema[ReadMoreInput](),
+	Function:    ReadMore,
+	ReadOnly:    true,
+}
+
+// ReadMore reads the next block of lines after the last position read for a path
+func ReadMore(ctx context.Context, input json.RawMessage) (string, error) {
+	var readMoreInput ReadMoreInput
+	err := json.Unmarshal(input, &readMoreInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if readMoreInput.Path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	if err := checkSensitiveFile(readMoreInput.Path, readMoreInput.AllowSensitive); err != nil {
+		return "", err
+	}
+
+	maxLines := readMoreInput.MaxLines
+	if maxLines <= 0 {
+		maxLines = 200 // Default page size
+	}
+
+	path := filepath.Clean(readMoreInput.Path)
+	readCursor.Lock()
+	last := readCursor.lines[path]
+	readCursor.Unlock()
+
+	// Only the page is held in memory, so files over read_file's size limit can be paged too
+	start := last + 1
+	lines, totalLines, err := readLineRange(readMoreInput.Path, start, start+maxLines-1)
+	if err != nil {
+		return "", err
+	}
+	if last >= totalLines {
+		return fmt.Sprintf("Reached end of file %s (%d lines).", readMoreInput.Path, totalLines), nil
+	}
+	end := start + len(lines) - 1
+
+	readCursor.Lock()
+	readCursor.lines[path] = end
+	readCursor.Unlock()
+
+	result := strings.Join(lines, "\n")
+	if readMoreInput.WithLineNumbers {
+		result = numberLines(lines, start)
+	}
+	return fmt.Sprintf("Lines %d-%d of %d:\n%s", start, end, totalLines, result), nil
+}
+
+// readLineRange streams path and returns lines start through end (1-indexed, inclusive)
+// along with the file's line count, counted the way read_file splits lines
+func readLineRange(path string, start, end int) ([]string, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if head, _ := reader.Peek(binarySniffLen); isBinary(head) {
+		return nil, 0, fmt.Errorf("file %s appears to be binary", path)
+	}
+
+	var lines []string
+	total := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, 0, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		total++
+		if total >= start && total <= end {
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+		if err == io.EOF {
+			return lines, total, nil
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadMorePagesFilesOverTheReadLimit(t *testing.T) {
+	inTempDir(t)
+	readCursor.Lock()
+	delete(readCursor.lines, "huge.log")
+	readCursor.Unlock()
+
+	// About 1.4MB, over read_file's default 1MB limit
+	var b strings.Builder
+	for i := 1; i <= 30000; i++ {
+		fmt.Fprintf(&b, "line %d of a log that is much too large to read at once\n", i)
+	}
+	writeFile(t, "huge.log", b.String())
+
+	first := mustRunTool(t, ReadMore, ReadMoreInput{Path: "huge.log", MaxLines: 2})
+	if first != "Lines 1-2 of 30001:\nline 1 of a log that is much too large to read at once\nline 2 of a log that is much too large to read at once" {
+		t.Errorf("first page = %q", first)
+	}
+	second := mustRunTool(t, ReadMore, ReadMoreInput{Path: "huge.log", MaxLines: 2, WithLineNumbers: true})
+	if !strings.HasPrefix(second, "Lines 3-4 of 30001:\n3 | line 3 ") || !strings.Contains(second, "\n4 | line 4 ") {
+		t.Errorf("second page = %q, want lines 3 and 4 numbered", second)
+	}
+
+	// The last page stops at the end of the file, and the next call says so
+	readCursor.Lock()
+	readCursor.lines["huge.log"] = 29999
+	readCursor.Unlock()
+	last := mustRunTool(t, ReadMore, ReadMoreInput{Path: "huge.log", MaxLines: 5})
+	if last != "Lines 30000-30001 of 30001:\nline 30000 of a log that is much too large to read at once\n" {
+		t.Errorf("last page = %q", last)
+	}
+	if end := mustRunTool(t, ReadMore, ReadMoreInput{Path: "huge.log"}); end != "Reached end of file huge.log (30001 lines)." {
+		t.Errorf("past the end = %q", end)
+	}
+}
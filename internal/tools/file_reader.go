@@ -1,30 +1,50 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"agent/internal/agent"
 	"agent/internal/schema"
 )
 
+const (
+	// defaultReadMaxBytes is the largest file read_file loads unless max_bytes says otherwise
+	defaultReadMaxBytes = 1 << 20
+	// binarySniffLen is how much of a file is checked for NUL bytes to detect binary content
+	binarySniffLen = 8 << 10
+)
+
 // ReadFileInput defines the input parameters for the read_file tool
 type ReadFileInput struct {
-	Path      string `json:"path" jsonschema_description:"The relative path of a file in the working directory."`
-	StartLine int    `json:"start_line,omitempty" jsonschema_description:"The line number to start reading from (1-indexed). Defaults to 1."`
-	EndLine   int    `json:"end_line,omitempty" jsonschema_description:"The line number to end reading at (inclusive). Defaults to reading the whole file."`
-	MaxLines  int    `json:"max_lines,omitempty" jsonschema_description:"The maximum number of lines to read. Defaults to 1000."`
+	Path            string `json:"path" jsonschema_description:"The relative path of a file in the working directory."`
+	StartLine       int    `json:"start_line,omitempty" jsonschema_description:"The line number to start reading from (1-indexed). Defaults to 1."`
+	EndLine         int    `json:"end_line,omitempty" jsonschema_description:"The line number to end reading at (inclusive). Defaults to reading the whole file."`
+	MaxLines        int    `json:"max_lines,omitempty" jsonschema_description:"The maximum number of lines to read. Defaults to 1000."`
+	AllowSensitive  bool   `json:"allow_sensitive,omitempty" jsonschema_description:"Allow reading files that likely contain secrets (.env, *.pem, id_rsa, credentials). Defaults to false."`
+	MaxBytes        int64  `json:"max_bytes,omitempty" jsonschema_description:"Refuse files larger than this many bytes. Defaults to 1MB; read a line range of a larger file with a higher limit only when needed."`
+	WithLineNumbers bool   `json:"with_line_numbers,omitempty" jsonschema_description:"Prefix each line with its 1-indexed line number, e.g. '  12 | '. Useful before a line-range edit. The numbers are not part of the file."`
 }
 
+// readCursor tracks the last line returned by read_file for each path this session
+var readCursor = struct {
+	sync.Mutex
+	lines map[string]int
+}{lines: make(map[string]int)}
+
 // ReadFileDefinition provides the read_file tool definition
 var ReadFileDefinition = agent.ToolDefinition{
 	Name:        "read_file",
 	Description: "Read the contents of a given relative file path. Can read the whole file or a specific range of lines. Use this when you want to see what's inside a file. Do not use this with directory names.",
 	InputSchema: schema.GenerateSchema[ReadFileInput](),
 	Function:    ReadFile,
+	ReadOnly:    true,
 }
 
 // ReadFile reads the contents of a file
@@ -35,9 +55,9 @@ func ReadFile(ctx context.Context, input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("failed to unmarshal input: %w", err)
 	}
 
-	content, err := os.ReadFile(readFileInput.Path)
+	content, err := readTextFile(readFileInput.Path, readFileInput.AllowSensitive, readFileInput.MaxBytes)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file %s: %w", readFileInput.Path, err)
+		return "", err
 	}
 
 	lines := strings.Split(string(content), "\n")
@@ -68,5 +88,58 @@ func ReadFile(ctx context.Context, input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("start_line (%d) is greater than the total number of lines (%d)", start, len(lines))
 	}
 
+	readCursor.Lock()
+	readCursor.lines[filepath.Clean(readFileInput.Path)] = end
+	readCursor.Unlock()
+
+	if readFileInput.WithLineNumbers {
+		return numberLines(lines[start-1:end], start), nil
+	}
 	return strings.Join(lines[start-1:end], "\n"), nil
 }
+
+// readTextFile loads a file for reading tools, refusing likely-secret files unless
+// allowed, files over maxBytes (or the default limit) and binary files
+func readTextFile(path string, allowSensitive bool, maxBytes int64) ([]byte, error) {
+	if err := checkSensitiveFile(path, allowSensitive); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultReadMaxBytes
+	}
+	if info.Size() > maxBytes {
+		return nil, fmt.Errorf("file %s is %d bytes, over the %d byte limit: use search_file to find the relevant part, or raise max_bytes", path, info.Size(), maxBytes)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	if isBinary(content) {
+		return nil, fmt.Errorf("file %s appears to be binary", path)
+	}
+	return content, nil
+}
+
+// numberLines prefixes each line with its line number, right-aligned to the widest number
+func numberLines(lines []string, first int) string {
+	width := len(fmt.Sprint(first + len(lines) - 1))
+	numbered := make([]string, len(lines))
+	for i, line := range lines {
+		numbered[i] = fmt.Sprintf("%*d | %s", width, first+i, line)
+	}
+	return strings.Join(numbered, "\n")
+}
+
+// isBinary reports whether content looks binary, i.e. has a NUL byte near the start
+func isBinary(content []byte) bool {
+	if len(content) > binarySniffLen {
+		content = content[:binarySniffLen]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
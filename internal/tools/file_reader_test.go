@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadFileLineNumbers(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "poem.txt", numberedLines(12))
+
+	plain := mustRunTool(t, ReadFile, ReadFileInput{Path: "poem.txt", StartLine: 8, EndLine: 10})
+	if plain != "line 8\nline 9\nline 10" {
+		t.Errorf("plain read = %q", plain)
+	}
+
+	numbered := mustRunTool(t, ReadFile, ReadFileInput{Path: "poem.txt", StartLine: 8, EndLine: 10, WithLineNumbers: true})
+	if numbered != " 8 | line 8\n 9 | line 9\n10 | line 10" {
+		t.Errorf("numbered read = %q, want right-aligned line numbers", numbered)
+	}
+}
+
+func TestReadFileRefusesBinaryAndOversizedFiles(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "logo.txt", "PNG\x00\x01\x02 not really text")
+	writeFile(t, "big.log", strings.Repeat("x", 2000))
+
+	if _, err := runTool(t, context.Background(), ReadFile, ReadFileInput{Path: "logo.txt"}); err == nil || !strings.Contains(err.Error(), "appears to be binary") {
+		t.Errorf("reading a binary file: err = %v, want it refused as binary", err)
+	}
+
+	_, err := runTool(t, context.Background(), ReadFile, ReadFileInput{Path: "big.log", MaxBytes: 1000})
+	if err == nil || !strings.Contains(err.Error(), "2000 bytes, over the 1000 byte limit") {
+		t.Errorf("reading an oversized file: err = %v, want it refused with its size", err)
+	}
+	// Raising the limit reads it
+	if content := mustRunTool(t, ReadFile, ReadFileInput{Path: "big.log", MaxBytes: 4000}); len(content) != 2000 {
+		t.Errorf("read %d bytes with a higher limit, want 2000", len(content))
+	}
+}
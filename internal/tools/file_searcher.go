@@ -14,17 +14,23 @@ import (
 
 // SearchFileInput defines the input parameters for the search_file tool
 type SearchFileInput struct {
-	Path          string `json:"path" jsonschema_description:"The relative path of the file to search in."`
-	Query         string `json:"query" jsonschema_description:"The string or regex pattern to search for."`
-	IsRegex       bool   `json:"is_regex,omitempty" jsonschema_description:"Treat the query as a regular expression. Defaults to false."`
-	CaseSensitive bool   `json:"case_sensitive,omitempty" jsonschema_description:"Perform a case-sensitive search. Defaults to false."`
-	Line          int    `json:"line,omitempty" jsonschema_description:"If provided, only this line number will be searched."`
+	Path           string `json:"path" jsonschema_description:"The relative path of the file to search in."`
+	Query          string `json:"query" jsonschema_description:"The string or regex pattern to search for."`
+	IsRegex        bool   `json:"is_regex,omitempty" jsonschema_description:"Treat the query as a regular expression. Defaults to false."`
+	CaseSensitive  bool   `json:"case_sensitive,omitempty" jsonschema_description:"Perform a case-sensitive search. Defaults to false."`
+	Line           int    `json:"line,omitempty" jsonschema_description:"If provided, only this line number will be searched."`
+	Limit          int    `json:"limit,omitempty" jsonschema_description:"The maximum number of matches to return. Defaults to 100."`
+	ContextBefore  int    `json:"context_before,omitempty" jsonschema_description:"The number of lines to include before each match. Defaults to 0."`
+	ContextAfter   int    `json:"context_after,omitempty" jsonschema_description:"The number of lines to include after each match. Defaults to 0."`
+	AllowSensitive bool   `json:"allow_sensitive,omitempty" jsonschema_description:"Allow searching files that likely contain secrets (.env, *.pem, id_rsa, credentials). Defaults to false."`
 }
 
 // SearchFileResult defines the structure of a search result
 type SearchFileResult struct {
-	LineNumber int    `json:"line_number"`
-	Line       string `json:"line"`
+	LineNumber int      `json:"line_number"`
+	Line       string   `json:"line"`
+	Before     []string `json:"before,omitempty"`
+	After      []string `json:"after,omitempty"`
 }
 
 // SearchFileDefinition provides the search_file tool definition
@@ -33,6 +39,7 @@ var SearchFileDefinition = agent.ToolDefinition{
 	Description: "Search for a string or regex pattern in a file. Returns a list of matching lines with their line numbers.",
 	InputSchema: schema.GenerateSchema[SearchFileInput](),
 	Function:    SearchFile,
+	ReadOnly:    true,
 }
 
 // SearchFile searches for a query string in a file and returns matching lines.
@@ -47,6 +54,10 @@ func SearchFile(ctx context.Context, input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("path and query must be provided")
 	}
 
+	if err := checkSensitiveFile(searchFileInput.Path, searchFileInput.AllowSensitive); err != nil {
+		return "", err
+	}
+
 	content, err := os.ReadFile(searchFileInput.Path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file %s: %w", searchFileInput.Path, err)
@@ -75,17 +86,53 @@ func SearchFile(ctx context.Context, input json.RawMessage) (string, error) {
 		}
 	}
 
+	limit := searchFileInput.Limit
+	if limit <= 0 {
+		limit = 100 // Default max matches
+	}
+
+	omitted := 0
+	shown := 0 // lines before this index were already returned as a match or context
 	for i, line := range lines {
+		// Huge files can take a while; give up promptly if the turn was cancelled
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+
 		lineNumber := i + 1
 		if searchFileInput.Line != 0 && searchFileInput.Line != lineNumber {
 			continue
 		}
 
 		if matcher(line) {
-			results = append(results, SearchFileResult{
+			if len(results) >= limit {
+				omitted++
+				continue
+			}
+
+			// Adjacent matches share context: the previous match's after-context stops
+			// at this match, and this match's before-context starts where it ended
+			if len(results) > 0 {
+				previous := &results[len(results)-1]
+				if previous.LineNumber+len(previous.After) > i {
+					previous.After = previous.After[:i-previous.LineNumber]
+					shown = i
+				}
+			}
+			result := SearchFileResult{
 				LineNumber: lineNumber,
 				Line:       line,
-			})
+			}
+			if before := max(i-searchFileInput.ContextBefore, shown, 0); before < i {
+				result.Before = lines[before:i]
+			}
+			if after := min(i+1+searchFileInput.ContextAfter, len(lines)); after > i+1 {
+				result.After = lines[i+1 : after]
+			}
+			shown = i + 1 + len(result.After)
+			results = append(results, result)
 		}
 	}
 
@@ -94,5 +141,9 @@ func SearchFile(ctx context.Context, input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("failed to marshal search results: %w", err)
 	}
 
+	if omitted > 0 {
+		return fmt.Sprintf("%s\n\n%d more match(es) omitted. Narrow the query or raise the limit to see them.", resultJSON, omitted), nil
+	}
+
 	return string(resultJSON), nil
 }
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSearchFileContextNearTop(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "todo.txt", "header\nTODO first\nline 3\nTODO second\nline 5\nline 6\nline 7\n")
+
+	result := mustRunTool(t, SearchFile, SearchFileInput{Path: "todo.txt", Query: "todo", ContextBefore: 3, ContextAfter: 2})
+	var results []SearchFileResult
+	if err := json.Unmarshal([]byte(result), &results); err != nil {
+		t.Fatalf("result isn't JSON: %v\n%s", err, result)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d matches, want 2: %s", len(results), result)
+	}
+
+	// Before-context stops at the top of the file
+	first := results[0]
+	if first.LineNumber != 2 || !slices.Equal(first.Before, []string{"header"}) {
+		t.Errorf("first match = %+v, want line 2 with only the header before it", first)
+	}
+	// Context runs up to the next match rather than repeating it
+	if !slices.Equal(first.After, []string{"line 3"}) {
+		t.Errorf("first match after = %q, want it to stop at the next match", first.After)
+	}
+	second := results[1]
+	if second.LineNumber != 4 || len(second.Before) != 0 || !slices.Equal(second.After, []string{"line 5", "line 6"}) {
+		t.Errorf("second match = %+v, want line 4 with no repeated context and two lines after", second)
+	}
+}
@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// defaultStatsDepth is how deep file_stats descends into a directory by default
+const defaultStatsDepth = 5
+
+// FileStatsInput defines the input parameters for the file_stats tool
+type FileStatsInput struct {
+	Path          string `json:"path" jsonschema_description:"The relative path of a file or directory."`
+	MaxDepth      int    `json:"max_depth,omitempty" jsonschema_description:"For a directory, how many levels of subdirectories to count. Defaults to 5."`
+	IncludeHidden *bool  `json:"include_hidden,omitempty" jsonschema_description:"Whether to count hidden files and directories (those starting with a dot). Defaults to the user's preference (usually false)."`
+}
+
+// FileStats is the file_stats output: a file's metrics, or a directory's totals
+type FileStats struct {
+	Path         string `json:"path"`
+	IsDir        bool   `json:"is_dir,omitempty"`
+	Lines        int    `json:"lines"`
+	Bytes        int64  `json:"bytes"`
+	LastModified string `json:"last_modified"`
+	Language     string `json:"language,omitempty"`
+	Binary       bool   `json:"binary,omitempty"`
+
+	// Directory totals; lines count only text files, and gitignored files are skipped
+	Files        int                       `json:"files,omitempty"`
+	Languages    map[string]*LanguageStats `json:"languages,omitempty"`
+	DepthLimited bool                      `json:"depth_limited,omitempty"` // some subdirectories were below max_depth and not counted
+}
+
+// LanguageStats totals the files of one language in a directory
+type LanguageStats struct {
+	Files int `json:"files"`
+	Lines int `json:"lines"`
+}
+
+// languagesByExtension guesses a file's language from its extension
+var languagesByExtension = map[string]string{
+	".go": "Go", ".py": "Python", ".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".rs": "Rust", ".java": "Java", ".kt": "Kotlin",
+	".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".hpp": "C++", ".cs": "C#",
+	".rb": "Ruby", ".php": "PHP", ".swift": "Swift", ".scala": "Scala", ".lua": "Lua",
+	".sh": "Shell", ".bash": "Shell", ".zsh": "Shell", ".sql": "SQL", ".proto": "Protocol Buffers",
+	".html": "HTML", ".css": "CSS", ".scss": "SCSS", ".vue": "Vue", ".svelte": "Svelte",
+	".md": "Markdown", ".json": "JSON", ".yaml": "YAML", ".yml": "YAML", ".toml": "TOML",
+	".xml": "XML", ".mod": "Go Module", ".tf": "Terraform",
+}
+
+// languagesByName guesses the language of files known by name rather than extension
+var languagesByName = map[string]string{
+	"Makefile": "Makefile", "Dockerfile": "Dockerfile", "go.sum": "Go Checksums",
+}
+
+// FileStatsDefinition provides the file_stats tool definition
+var FileStatsDefinition = agent.ToolDefinition{
+	Name:        "file_stats",
+	Description: "Get quick metrics for a file or directory without reading it: line count, size in bytes, last-modified time and a language guess from the extension. For a directory, totals are aggregated recursively (skipping gitignored files) with a per-language breakdown. Use this to estimate the scope of a change.",
+	InputSchema: schema.GenerateSchema[FileStatsInput](),
+	Function:    GetFileStats,
+	ReadOnly:    true,
+}
+
+// GetFileStats reports metrics for a file, or totals for a directory
+func GetFileStats(ctx context.Context, input json.RawMessage) (string, error) {
+	var fileStatsInput FileStatsInput
+	err := json.Unmarshal(input, &fileStatsInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if fileStatsInput.Path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	info, err := os.Stat(fileStatsInput.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", fileStatsInput.Path, err)
+	}
+
+	var stats *FileStats
+	if info.IsDir() {
+		maxDepth := fileStatsInput.MaxDepth
+		if maxDepth <= 0 {
+			maxDepth = defaultStatsDepth
+		}
+		stats, err = directoryStats(ctx, fileStatsInput.Path, maxDepth, resolveIncludeHidden(fileStatsInput.IncludeHidden))
+	} else {
+		stats, err = singleFileStats(fileStatsInput.Path, info)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal file stats: %w", err)
+	}
+	return string(result), nil
+}
+
+// singleFileStats measures one file
+func singleFileStats(path string, info os.FileInfo) (*FileStats, error) {
+	lines, binary, err := countLines(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return &FileStats{
+		Path:         path,
+		Lines:        lines,
+		Bytes:        info.Size(),
+		LastModified: info.ModTime().Format(time.RFC3339),
+		Language:     guessLanguage(path),
+		Binary:       binary,
+	}, nil
+}
+
+// directoryStats totals the files under dir down to maxDepth levels of subdirectories,
+// with the most recent modification time among them
+func directoryStats(ctx context.Context, dir string, maxDepth int, includeHidden bool) (*FileStats, error) {
+	stats := &FileStats{Path: dir, IsDir: true, Languages: make(map[string]*LanguageStats)}
+	ignore := newGitignore(dir)
+	var latest time.Time
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil || relPath == "." {
+			return nil
+		}
+		if (!includeHidden && isHiddenName(info.Name())) || ignore.ignored(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			if strings.Count(filepath.ToSlash(relPath), "/")+1 > maxDepth {
+				stats.DepthLimited = true
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		lines, binary, err := countLines(path)
+		if err != nil {
+			return nil
+		}
+		stats.Files++
+		stats.Bytes += info.Size()
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		if binary {
+			return nil
+		}
+		stats.Lines += lines
+		if language := guessLanguage(path); language != "" {
+			if stats.Languages[language] == nil {
+				stats.Languages[language] = &LanguageStats{}
+			}
+			stats.Languages[language].Files++
+			stats.Languages[language].Lines += lines
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	if latest.IsZero() {
+		if info, err := os.Stat(dir); err == nil {
+			latest = info.ModTime()
+		}
+	}
+	stats.LastModified = latest.Format(time.RFC3339)
+	return stats, nil
+}
+
+// countLines counts a file's lines without loading it whole, including a last line
+// without a trailing newline. Binary files, detected as read_file does, report no lines.
+func countLines(path string) (lines int, binary bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, 32<<10)
+	var last byte
+	var read bool
+	for first := true; ; first = false {
+		n, err := file.Read(buf)
+		if first && isBinary(buf[:n]) {
+			return 0, true, nil
+		}
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+			read = true
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	if read && last != '\n' {
+		lines++
+	}
+	return lines, false, nil
+}
+
+// guessLanguage names a file's language from its name or extension, or returns ""
+func guessLanguage(path string) string {
+	name := filepath.Base(path)
+	if language, ok := languagesByName[name]; ok {
+		return language
+	}
+	return languagesByExtension[strings.ToLower(filepath.Ext(name))]
+}
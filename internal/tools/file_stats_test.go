@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// fileStats runs file_stats and decodes its output
+func fileStats(t *testing.T, input FileStatsInput) FileStats {
+	t.Helper()
+	var stats FileStats
+	if err := json.Unmarshal([]byte(mustRunTool(t, GetFileStats, input)), &stats); err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestFileStatsCountsLinesAndBytes(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, "notes.md", "no trailing newline")
+
+	goStats := fileStats(t, FileStatsInput{Path: "main.go"})
+	if goStats.Lines != 3 || goStats.Bytes != 29 || goStats.Language != "Go" || goStats.IsDir {
+		t.Errorf("main.go stats = %+v, want 3 Go lines in 29 bytes", goStats)
+	}
+	// A last line without a newline still counts
+	mdStats := fileStats(t, FileStatsInput{Path: "notes.md"})
+	if mdStats.Lines != 1 || mdStats.Bytes != 19 || mdStats.Language != "Markdown" {
+		t.Errorf("notes.md stats = %+v, want 1 Markdown line in 19 bytes", mdStats)
+	}
+
+	dirStats := fileStats(t, FileStatsInput{Path: "."})
+	if !dirStats.IsDir || dirStats.Files != 2 || dirStats.Lines != 4 || dirStats.Bytes != 48 {
+		t.Errorf("directory stats = %+v, want 2 files, 4 lines, 48 bytes", dirStats)
+	}
+	if goLang := dirStats.Languages["Go"]; goLang == nil || goLang.Files != 1 || goLang.Lines != 3 {
+		t.Errorf("Go breakdown = %+v", goLang)
+	}
+}
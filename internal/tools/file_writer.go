@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 
 	"agent/internal/agent"
 	"agent/internal/schema"
@@ -16,6 +17,9 @@ type WriteFileInput struct {
 	Path    string `json:"path" jsonschema_description:"The relative path of the file to write to."`
 	Content string `json:"content" jsonschema_description:"The content to write to the file."`
 	Append  bool   `json:"append,omitempty" jsonschema_description:"If true, appends the content to the file. If false (default), overwrites the file."`
+
+	NoBackup       bool `json:"no_backup,omitempty" jsonschema_description:"Skip saving the previous contents under .code-agent/backups before overwriting an existing file."`
+	AllowGenerated bool `json:"allow_generated,omitempty" jsonschema_description:"Set to true to modify a generated file (go.sum, *.pb.go, lock files, generated/ directories). Only do this when the user explicitly asks; otherwise regenerate the file."`
 }
 
 // WriteFileDefinition provides the write_file tool definition
@@ -27,6 +31,7 @@ Use the 'append' parameter to control the behavior. By default, it overwrites.
 `,
 	InputSchema: schema.GenerateSchema[WriteFileInput](),
 	Function:    WriteFile,
+	EditsFiles:  true,
 }
 
 // WriteFile writes content to a file, with options to overwrite or append.
@@ -41,6 +46,19 @@ func WriteFile(ctx context.Context, input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("path cannot be empty")
 	}
 
+	if err := checkGeneratedFile(writeFileInput.Path, writeFileInput.AllowGenerated); err != nil {
+		return "", err
+	}
+
+	// Don't start writing for a turn that has already been cancelled
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if agent.IsDryRun(ctx) {
+		return dryRunWrite(ctx, writeFileInput)
+	}
+
 	dir := path.Dir(writeFileInput.Path)
 	if dir != "." && dir != "/" {
 		err := os.MkdirAll(dir, 0755)
@@ -53,15 +71,75 @@ func WriteFile(ctx context.Context, input json.RawMessage) (string, error) {
 		return appendToFile(writeFileInput.Path, writeFileInput.Content)
 	}
 
-	return createOrOverwriteFile(writeFileInput.Path, writeFileInput.Content)
+	return createOrOverwriteFile(writeFileInput.Path, writeFileInput.Content, !writeFileInput.NoBackup)
 }
 
-func createOrOverwriteFile(filePath, content string) (string, error) {
-	err := os.WriteFile(filePath, []byte(content), 0644)
+// dryRunWrite describes what WriteFile would do to the file
+func dryRunWrite(ctx context.Context, writeFileInput WriteFileInput) (string, error) {
+	filePath, content := writeFileInput.Path, writeFileInput.Content
+	existing, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return dryRunEdit(ctx, filePath, "", content, "Would create %s with %d bytes.", filePath, len(content)), nil
+	}
 	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	if writeFileInput.Append {
+		return dryRunEdit(ctx, filePath, string(existing), string(existing)+content, "Would append %d bytes to %s.", len(content), filePath), nil
+	}
+	return dryRunEdit(ctx, filePath, string(existing), content, "Would overwrite %s with %d bytes.", filePath, len(content)), nil
+}
+
+func createOrOverwriteFile(filePath, content string, backup bool) (string, error) {
+	var backupPath string
+	if backup {
+		var err error
+		if backupPath, err = backupFile(filePath); err != nil {
+			return "", err
+		}
+	}
+
+	if err := writeFileAtomic(filePath, []byte(content)); err != nil {
 		return "", fmt.Errorf("failed to write to file %s: %w", filePath, err)
 	}
-	return fmt.Sprintf("File %s written successfully.", filePath), nil
+	return fmt.Sprintf("File %s written successfully.", filePath) + backupNote(backupPath), nil
+}
+
+// writeFileAtomic replaces a file's content by writing a temporary file beside it and
+// renaming it into place, so readers and crashes see the old content or the new, never
+// part of it. An existing file keeps its permissions, and a symlink keeps pointing at
+// the file it names, which is the one replaced.
+func writeFileAtomic(filePath string, content []byte) error {
+	mode := os.FileMode(0644)
+	if resolved, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = resolved
+	}
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Cleans up after a failure; after the rename there is nothing left to remove
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
 }
 
 func appendToFile(filePath, content string) (string, error) {
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileAtomicKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.env")
+	writeFile(t, path, "TOKEN=old\n")
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("TOKEN=new\n")); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+	if got := readFile(t, path); got != "TOKEN=new\n" {
+		t.Errorf("content = %q, want the new content", got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+
+	// No temporary files are left beside it
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want just the file", len(entries))
+	}
+}
+
+func TestWriteFileAtomicReadersSeeOldOrNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	oldContent := strings.Repeat("old\n", 50000)
+	newContent := strings.Repeat("new\n", 50000)
+	writeFile(t, path, oldContent)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Errorf("read during write: %v", err)
+				return
+			}
+			if got := string(content); got != oldContent && got != newContent {
+				t.Errorf("read %d bytes mixing old and new content", len(got))
+				return
+			}
+		}
+	}()
+
+	for i := range 20 {
+		content := newContent
+		if i%2 == 1 {
+			content = oldContent
+		}
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("writeFileAtomic: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// CheckFormatInput defines the input parameters for the check_format tool
+type CheckFormatInput struct {
+	Path string `json:"path" jsonschema_description:"The relative path of the file to check. Directories are checked with gofmt only."`
+}
+
+// CheckFormatDefinition provides the check_format tool definition
+var CheckFormatDefinition = agent.ToolDefinition{
+	Name: "check_format",
+	Description: `Report whether a file is already formatted, without rewriting it, and show what the formatter would change.
+Supports Go (gofmt -d, files or directories), JavaScript/TypeScript/CSS/JSON/Markdown/YAML (prettier --check), Python (black --check --diff) and Rust (rustfmt --check).`,
+	InputSchema: schema.GenerateSchema[CheckFormatInput](),
+	Function:    CheckFormat,
+	ReadOnly:    true,
+}
+
+// prettierExtensions are the file types checked with prettier
+var prettierExtensions = map[string]bool{
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true,
+	".css": true, ".scss": true, ".json": true, ".md": true, ".yaml": true, ".yml": true, ".html": true,
+}
+
+// CheckFormat runs a formatter in check mode and reports any differences
+func CheckFormat(ctx context.Context, input json.RawMessage) (string, error) {
+	var checkFormatInput CheckFormatInput
+	err := json.Unmarshal(input, &checkFormatInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	path := checkFormatInput.Path
+	if path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var name string
+	var args []string
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case info.IsDir() || ext == ".go":
+		name, args = "gofmt", []string{"-l", "-d", path}
+	case prettierExtensions[ext]:
+		name, args = "npx", []string{"--no-install", "prettier", "--check", path}
+	case ext == ".py":
+		name, args = "black", []string{"--check", "--diff", "--quiet", path}
+	case ext == ".rs":
+		name, args = "rustfmt", []string{"--check", path}
+	default:
+		return fmt.Sprintf("Format checking is not supported for %s files.", ext), nil
+	}
+
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Sprintf("Cannot check formatting of %s: %s is not installed.", path, name), nil
+	}
+
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	report := strings.TrimSpace(string(output))
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return "", fmt.Errorf("failed to run %s: %w", name, err)
+		}
+	}
+
+	// gofmt -d prints diffs for unformatted files and bare errors for files it can't parse;
+	// the other formatters signal unformatted files through a non-zero exit code
+	if name == "gofmt" {
+		if report == "" {
+			return fmt.Sprintf("OK. %s is formatted.", path), nil
+		}
+		if !strings.Contains(report, "\n--- ") {
+			return fmt.Sprintf("gofmt could not parse %s:\n%s", path, report), nil
+		}
+		return fmt.Sprintf("%s needs formatting (gofmt):\n%s", path, report), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("%s needs formatting (%s):\n%s", path, name, report), nil
+	}
+	return fmt.Sprintf("OK. %s is formatted.", path), nil
+}
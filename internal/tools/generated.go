@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"agent/internal/config"
+)
+
+// defaultGeneratedPatterns match files that are produced by tools rather than edited by hand.
+// Patterns ending in "/" match a directory anywhere in the path; others match the file name.
+var defaultGeneratedPatterns = []string{
+	"go.sum",
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_generated.go",
+	"*.gen.go",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"Cargo.lock",
+	"poetry.lock",
+	"generated/",
+}
+
+// goGeneratedHeader is the standard marker for generated Go source (see go help generate)
+var goGeneratedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// generatedPatterns returns the user's GeneratedFilePatterns preference, or the defaults
+func generatedPatterns() []string {
+	prefs, err := config.LoadPreferences()
+	if err != nil || prefs == nil || prefs.GeneratedFilePatterns == nil {
+		return defaultGeneratedPatterns
+	}
+	return prefs.GeneratedFilePatterns
+}
+
+// isGeneratedFile reports whether a path matches a generated-file pattern or carries a
+// Go "Code generated ... DO NOT EDIT." header
+func isGeneratedFile(path string) bool {
+	slashed := filepath.ToSlash(filepath.Clean(path))
+	name := filepath.Base(path)
+	for _, pattern := range generatedPatterns() {
+		if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+			for _, part := range strings.Split(slashed, "/") {
+				if matched, _ := filepath.Match(dir, part); matched {
+					return true
+				}
+			}
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return strings.HasSuffix(name, ".go") && hasGoGeneratedHeader(path)
+}
+
+// hasGoGeneratedHeader looks for the generated marker before the package clause
+func hasGoGeneratedHeader(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if goGeneratedHeader.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
+
+// checkGeneratedFile refuses to modify generated files unless explicitly allowed
+func checkGeneratedFile(path string, allowGenerated bool) error {
+	if !allowGenerated && isGeneratedFile(path) {
+		return fmt.Errorf("refusing to edit generated file %s; regenerate it instead, or pass allow_generated=true if the user explicitly asked to edit it", path)
+	}
+	return nil
+}
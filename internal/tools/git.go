@@ -0,0 +1,273 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// GitStatusInput defines the input parameters for the git_status tool
+type GitStatusInput struct {
+	Path string `json:"path,omitempty" jsonschema_description:"Optional path to limit the status to. Defaults to the whole repository."`
+}
+
+// GitFileStatus is one changed file and how it changed, e.g. "modified", "added", "renamed"
+type GitFileStatus struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	OrigPath string `json:"orig_path,omitempty"`
+}
+
+// GitStatusOutput groups the working tree's changes the way git status does
+type GitStatusOutput struct {
+	Branch     string          `json:"branch,omitempty"`
+	Staged     []GitFileStatus `json:"staged"`
+	Modified   []GitFileStatus `json:"modified"`
+	Untracked  []string        `json:"untracked"`
+	Conflicted []string        `json:"conflicted,omitempty"`
+}
+
+// GitStatusDefinition provides the git_status tool definition
+var GitStatusDefinition = agent.ToolDefinition{
+	Name:        "git_status",
+	Description: "Show the git branch and the staged, modified (unstaged), untracked and conflicted files as JSON. Use this instead of running git status in the shell.",
+	InputSchema: schema.GenerateSchema[GitStatusInput](),
+	Function:    GitStatus,
+	ReadOnly:    true,
+}
+
+// GitDiffInput defines the input parameters for the git_diff tool
+type GitDiffInput struct {
+	Path   string `json:"path,omitempty" jsonschema_description:"Optional file or directory to diff. Defaults to the whole repository."`
+	Staged bool   `json:"staged,omitempty" jsonschema_description:"Show staged changes (git diff --cached) instead of unstaged ones. Defaults to false."`
+}
+
+// GitDiffDefinition provides the git_diff tool definition
+var GitDiffDefinition = agent.ToolDefinition{
+	Name:        "git_diff",
+	Description: "Show the unified diff of unstaged changes, or of staged changes with staged=true, for a file or the whole repository. Untracked files are not included; see git_status for those.",
+	InputSchema: schema.GenerateSchema[GitDiffInput](),
+	Function:    GitDiff,
+	ReadOnly:    true,
+}
+
+// gitStatusNames maps porcelain status letters to readable names
+var gitStatusNames = map[byte]string{
+	'M': "modified",
+	'T': "type changed",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+}
+
+// GitStatus reports the repository's changes, parsed from git status --porcelain
+func GitStatus(ctx context.Context, input json.RawMessage) (string, error) {
+	var gitStatusInput GitStatusInput
+	err := json.Unmarshal(input, &gitStatusInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	args := []string{"status", "--porcelain=v1", "--branch", "-z"}
+	if gitStatusInput.Path != "" {
+		args = append(args, "--", gitStatusInput.Path)
+	}
+	output, err := runGit(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	status := GitStatusOutput{Staged: []GitFileStatus{}, Modified: []GitFileStatus{}, Untracked: []string{}}
+	entries := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if strings.HasPrefix(entry, "## ") {
+			status.Branch = strings.TrimPrefix(entry, "## ")
+			continue
+		}
+		if len(entry) < 4 {
+			continue
+		}
+
+		x, y, path := entry[0], entry[1], entry[3:]
+		var origPath string
+		if x == 'R' || x == 'C' {
+			// -z puts a rename's original path in the next entry
+			if i+1 < len(entries) {
+				origPath = entries[i+1]
+				i++
+			}
+		}
+
+		switch {
+		case x == '?' && y == '?':
+			status.Untracked = append(status.Untracked, path)
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			status.Conflicted = append(status.Conflicted, path)
+		default:
+			if name, ok := gitStatusNames[x]; ok {
+				status.Staged = append(status.Staged, GitFileStatus{Path: path, Status: name, OrigPath: origPath})
+			}
+			if name, ok := gitStatusNames[y]; ok {
+				status.Modified = append(status.Modified, GitFileStatus{Path: path, Status: name})
+			}
+		}
+	}
+
+	result, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal git status: %w", err)
+	}
+	return string(result), nil
+}
+
+// GitDiff returns git diff output for the working tree or the index
+func GitDiff(ctx context.Context, input json.RawMessage) (string, error) {
+	var gitDiffInput GitDiffInput
+	err := json.Unmarshal(input, &gitDiffInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if gitDiffInput.Staged {
+		args = append(args, "--cached")
+	}
+	if gitDiffInput.Path != "" {
+		args = append(args, "--", gitDiffInput.Path)
+	}
+	output, err := runGit(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(output) == "" {
+		kind := "unstaged"
+		if gitDiffInput.Staged {
+			kind = "staged"
+		}
+		if gitDiffInput.Path != "" {
+			return fmt.Sprintf("No %s changes in %s.", kind, gitDiffInput.Path), nil
+		}
+		return fmt.Sprintf("No %s changes.", kind), nil
+	}
+	return strings.TrimRight(output, "\n"), nil
+}
+
+// GitBlameInput defines the input parameters for the git_blame tool
+type GitBlameInput struct {
+	Path      string `json:"path" jsonschema_description:"The file to blame."`
+	StartLine int    `json:"start_line,omitempty" jsonschema_description:"First line to blame (1-based). Defaults to 1."`
+	EndLine   int    `json:"end_line,omitempty" jsonschema_description:"Last line to blame (inclusive). Defaults to the end of the file."`
+}
+
+// GitBlameLine is one line of a file and the commit that last changed it
+type GitBlameLine struct {
+	Line    int    `json:"line"`
+	Commit  string `json:"commit"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+}
+
+// GitBlameDefinition provides the git_blame tool definition
+var GitBlameDefinition = agent.ToolDefinition{
+	Name:        "git_blame",
+	Description: "Show which commit last changed each line in a range of a file, with its author, date and commit summary, as JSON. Use this to find out who changed some code and why.",
+	InputSchema: schema.GenerateSchema[GitBlameInput](),
+	Function:    GitBlame,
+	ReadOnly:    true,
+}
+
+// uncommittedCommit is the commit ID git blame gives lines changed in the working tree
+const uncommittedCommit = "0000000000000000000000000000000000000000"
+
+// GitBlame returns git blame for a line range, parsed from git blame --porcelain
+func GitBlame(ctx context.Context, input json.RawMessage) (string, error) {
+	var gitBlameInput GitBlameInput
+	err := json.Unmarshal(input, &gitBlameInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if gitBlameInput.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	start := max(gitBlameInput.StartLine, 1)
+	lineRange := fmt.Sprintf("%d,", start)
+	if gitBlameInput.EndLine > 0 {
+		if gitBlameInput.EndLine < start {
+			return "", fmt.Errorf("end_line %d is before start_line %d", gitBlameInput.EndLine, start)
+		}
+		lineRange += fmt.Sprint(gitBlameInput.EndLine)
+	}
+
+	output, err := runGit(ctx, "blame", "--porcelain", "-L", lineRange, "--", gitBlameInput.Path)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such path") {
+			return "", fmt.Errorf("%s is not under version control; commit it before blaming", gitBlameInput.Path)
+		}
+		return "", err
+	}
+
+	result, err := json.MarshalIndent(parseBlame(output), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal git blame: %w", err)
+	}
+	return string(result), nil
+}
+
+// parseBlame turns git blame --porcelain output into one entry per line. Commit
+// details are only printed the first time a commit appears, so they're remembered.
+func parseBlame(output string) []GitBlameLine {
+	commits := make(map[string]*GitBlameLine)
+	lines := []GitBlameLine{}
+	var current *GitBlameLine
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			if current != nil {
+				entry := *current
+				entry.Content = line[1:]
+				lines = append(lines, entry)
+			}
+		case current != nil && strings.HasPrefix(line, "author "):
+			current.Author = strings.TrimPrefix(line, "author ")
+		case current != nil && strings.HasPrefix(line, "author-time "):
+			if seconds, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64); err == nil {
+				current.Date = time.Unix(seconds, 0).UTC().Format("2006-01-02")
+			}
+		case current != nil && strings.HasPrefix(line, "summary "):
+			current.Summary = strings.TrimPrefix(line, "summary ")
+		default:
+			// A header line: <commit> <original line> <final line> [<group size>]
+			fields := strings.Fields(line)
+			if len(fields) < 3 || len(fields[0]) != len(uncommittedCommit) {
+				continue
+			}
+			finalLine, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			info, ok := commits[fields[0]]
+			if !ok {
+				info = &GitBlameLine{Commit: fields[0][:12]}
+				if fields[0] == uncommittedCommit {
+					info.Commit = "uncommitted"
+				}
+				commits[fields[0]] = info
+			}
+			info.Line = finalLine
+			current = info
+		}
+	}
+	return lines
+}
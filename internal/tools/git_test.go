@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// initRepo makes a fresh git repository the working directory, isolated from the
+// user's git configuration, with commits authored by setAuthor's name
+func initRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	inTempDir(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	setAuthor(t, "Ada")
+	git(t, "init", "-q", "-b", "main")
+}
+
+// setAuthor makes name the author and committer of the following commits
+func setAuthor(t *testing.T, name string) {
+	t.Helper()
+	for _, role := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+role+"_NAME", name)
+		t.Setenv("GIT_"+role+"_EMAIL", strings.ToLower(name)+"@example.com")
+	}
+}
+
+// git runs a git command in the working directory
+func git(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+func TestGitStatusListsChanges(t *testing.T) {
+	initRepo(t)
+	writeFile(t, "main.go", "package main\n")
+	writeFile(t, "old.txt", "old\n")
+	git(t, "add", ".")
+	git(t, "commit", "-q", "-m", "initial")
+
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	git(t, "mv", "old.txt", "new.txt")
+	writeFile(t, "notes.md", "todo\n")
+
+	var status GitStatusOutput
+	if err := json.Unmarshal([]byte(mustRunTool(t, GitStatus, GitStatusInput{})), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Branch != "main" {
+		t.Errorf("branch = %q, want main", status.Branch)
+	}
+	if !slices.Equal(status.Modified, []GitFileStatus{{Path: "main.go", Status: "modified"}}) {
+		t.Errorf("modified = %+v, want main.go", status.Modified)
+	}
+	if !slices.Equal(status.Staged, []GitFileStatus{{Path: "new.txt", Status: "renamed", OrigPath: "old.txt"}}) {
+		t.Errorf("staged = %+v, want the rename of old.txt", status.Staged)
+	}
+	if !slices.Equal(status.Untracked, []string{"notes.md"}) {
+		t.Errorf("untracked = %v, want notes.md", status.Untracked)
+	}
+}
+
+func TestGitBlameAttributesLines(t *testing.T) {
+	initRepo(t)
+	writeFile(t, "main.go", "package main\n\nfunc main() {}\n")
+	git(t, "add", ".")
+	git(t, "commit", "-q", "-m", "Add main")
+
+	setAuthor(t, "Grace")
+	writeFile(t, "main.go", "package main\n\nfunc main() { run() }\n")
+	git(t, "commit", "-q", "-am", "Call run from main")
+
+	var lines []GitBlameLine
+	if err := json.Unmarshal([]byte(mustRunTool(t, GitBlame, GitBlameInput{Path: "main.go"})), &lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("blamed %d lines, want 3", len(lines))
+	}
+	for i, want := range []struct{ author, summary, content string }{
+		{"Ada", "Add main", "package main"},
+		{"Ada", "Add main", ""},
+		{"Grace", "Call run from main", "func main() { run() }"},
+	} {
+		line := lines[i]
+		if line.Line != i+1 || line.Author != want.author || line.Summary != want.summary || line.Content != want.content {
+			t.Errorf("line %d = %+v, want %s's %q", i+1, line, want.author, want.summary)
+		}
+	}
+	if lines[0].Commit != lines[1].Commit || lines[0].Commit == lines[2].Commit {
+		t.Errorf("commits = %s, %s, %s; want the first two lines from one commit and the last from another", lines[0].Commit, lines[1].Commit, lines[2].Commit)
+	}
+
+	// A range blames just those lines
+	if err := json.Unmarshal([]byte(mustRunTool(t, GitBlame, GitBlameInput{Path: "main.go", StartLine: 3, EndLine: 3})), &lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Line != 3 || lines[0].Author != "Grace" {
+		t.Errorf("blame of line 3 = %+v, want Grace's line", lines)
+	}
+}
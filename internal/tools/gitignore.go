@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreRule is one pattern from a .gitignore file, relative to the file's directory
+type gitignoreRule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// gitignore matches paths against the .gitignore files of a repository. It covers the
+// common cases (names, *.ext, dir/, leading /, leading **/ and ! negation) rather than
+// the full gitignore syntax. Files are loaded lazily as directories are visited.
+type gitignore struct {
+	root   string
+	rules  []gitignoreRule
+	loaded map[string]bool
+}
+
+// newGitignore returns a matcher for paths under basePath, including the .gitignore
+// files of its parent directories up to the repository root
+func newGitignore(basePath string) *gitignore {
+	base, err := filepath.Abs(basePath)
+	if err != nil {
+		base = basePath
+	}
+
+	root := base
+	for dir := base; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			root = dir
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return &gitignore{root: root, loaded: make(map[string]bool)}
+}
+
+// load reads the .gitignore file in dir, once
+func (g *gitignore) load(dir string) {
+	if g.loaded[dir] {
+		return
+	}
+	g.loaded[dir] = true
+
+	content, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/**") {
+			line = strings.TrimSuffix(line, "/**") + "/"
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.HasPrefix(line, "**/") {
+			line = strings.TrimPrefix(line, "**/")
+		} else if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		rule.pattern = line
+		g.rules = append(g.rules, rule)
+	}
+}
+
+// ignored reports whether path, or any directory containing it, is ignored
+func (g *gitignore) ignored(path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(g.root, abs)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return false
+	}
+
+	dir := g.root
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i, part := range parts {
+		g.load(dir)
+		dir = filepath.Join(dir, part)
+		if g.matches(dir, isDir || i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the loaded rules to a single path; the last matching rule wins
+func (g *gitignore) matches(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+
+		subject := filepath.Base(rel)
+		if rule.anchored {
+			subject = filepath.ToSlash(rel)
+		}
+		if matched, _ := filepath.Match(rule.pattern, subject); matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
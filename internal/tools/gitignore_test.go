@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitignoreExcludesDirectoriesAndExtensions(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, ".gitignore"), "# build output\nbuild/\n*.log\n!keep.log\n")
+	writeFile(t, filepath.Join(root, "cmd", ".gitignore"), "/local.txt\n")
+
+	// The matcher finds the repository root from a subdirectory
+	ignore := newGitignore(filepath.Join(root, "cmd"))
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"build", true, true},
+		{"build/app/main", false, true}, // inside an ignored directory
+		{"cmd/build", true, true},       // unanchored, so at any depth
+		{"build", false, false},         // build/ only matches directories
+		{"debug.log", false, true},
+		{"cmd/server/trace.log", false, true},
+		{"keep.log", false, false}, // negated
+		{"cmd/local.txt", false, true},
+		{"cmd/sub/local.txt", false, false}, // anchored to cmd/
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := ignore.ignored(filepath.Join(root, tt.path), tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
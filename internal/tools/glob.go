@@ -1,9 +1,9 @@
 package tools
 
 import (
-	"context"
 	"agent/internal/agent"
 	"agent/internal/schema"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,8 +13,10 @@ import (
 
 // GlobInput represents the input parameters for the glob tool
 type GlobInput struct {
-	Pattern string `json:"pattern" description:"Glob pattern to match files (e.g., '*.go' for all Go files, '**/*.txt' for all text files recursively)"`
-	Path    string `json:"path,omitempty" description:"Base path to search from (defaults to current directory)"`
+	Pattern          string `json:"pattern" description:"Glob pattern to match files (e.g., '*.go' for all Go files, '**/*.txt' for all text files recursively)"`
+	Path             string `json:"path,omitempty" description:"Base path to search from (defaults to current directory)"`
+	IncludeHidden    *bool  `json:"include_hidden,omitempty" jsonschema_description:"Whether to match hidden files and directories (those starting with a dot). Defaults to the user's preference (usually false). Patterns that name a dot path, like '.github/**', always match it."`
+	RespectGitignore *bool  `json:"respect_gitignore,omitempty" jsonschema_description:"Whether to skip files and directories excluded by .gitignore files. Defaults to true."`
 }
 
 // GlobDefinition provides the glob tool definition
@@ -23,6 +25,7 @@ var GlobDefinition = agent.ToolDefinition{
 	Description: "Find files matching a glob pattern (e.g., '*.go', '**/*.txt'). Supports recursive patterns with **.",
 	InputSchema: schema.GenerateSchema[GlobInput](),
 	Function:    Glob,
+	ReadOnly:    true,
 }
 
 // Glob finds files matching a pattern
@@ -41,9 +44,16 @@ func Glob(ctx context.Context, input json.RawMessage) (string, error) {
 		basePath = "."
 	}
 
+	includeHidden := resolveIncludeHidden(params.IncludeHidden) || isHiddenPath(params.Pattern)
+
+	var ignore *gitignore
+	if params.RespectGitignore == nil || *params.RespectGitignore {
+		ignore = newGitignore(basePath)
+	}
+
 	// Convert ** to filepath walking pattern
 	if strings.Contains(params.Pattern, "**") {
-		return walkPattern(basePath, params.Pattern)
+		return walkPattern(ctx, basePath, params.Pattern, includeHidden, ignore)
 	}
 
 	// Simple glob pattern
@@ -56,6 +66,17 @@ func Glob(ctx context.Context, input json.RawMessage) (string, error) {
 	// Convert to relative paths and format output
 	var result []string
 	for _, match := range matches {
+		if !includeHidden {
+			if rel, err := filepath.Rel(basePath, match); err == nil && isHiddenPath(rel) {
+				continue
+			}
+		}
+		if ignore != nil {
+			if info, err := os.Stat(match); err == nil && ignore.ignored(match, info.IsDir()) {
+				continue
+			}
+		}
+
 		relPath, err := filepath.Rel(".", match)
 		if err != nil {
 			result = append(result, match)
@@ -71,7 +92,9 @@ func Glob(ctx context.Context, input json.RawMessage) (string, error) {
 	return formatFileList(result), nil
 }
 
-func walkPattern(basePath, pattern string) (string, error) {
+// walkPattern matches a ** pattern by walking basePath, stopping early if ctx is cancelled.
+// Paths excluded by ignore are skipped when it is non-nil.
+func walkPattern(ctx context.Context, basePath, pattern string, includeHidden bool, ignore *gitignore) (string, error) {
 	// Split pattern by ** to handle recursive matching
 	parts := strings.Split(pattern, "**")
 	if len(parts) != 2 {
@@ -83,6 +106,9 @@ func walkPattern(basePath, pattern string) (string, error) {
 
 	var matches []string
 	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			return nil // Skip errors
 		}
@@ -92,6 +118,20 @@ func walkPattern(basePath, pattern string) (string, error) {
 			return nil
 		}
 
+		if !includeHidden && isHiddenName(info.Name()) && relPath != "." {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if ignore != nil && relPath != "." && ignore.ignored(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Check if path matches the pattern
 		if matchesRecursivePattern(relPath, prefix, suffix) {
 			matches = append(matches, relPath)
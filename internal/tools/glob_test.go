@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGlobHiddenDirectories(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "main.go", "package main\n")
+	writeFile(t, "internal/app.go", "package internal\n")
+	writeFile(t, ".hidden/secret.go", "package hidden\n")
+
+	result := mustRunTool(t, Glob, GlobInput{Pattern: "**/*.go"})
+	if !strings.Contains(result, "main.go") || !strings.Contains(result, "internal/app.go") {
+		t.Errorf("glob result = %q, want the visible files", result)
+	}
+	if strings.Contains(result, ".hidden") {
+		t.Errorf("glob result = %q, want .hidden excluded by default", result)
+	}
+
+	include := true
+	result = mustRunTool(t, Glob, GlobInput{Pattern: "**/*.go", IncludeHidden: &include})
+	if !strings.Contains(result, ".hidden/secret.go") || !strings.Contains(result, "main.go") {
+		t.Errorf("glob result with include_hidden = %q, want .hidden/secret.go too", result)
+	}
+}
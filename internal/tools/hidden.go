@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+
+	"agent/internal/config"
+)
+
+// resolveIncludeHidden returns the per-call include_hidden flag, falling back to
+// the user's DefaultIncludeHidden preference when the call doesn't set it
+func resolveIncludeHidden(flag *bool) bool {
+	if flag != nil {
+		return *flag
+	}
+	prefs, err := config.LoadPreferences()
+	if err != nil || prefs == nil {
+		return false
+	}
+	return prefs.DefaultIncludeHidden
+}
+
+// isHiddenName reports whether a single path element is a dotfile or dot directory
+func isHiddenName(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// isHiddenPath reports whether any element of a relative path is hidden
+func isHiddenPath(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if isHiddenName(part) {
+			return true
+		}
+	}
+	return false
+}
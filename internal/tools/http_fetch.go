@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+const (
+	// fetchTimeout bounds a single fetch_url request
+	fetchTimeout = 30 * time.Second
+	// defaultFetchMaxBytes is how much of a response body fetch_url returns by default
+	defaultFetchMaxBytes = 100 << 10
+	// fetchUserAgent identifies the agent to the servers it fetches from
+	fetchUserAgent = "code-editing-agent/1.0"
+)
+
+// FetchURLInput defines the input parameters for the fetch_url tool
+type FetchURLInput struct {
+	URL      string `json:"url" jsonschema_description:"The http or https URL to fetch."`
+	MaxBytes int    `json:"max_bytes,omitempty" jsonschema_description:"The maximum number of body bytes to return. Defaults to 100KB."`
+}
+
+// FetchURLDefinition provides the fetch_url tool definition
+var FetchURLDefinition = agent.ToolDefinition{
+	Name:        "fetch_url",
+	Description: "Fetch a URL with an HTTP GET and return its status, content type and body as text, e.g. to read documentation or an issue referenced in the code. Binary responses are not returned.",
+	InputSchema: schema.GenerateSchema[FetchURLInput](),
+	Function:    FetchURL,
+}
+
+// FetchURL GETs an http(s) URL and returns the start of its body
+func FetchURL(ctx context.Context, input json.RawMessage) (string, error) {
+	var fetchURLInput FetchURLInput
+	err := json.Unmarshal(input, &fetchURLInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	target, err := url.Parse(fetchURLInput.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", fetchURLInput.URL, err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL scheme %q: only http and https can be fetched", target.Scheme)
+	}
+	if target.Host == "" {
+		return "", fmt.Errorf("invalid URL %q: missing host", fetchURLInput.URL)
+	}
+
+	maxBytes := fetchURLInput.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultFetchMaxBytes
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	// Read one byte past the limit so we can tell whether the body was truncated
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response from %s: %w", target, err)
+	}
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = body[:maxBytes]
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	header := fmt.Sprintf("Status: %s\nContent-Type: %s\n\n", resp.Status, contentType)
+	if isBinary(body) {
+		return header + "(binary content not shown)", nil
+	}
+
+	result := header + string(body)
+	if truncated {
+		result += fmt.Sprintf("\n\n[truncated after %d bytes; raise max_bytes to see more]", maxBytes)
+	}
+	return result, nil
+}
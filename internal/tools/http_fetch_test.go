@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != fetchUserAgent {
+			http.Error(w, "unexpected user agent", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/docs":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("0123456789abcdef"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	result := mustRunTool(t, FetchURL, FetchURLInput{URL: srv.URL + "/docs"})
+	if result != "Status: 200 OK\nContent-Type: text/plain; charset=utf-8\n\n0123456789abcdef" {
+		t.Errorf("fetch = %q", result)
+	}
+
+	result = mustRunTool(t, FetchURL, FetchURLInput{URL: srv.URL + "/docs", MaxBytes: 10})
+	if !strings.HasSuffix(result, "\n\n0123456789\n\n[truncated after 10 bytes; raise max_bytes to see more]") {
+		t.Errorf("truncated fetch = %q", result)
+	}
+
+	result = mustRunTool(t, FetchURL, FetchURLInput{URL: srv.URL + "/logo.png"})
+	if !strings.HasSuffix(result, "(binary content not shown)") {
+		t.Errorf("binary fetch = %q, want the body withheld", result)
+	}
+
+	result = mustRunTool(t, FetchURL, FetchURLInput{URL: srv.URL + "/missing"})
+	if !strings.HasPrefix(result, "Status: 404 Not Found") {
+		t.Errorf("missing page = %q, want the 404 status reported", result)
+	}
+
+	if _, err := runTool(t, context.Background(), FetchURL, FetchURLInput{URL: "file:///etc/passwd"}); err == nil || !strings.Contains(err.Error(), "unsupported URL scheme") {
+		t.Errorf("file URL error = %v, want it refused", err)
+	}
+}
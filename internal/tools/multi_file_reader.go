@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+const (
+	// maxReadManyFiles caps how many files one read_many_files call reads
+	maxReadManyFiles = 20
+	// defaultLinesPerFile is how much of each file read_many_files returns by default
+	defaultLinesPerFile = 500
+)
+
+// ReadManyFilesInput defines the input parameters for the read_many_files tool
+type ReadManyFilesInput struct {
+	Paths           []string `json:"paths" jsonschema_description:"The relative paths of the files to read, at most 20."`
+	MaxLinesPerFile int      `json:"max_lines_per_file,omitempty" jsonschema_description:"Return at most this many lines from the start of each file. Defaults to 500; continue a cut-off file with read_more."`
+}
+
+// ReadManyFilesResult is one file's entry in the read_many_files output
+type ReadManyFilesResult struct {
+	Content    string `json:"content,omitempty"`
+	TotalLines int    `json:"total_lines,omitempty"` // set when content stops at max_lines_per_file
+	Error      string `json:"error,omitempty"`
+}
+
+// ReadManyFilesDefinition provides the read_many_files tool definition
+var ReadManyFilesDefinition = agent.ToolDefinition{
+	Name:        "read_many_files",
+	Description: "Read several files in one call, returning a JSON object mapping each path to its content, or to an error if that file couldn't be read. Prefer this over consecutive read_file calls when you already know which files you need. Binary, oversized and likely-secret files are skipped with an error, as read_file would refuse them.",
+	InputSchema: schema.GenerateSchema[ReadManyFilesInput](),
+	Function:    ReadManyFiles,
+	ReadOnly:    true,
+}
+
+// ReadManyFiles reads the start of each requested file, reporting failures per path
+func ReadManyFiles(ctx context.Context, input json.RawMessage) (string, error) {
+	var readManyFilesInput ReadManyFilesInput
+	err := json.Unmarshal(input, &readManyFilesInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if len(readManyFilesInput.Paths) == 0 {
+		return "", fmt.Errorf("paths cannot be empty")
+	}
+	if len(readManyFilesInput.Paths) > maxReadManyFiles {
+		return "", fmt.Errorf("cannot read more than %d files at once, got %d", maxReadManyFiles, len(readManyFilesInput.Paths))
+	}
+
+	maxLines := readManyFilesInput.MaxLinesPerFile
+	if maxLines <= 0 {
+		maxLines = defaultLinesPerFile
+	}
+
+	results := make(map[string]ReadManyFilesResult, len(readManyFilesInput.Paths))
+	for _, path := range readManyFilesInput.Paths {
+		results[path] = readFileStart(path, maxLines)
+	}
+
+	resultJSON, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal results: %w", err)
+	}
+	return string(resultJSON), nil
+}
+
+// readFileStart reads up to maxLines lines of a file, moving its read_more cursor past them
+func readFileStart(path string, maxLines int) ReadManyFilesResult {
+	content, err := readTextFile(path, false, 0)
+	if err != nil {
+		return ReadManyFilesResult{Error: err.Error()}
+	}
+
+	var result ReadManyFilesResult
+	lines := strings.Split(string(content), "\n")
+	if len(lines) > maxLines {
+		result.TotalLines = len(lines)
+		lines = lines[:maxLines]
+	}
+	result.Content = strings.Join(lines, "\n")
+
+	readCursor.Lock()
+	readCursor.lines[filepath.Clean(path)] = len(lines)
+	readCursor.Unlock()
+	return result
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReadManyFilesReportsMissingFilesPerPath(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "a.txt", "alpha\n")
+	writeFile(t, "dir/b.txt", "beta one\nbeta two")
+
+	output := mustRunTool(t, ReadManyFiles, ReadManyFilesInput{Paths: []string{"a.txt", "missing.txt", "dir/b.txt"}})
+	var results map[string]ReadManyFilesResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, output)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want one per path: %v", len(results), results)
+	}
+	if got := results["a.txt"]; got.Content != "alpha\n" || got.Error != "" {
+		t.Errorf("a.txt = %+v", got)
+	}
+	if got := results["dir/b.txt"]; got.Content != "beta one\nbeta two" || got.Error != "" {
+		t.Errorf("dir/b.txt = %+v", got)
+	}
+	// The missing file fails on its own without failing the call
+	if got := results["missing.txt"]; got.Content != "" || !strings.Contains(got.Error, "failed to read file missing.txt") {
+		t.Errorf("missing.txt = %+v, want a read error", got)
+	}
+}
+
+func TestReadManyFilesCutsOffLongFiles(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "long.txt", numberedLines(10))
+
+	output := mustRunTool(t, ReadManyFiles, ReadManyFilesInput{Paths: []string{"long.txt"}, MaxLinesPerFile: 3})
+	var results map[string]ReadManyFilesResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatal(err)
+	}
+	// numberedLines ends with a newline, so the split yields an eleventh, empty line
+	if got := results["long.txt"]; got.Content != "line 1\nline 2\nline 3" || got.TotalLines != 11 {
+		t.Errorf("long.txt = %+v, want its first three lines and the total", got)
+	}
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// ApplyPatchInput defines the input parameters for the apply_patch tool
+type ApplyPatchInput struct {
+	Path  string `json:"path" jsonschema_description:"The path to the file to patch."`
+	Patch string `json:"patch" jsonschema_description:"A unified diff for this one file: one or more hunks starting with '@@ -start,count +start,count @@', with context lines prefixed by ' ', removed lines by '-' and added lines by '+'. ---/+++ file headers are optional."`
+
+	NoBackup       bool `json:"no_backup,omitempty" jsonschema_description:"Skip saving the previous contents under .code-agent/backups before patching."`
+	AllowGenerated bool `json:"allow_generated,omitempty" jsonschema_description:"Set to true to modify a generated file (go.sum, *.pb.go, lock files, generated/ directories). Only do this when the user explicitly asks; otherwise regenerate the file."`
+}
+
+// ApplyPatchDefinition provides the apply_patch tool definition
+var ApplyPatchDefinition = agent.ToolDefinition{
+	Name: "apply_patch",
+	Description: `Apply a unified diff to a file. Prefer this over several edit_file calls for multi-hunk changes.
+
+Every hunk's context and removed lines must match the file exactly; line numbers may be slightly off. If any hunk doesn't match, nothing is written and the failing hunk is reported.`,
+	InputSchema: schema.GenerateSchema[ApplyPatchInput](),
+	Function:    ApplyPatch,
+	EditsFiles:  true,
+}
+
+// patchHunk is one parsed hunk: the lines it expects to find and the lines to put in their place
+type patchHunk struct {
+	header   string
+	oldStart int
+	old      []string
+	new      []string
+}
+
+// ApplyPatch applies the hunks of a unified diff to a file, all or nothing
+func ApplyPatch(ctx context.Context, input json.RawMessage) (string, error) {
+	var applyPatchInput ApplyPatchInput
+	err := json.Unmarshal(input, &applyPatchInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if applyPatchInput.Path == "" || strings.TrimSpace(applyPatchInput.Patch) == "" {
+		return "", fmt.Errorf("invalid input parameters: path and patch must be non-empty")
+	}
+
+	if err := checkGeneratedFile(applyPatchInput.Path, applyPatchInput.AllowGenerated); err != nil {
+		return "", err
+	}
+
+	hunks, err := parseUnifiedDiff(applyPatchInput.Patch)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(applyPatchInput.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	var patched []string
+	var notes []string
+	next := 0
+	for i, hunk := range hunks {
+		expected := hunk.oldStart - 1
+		if len(hunk.old) == 0 {
+			expected = hunk.oldStart // pure insertions name the line they follow
+		}
+
+		at := findHunk(lines, hunk.old, next, expected)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d (%s) does not apply: its context doesn't match %s%s. No changes made",
+				i+1, hunk.header, applyPatchInput.Path, hunkMismatch(lines, hunk.old, expected))
+		}
+		if at != expected {
+			notes = append(notes, fmt.Sprintf("hunk %d applied at line %d (offset %+d)", i+1, at+1, at-expected))
+		}
+
+		patched = append(patched, lines[next:at]...)
+		patched = append(patched, hunk.new...)
+		next = at + len(hunk.old)
+	}
+	patched = append(patched, lines[next:]...)
+
+	if agent.IsDryRun(ctx) {
+		return dryRunEdit(ctx, applyPatchInput.Path, string(content), strings.Join(patched, "\n"),
+			"Would apply %d hunk(s) to %s.", len(hunks), applyPatchInput.Path), nil
+	}
+
+	// Remember whether the file parsed before the patch so we only warn about new breakage
+	errorsBefore, _, _ := syntaxErrors(ctx, applyPatchInput.Path)
+
+	var backupPath string
+	if !applyPatchInput.NoBackup {
+		if backupPath, err = backupFile(applyPatchInput.Path); err != nil {
+			return "", err
+		}
+	}
+
+	err = os.WriteFile(applyPatchInput.Path, []byte(strings.Join(patched, "\n")), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	result := fmt.Sprintf("OK. Applied %d hunk(s) to %s.", len(hunks), applyPatchInput.Path)
+	if len(notes) > 0 {
+		result += " Note: " + strings.Join(notes, "; ") + "."
+	}
+	result += backupNote(backupPath)
+	if errorsBefore == "" {
+		if errorsAfter, supported, err := syntaxErrors(ctx, applyPatchInput.Path); err == nil && supported && errorsAfter != "" {
+			result += "\nWarning: this patch introduced syntax errors:\n" + errorsAfter
+		}
+	}
+	return result, nil
+}
+
+// parseUnifiedDiff splits a unified diff into hunks, ignoring file headers
+func parseUnifiedDiff(patch string) ([]patchHunk, error) {
+	var hunks []patchHunk
+	var current *patchHunk
+	for _, line := range strings.Split(strings.TrimRight(patch, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if match := hunkHeader.FindStringSubmatch(line); match != nil {
+			start, _ := strconv.Atoi(match[1])
+			hunks = append(hunks, patchHunk{header: strings.TrimSpace(match[0]), oldStart: start})
+			current = &hunks[len(hunks)-1]
+			continue
+		}
+		if current == nil {
+			// Lines before the first hunk are headers like diff, index, --- and +++
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "\\"):
+			continue // "\ No newline at end of file"
+		case line == "":
+			// Editors often strip the single space from blank context lines
+			current.old = append(current.old, "")
+			current.new = append(current.new, "")
+		case line[0] == ' ':
+			current.old = append(current.old, line[1:])
+			current.new = append(current.new, line[1:])
+		case line[0] == '-':
+			current.old = append(current.old, line[1:])
+		case line[0] == '+':
+			current.new = append(current.new, line[1:])
+		default:
+			return nil, fmt.Errorf("invalid patch line in hunk %d (%s): %q", len(hunks), current.header, line)
+		}
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch contains no hunks: expected lines starting with '@@ -start,count +start,count @@'")
+	}
+	return hunks, nil
+}
+
+// findHunk returns where old occurs in lines at or after from, preferring the
+// occurrence nearest expected, or -1 if it doesn't occur
+func findHunk(lines, old []string, from, expected int) int {
+	best := -1
+	for at := from; at+len(old) <= len(lines); at++ {
+		if !linesEqual(lines[at:at+len(old)], old) {
+			continue
+		}
+		if best < 0 || distance(at, expected) < distance(best, expected) {
+			best = at
+		}
+	}
+	return best
+}
+
+// hunkMismatch describes the first line where old differs from the file at expected
+func hunkMismatch(lines, old []string, expected int) string {
+	for i, want := range old {
+		at := expected + i
+		if at < 0 || at >= len(lines) {
+			return fmt.Sprintf(" (line %d is past the end of the file)", at+1)
+		}
+		if lines[at] != want {
+			return fmt.Sprintf(" (line %d is %q, expected %q)", at+1, lines[at], want)
+		}
+	}
+	return ""
+}
+
+// linesEqual reports whether two line slices are identical
+func linesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// distance returns how many lines apart a and b are
+func distance(a, b int) int {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatchMultipleHunks(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "notes.txt", numberedLines(20))
+
+	// The second hunk's line numbers are 2 off, as if the file had changed since the diff
+	patch := `--- a/notes.txt
++++ b/notes.txt
+@@ -2,3 +2,3 @@
+ line 2
+-line 3
++line three
+ line 4
+@@ -13,3 +13,4 @@
+ line 15
+ line 16
++line 16.5
+ line 17
+`
+	result := mustRunTool(t, ApplyPatch, ApplyPatchInput{Path: "notes.txt", Patch: patch, NoBackup: true})
+	if !strings.Contains(result, "Applied 2 hunk(s)") || !strings.Contains(result, "hunk 2 applied at line 15 (offset +2)") {
+		t.Errorf("result = %q, want both hunks applied and the offset noted", result)
+	}
+
+	want := strings.Replace(numberedLines(20), "line 3\n", "line three\n", 1)
+	want = strings.Replace(want, "line 16\n", "line 16\nline 16.5\n", 1)
+	if got := readFile(t, "notes.txt"); got != want {
+		t.Errorf("patched file =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApplyPatchPureInsertion(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "notes.txt", numberedLines(3))
+
+	// With no context, the hunk names the line it follows
+	patch := "@@ -2,0 +3,1 @@\n+inserted\n"
+	result := mustRunTool(t, ApplyPatch, ApplyPatchInput{Path: "notes.txt", Patch: patch, NoBackup: true})
+	if strings.Contains(result, "offset") {
+		t.Errorf("result = %q, want the insertion applied where it was expected", result)
+	}
+	if got := readFile(t, "notes.txt"); got != "line 1\nline 2\ninserted\nline 3\n" {
+		t.Errorf("patched file = %q, want the line inserted after line 2", got)
+	}
+}
+
+func TestApplyPatchRejectsContextMismatch(t *testing.T) {
+	inTempDir(t)
+	original := numberedLines(10)
+	writeFile(t, "notes.txt", original)
+
+	// The first hunk applies, but the second doesn't, so nothing is written
+	patch := `@@ -1,2 +1,2 @@
+-line 1
++line one
+ line 2
+@@ -6,2 +6,2 @@
+ line 6
+-line seven
++line 7!
+`
+	_, err := runTool(t, context.Background(), ApplyPatch, ApplyPatchInput{Path: "notes.txt", Patch: patch, NoBackup: true})
+	if err == nil || !strings.Contains(err.Error(), "hunk 2") || !strings.Contains(err.Error(), `line 7 is "line 7", expected "line seven"`) {
+		t.Errorf("err = %v, want hunk 2 rejected with the mismatching line", err)
+	}
+	if got := readFile(t, "notes.txt"); got != original {
+		t.Errorf("file changed despite the rejected patch: %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// maxSearchLineLength truncates long matching lines, e.g. from minified files
+const maxSearchLineLength = 300
+
+// searchIgnoredDirs are directories search_project never descends into
+var searchIgnoredDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// SearchProjectInput defines the input parameters for the search_project tool
+type SearchProjectInput struct {
+	Query          string `json:"query" jsonschema_description:"The string or regex pattern to search for."`
+	PathGlob       string `json:"path_glob,omitempty" jsonschema_description:"Only search files matching this glob, e.g. '*.go', 'internal/**/*.go' or 'src/*.ts'. Patterns without a slash match the file name. Defaults to all files."`
+	IsRegex        bool   `json:"is_regex,omitempty" jsonschema_description:"Treat the query as a regular expression. Defaults to false."`
+	CaseSensitive  bool   `json:"case_sensitive,omitempty" jsonschema_description:"Perform a case-sensitive search. Defaults to false."`
+	MaxResults     int    `json:"max_results,omitempty" jsonschema_description:"The maximum number of matching lines to return across all files. Defaults to 200."`
+	IncludeHidden  *bool  `json:"include_hidden,omitempty" jsonschema_description:"Whether to search hidden files and directories (those starting with a dot). Defaults to the user's preference (usually false). .git is never searched."`
+	AllowSensitive bool   `json:"allow_sensitive,omitempty" jsonschema_description:"Also search files that likely contain secrets (.env, *.pem, id_rsa, credentials). Defaults to false."`
+}
+
+// SearchProjectDefinition provides the search_project tool definition
+var SearchProjectDefinition = agent.ToolDefinition{
+	Name:        "search_project",
+	Description: "Search every file under the working directory for a string or regex pattern, like grep -rn. Returns matching lines with line numbers grouped by file. Skips .git, node_modules, vendor and binary files. Use this to find all usages of a symbol in one call.",
+	InputSchema: schema.GenerateSchema[SearchProjectInput](),
+	Function:    SearchProject,
+	ReadOnly:    true,
+}
+
+// SearchProject searches all matching files under the working directory
+func SearchProject(ctx context.Context, input json.RawMessage) (string, error) {
+	var searchProjectInput SearchProjectInput
+	err := json.Unmarshal(input, &searchProjectInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if searchProjectInput.Query == "" {
+		return "", fmt.Errorf("query must be provided")
+	}
+
+	query := searchProjectInput.Query
+	if !searchProjectInput.IsRegex {
+		query = regexp.QuoteMeta(query)
+	}
+	if !searchProjectInput.CaseSensitive {
+		query = "(?i)" + query
+	}
+	re, err := regexp.Compile(query)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	maxResults := searchProjectInput.MaxResults
+	if maxResults <= 0 {
+		maxResults = 200 // Default max matches
+	}
+
+	pathGlob := filepath.ToSlash(searchProjectInput.PathGlob)
+	includeHidden := resolveIncludeHidden(searchProjectInput.IncludeHidden) || isHiddenPath(pathGlob)
+
+	var result strings.Builder
+	matches, files, omitted := 0, 0, 0
+	err = filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+
+		if d.IsDir() {
+			if path != "." && (searchIgnoredDirs[d.Name()] || (!includeHidden && isHiddenName(d.Name()))) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || (!includeHidden && isHiddenName(d.Name())) {
+			return nil
+		}
+		if pathGlob != "" && !matchesSearchGlob(filepath.ToSlash(path), pathGlob) {
+			return nil
+		}
+		if !searchProjectInput.AllowSensitive && isSensitiveFile(path) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > defaultReadMaxBytes {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || isBinary(content) {
+			return nil
+		}
+
+		fileHeader := false
+		for i, line := range strings.Split(string(content), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches >= maxResults {
+				omitted++
+				continue
+			}
+			if !fileHeader {
+				if files > 0 {
+					result.WriteString("\n")
+				}
+				result.WriteString(path + "\n")
+				fileHeader = true
+				files++
+			}
+			if len(line) > maxSearchLineLength {
+				line = line[:maxSearchLineLength] + "..."
+			}
+			result.WriteString(fmt.Sprintf("  %d: %s\n", i+1, strings.TrimRight(line, "\r")))
+			matches++
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search files: %w", err)
+	}
+
+	if matches == 0 {
+		return fmt.Sprintf("No matches found for %q.", searchProjectInput.Query), nil
+	}
+
+	summary := fmt.Sprintf("Found %d match(es) in %d file(s):\n\n", matches, files)
+	if omitted > 0 {
+		return fmt.Sprintf("%s%s\n%d more match(es) omitted. Narrow the query or path_glob, or raise max_results to see them.", summary, result.String(), omitted), nil
+	}
+	return summary + strings.TrimRight(result.String(), "\n"), nil
+}
+
+// matchesSearchGlob matches a slash-separated path against a glob. Patterns with **
+// match recursively, other patterns with a slash match the whole path, and patterns
+// without one match just the file name.
+func matchesSearchGlob(path, pattern string) bool {
+	if strings.Contains(pattern, "**") {
+		parts := strings.SplitN(pattern, "**", 2)
+		return matchesRecursivePattern(path, strings.TrimSuffix(parts[0], "/"), strings.TrimPrefix(parts[1], "/"))
+	}
+	if strings.Contains(pattern, "/") {
+		matched, _ := filepath.Match(pattern, path)
+		return matched
+	}
+	matched, _ := filepath.Match(pattern, filepath.Base(path))
+	return matched
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchProjectRegex(t *testing.T) {
+	inTempDir(t)
+	writeFile(t, "main.go", "package main\n\nfunc main() {\n\trun()\n}\n\nfunc run() {}\n")
+	writeFile(t, "internal/app/app.go", "package app\n\nfunc Run() error { return nil }\n")
+	writeFile(t, "README.md", "Call run() to start.\n")
+	writeFile(t, "node_modules/dep/index.go", "func run() {}\n")
+
+	result := mustRunTool(t, SearchProject, SearchProjectInput{Query: `^func \w+\(`, IsRegex: true, PathGlob: "*.go", CaseSensitive: true})
+	want := "Found 3 match(es) in 2 file(s):\n\ninternal/app/app.go\n  3: func Run() error { return nil }\n\nmain.go\n  3: func main() {\n  7: func run() {}"
+	if strings.TrimSpace(result) != want {
+		t.Errorf("search result =\n%s\nwant\n%s", result, want)
+	}
+
+	// Case-insensitive by default, across every file type
+	result = mustRunTool(t, SearchProject, SearchProjectInput{Query: `\brun\(\)`, IsRegex: true})
+	for _, match := range []string{"main.go\n  4: \trun()", "README.md\n  1: Call run() to start."} {
+		if !strings.Contains(result, match) {
+			t.Errorf("search result =\n%s\nwant it to contain %q", result, match)
+		}
+	}
+	if strings.Contains(result, "node_modules") {
+		t.Errorf("search result =\n%s\nwant node_modules skipped", result)
+	}
+}
@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// ScopedEditInput defines the input parameters for the scoped_edit tool
+type ScopedEditInput struct {
+	Path      string `json:"path" jsonschema_description:"The path to the file"`
+	Symbol    string `json:"symbol,omitempty" jsonschema_description:"For Go files, the function, method, or type whose body limits the edit. Methods may be written as Type.Method."`
+	StartLine int    `json:"start_line,omitempty" jsonschema_description:"For non-Go files (or instead of symbol), the first line of the range to edit (1-indexed)."`
+	EndLine   int    `json:"end_line,omitempty" jsonschema_description:"For non-Go files (or instead of symbol), the last line of the range to edit (inclusive)."`
+	OldStr    string `json:"old_str" jsonschema_description:"Text to search for within the scope. All occurrences in the scope will be replaced."`
+	NewStr    string `json:"new_str" jsonschema_description:"Text to replace old_str with"`
+
+	AllowGenerated bool `json:"allow_generated,omitempty" jsonschema_description:"Set to true to modify a generated file (go.sum, *.pb.go, lock files, generated/ directories). Only do this when the user explicitly asks; otherwise regenerate the file."`
+}
+
+// ScopedEditDefinition provides the scoped_edit tool definition
+var ScopedEditDefinition = agent.ToolDefinition{
+	Name: "scoped_edit",
+	Description: `Replace text only within a single function, method, or type (Go files) or an explicit line range (any file).
+
+Use this instead of edit_file when old_str also appears outside the code you mean to change, e.g. renaming a local variable in one function.
+For Go files pass 'symbol'; for other files pass 'start_line' and 'end_line'.
+`,
+	InputSchema: schema.GenerateSchema[ScopedEditInput](),
+	Function:    ScopedEdit,
+	EditsFiles:  true,
+}
+
+// ScopedEdit replaces old_str with new_str within the byte range of a symbol or line range
+func ScopedEdit(ctx context.Context, input json.RawMessage) (string, error) {
+	var scopedEditInput ScopedEditInput
+	err := json.Unmarshal(input, &scopedEditInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if scopedEditInput.Path == "" || scopedEditInput.OldStr == "" || scopedEditInput.OldStr == scopedEditInput.NewStr {
+		return "", fmt.Errorf("invalid input parameters: path and old_str must be non-empty, and old_str must be different from new_str")
+	}
+
+	if err := checkGeneratedFile(scopedEditInput.Path, scopedEditInput.AllowGenerated); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(scopedEditInput.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var start, end int
+	var scope string
+	switch {
+	case scopedEditInput.Symbol != "":
+		if filepath.Ext(scopedEditInput.Path) != ".go" {
+			return "", fmt.Errorf("symbol scopes are only supported for Go files; pass start_line and end_line instead")
+		}
+		start, end, err = goSymbolRange(scopedEditInput.Path, content, scopedEditInput.Symbol)
+		if err != nil {
+			return "", err
+		}
+		scope = scopedEditInput.Symbol
+	case scopedEditInput.StartLine > 0 && scopedEditInput.EndLine >= scopedEditInput.StartLine:
+		start, end, err = lineRange(content, scopedEditInput.StartLine, scopedEditInput.EndLine)
+		if err != nil {
+			return "", err
+		}
+		scope = fmt.Sprintf("lines %d-%d", scopedEditInput.StartLine, scopedEditInput.EndLine)
+	default:
+		return "", fmt.Errorf("either symbol or a valid start_line/end_line range must be provided")
+	}
+
+	section := string(content[start:end])
+	replacements := strings.Count(section, scopedEditInput.OldStr)
+	if replacements == 0 {
+		return fmt.Sprintf("No occurrences of `old_str` found in %s. No changes made to the file.", scope), nil
+	}
+
+	newContent := string(content[:start]) + strings.ReplaceAll(section, scopedEditInput.OldStr, scopedEditInput.NewStr) + string(content[end:])
+
+	if agent.IsDryRun(ctx) {
+		return dryRunEdit(ctx, scopedEditInput.Path, string(content), newContent, "Would make %d replacement(s) in %s of %s.", replacements, scope, scopedEditInput.Path), nil
+	}
+
+	err = os.WriteFile(scopedEditInput.Path, []byte(newContent), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return fmt.Sprintf("OK. Edited %s successfully. Made %d replacement(s).", scope, replacements), nil
+}
+
+// goSymbolRange returns the byte range of the named top-level declaration in a Go file
+func goSymbolRange(path string, content []byte, symbol string) (int, int, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	receiver, name := "", symbol
+	if i := strings.LastIndex(symbol, "."); i >= 0 {
+		receiver, name = symbol[:i], symbol[i+1:]
+	}
+
+	var matches []ast.Node
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Name != name {
+				continue
+			}
+			if receiver != "" && (d.Recv == nil || receiverTypeName(d.Recv) != receiver) {
+				continue
+			}
+			matches = append(matches, d)
+		case *ast.GenDecl:
+			if receiver != "" {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == name {
+					matches = append(matches, ts)
+				}
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, 0, fmt.Errorf("symbol %s not found in %s", symbol, path)
+	case 1:
+		return fset.Position(matches[0].Pos()).Offset, fset.Position(matches[0].End()).Offset, nil
+	default:
+		return 0, 0, fmt.Errorf("symbol %s is ambiguous in %s; use Type.Method to pick a method", symbol, path)
+	}
+}
+
+// receiverTypeName returns the base type name of a method receiver
+func receiverTypeName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		if ident, ok := t.X.(*ast.Ident); ok {
+			return ident.Name
+		}
+	case *ast.IndexListExpr:
+		if ident, ok := t.X.(*ast.Ident); ok {
+			return ident.Name
+		}
+	}
+	return ""
+}
+
+// lineRange returns the byte range covering lines start through end (1-indexed, inclusive)
+func lineRange(content []byte, start, end int) (int, int, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	if start > len(lines) {
+		return 0, 0, fmt.Errorf("start_line (%d) is greater than the total number of lines (%d)", start, len(lines))
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	offset := 0
+	for i := 0; i < start-1; i++ {
+		offset += len(lines[i])
+	}
+	length := 0
+	for i := start - 1; i < end; i++ {
+		length += len(lines[i])
+	}
+	return offset, offset + length, nil
+}
@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// UpdateSectionInput defines the input parameters for the update_section tool
+type UpdateSectionInput struct {
+	Path           string `json:"path" jsonschema_description:"The path to a Markdown (.md) or INI/TOML (.ini, .toml, .cfg, .conf) file."`
+	Heading        string `json:"heading" jsonschema_description:"The section to replace. For Markdown, the heading text with or without its leading #s (e.g. '## Usage' or 'Usage'); for INI/TOML, the section name with or without brackets (e.g. '[server]')."`
+	Content        string `json:"content" jsonschema_description:"The new body of the section, without the heading line itself."`
+	AllowGenerated bool   `json:"allow_generated,omitempty" jsonschema_description:"Set to true to modify a generated file (go.sum, *.pb.go, lock files, generated/ directories). Only do this when the user explicitly asks; otherwise regenerate the file."`
+}
+
+// UpdateSectionDefinition provides the update_section tool definition
+var UpdateSectionDefinition = agent.ToolDefinition{
+	Name: "update_section",
+	Description: `Replace the body of a Markdown section (up to the next heading of the same or higher level) or an INI/TOML section (up to the next [section]), or append the section if it doesn't exist.
+Prefer this over edit_file for docs and config, since it doesn't require reproducing the existing text exactly.`,
+	InputSchema: schema.GenerateSchema[UpdateSectionInput](),
+	Function:    UpdateSection,
+	EditsFiles:  true,
+}
+
+// UpdateSection replaces or inserts a section of a Markdown or INI/TOML file
+func UpdateSection(ctx context.Context, input json.RawMessage) (string, error) {
+	var updateSectionInput UpdateSectionInput
+	err := json.Unmarshal(input, &updateSectionInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	path, heading := updateSectionInput.Path, strings.TrimSpace(updateSectionInput.Heading)
+	if path == "" || heading == "" {
+		return "", fmt.Errorf("invalid input parameters: path and heading must be non-empty")
+	}
+
+	if err := checkGeneratedFile(path, updateSectionInput.AllowGenerated); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	lines := strings.Split(string(content), "\n")
+
+	var headingLine string
+	var start, end int
+	var found bool
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		headingLine, start, end, found = findMarkdownSection(lines, heading)
+	case ".ini", ".toml", ".cfg", ".conf":
+		headingLine, start, end, found = findINISection(lines, heading)
+	default:
+		return "", fmt.Errorf("unsupported file type %q: expected Markdown or INI/TOML", filepath.Ext(path))
+	}
+
+	body := strings.Split(strings.TrimRight(updateSectionInput.Content, "\n"), "\n")
+	var updated []string
+	action := "Replaced"
+	if found {
+		// Keep the existing spacing under the heading, and a blank line before the next section
+		if start+1 < end && strings.TrimSpace(lines[start+1]) == "" && body[0] != "" {
+			body = append([]string{""}, body...)
+		}
+		if end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+			body = append(body, "")
+		}
+		updated = append(updated, lines[:start+1]...)
+		updated = append(updated, body...)
+		updated = append(updated, lines[end:]...)
+	} else {
+		action = "Inserted"
+		updated = lines
+		for len(updated) > 0 && strings.TrimSpace(updated[len(updated)-1]) == "" {
+			updated = updated[:len(updated)-1]
+		}
+		if len(updated) > 0 {
+			updated = append(updated, "")
+		}
+		updated = append(updated, headingLine)
+		if strings.HasPrefix(headingLine, "#") {
+			updated = append(updated, "")
+		}
+		updated = append(updated, body...)
+		updated = append(updated, "")
+	}
+
+	if agent.IsDryRun(ctx) {
+		verb := "replace"
+		if action == "Inserted" {
+			verb = "insert"
+		}
+		return dryRunEdit(ctx, path, string(content), strings.Join(updated, "\n"),
+			"Would %s section %q in %s.", verb, headingLine, path), nil
+	}
+
+	err = os.WriteFile(path, []byte(strings.Join(updated, "\n")), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return fmt.Sprintf("OK. %s section %q in %s.", action, headingLine, path), nil
+}
+
+// findMarkdownSection locates a heading and the line where its section ends. Without
+// leading #s the heading matches at any level; with them the level must match too.
+// It returns the heading line to use when inserting if the section isn't found.
+func findMarkdownSection(lines []string, heading string) (headingLine string, start, end int, found bool) {
+	wantLevel := len(heading) - len(strings.TrimLeft(heading, "#"))
+	wantText := strings.TrimSpace(strings.TrimLeft(heading, "#"))
+	headingLine = heading
+	if wantLevel == 0 {
+		headingLine = "## " + wantText
+	}
+
+	level := 0
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		lineLevel, text := markdownHeading(line)
+		if lineLevel == 0 {
+			continue
+		}
+		if found {
+			if lineLevel <= level {
+				return headingLine, start, i, true
+			}
+			continue
+		}
+		if text == wantText && (wantLevel == 0 || wantLevel == lineLevel) {
+			headingLine, start, level, found = line, i, lineLevel, true
+		}
+	}
+
+	if found {
+		return headingLine, start, trimTrailingBlank(lines, len(lines)), true
+	}
+	return headingLine, 0, 0, false
+}
+
+// markdownHeading returns an ATX heading's level and text, or level 0 for other lines
+func markdownHeading(line string) (int, string) {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	if level == 0 || level > 6 || (len(line) > level && line[level] != ' ') {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+}
+
+// findINISection locates a [section] header and the line where the next one starts
+func findINISection(lines []string, heading string) (headingLine string, start, end int, found bool) {
+	name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(heading, "["), "]"))
+	headingLine = "[" + name + "]"
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "[") {
+			continue
+		}
+		if found {
+			return lines[start], start, i, true
+		}
+		if strings.TrimSpace(strings.Trim(trimmed, "[]")) == name {
+			start, found = i, true
+		}
+	}
+
+	if found {
+		return lines[start], start, trimTrailingBlank(lines, len(lines)), true
+	}
+	return headingLine, 0, 0, false
+}
+
+// trimTrailingBlank moves end back over the file's trailing blank lines so they are kept
+func trimTrailingBlank(lines []string, end int) int {
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// sensitivePatterns match file names that likely contain secrets
+var sensitivePatterns = []string{
+	".env",
+	".env.*",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"*.pfx",
+	"id_rsa*",
+	"id_dsa*",
+	"id_ecdsa*",
+	"id_ed25519*",
+	"credentials",
+	"credentials.*",
+	".netrc",
+	".npmrc",
+	".pgpass",
+}
+
+// isSensitiveFile reports whether the file name matches a known secret pattern
+func isSensitiveFile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, pattern := range sensitivePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSensitiveFile refuses likely-secret files unless explicitly allowed
+func checkSensitiveFile(path string, allowSensitive bool) error {
+	if !allowSensitive && isSensitiveFile(path) {
+		return fmt.Errorf("refused to read likely-secret file %s; pass allow_sensitive=true to override", path)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// SetVarInput defines the input parameters for the set_var tool
+type SetVarInput struct {
+	Name  string `json:"name" jsonschema_description:"The variable name."`
+	Value string `json:"value" jsonschema_description:"The value to store. Overwrites any existing value."`
+}
+
+// GetVarInput defines the input parameters for the get_var tool
+type GetVarInput struct {
+	Name string `json:"name" jsonschema_description:"The variable name."`
+}
+
+// ListVarsInput defines the input parameters for the list_vars tool
+type ListVarsInput struct{}
+
+// SetVarDefinition provides the set_var tool definition
+var SetVarDefinition = agent.ToolDefinition{
+	Name:             "set_var",
+	Description:      "Remember a value for the rest of the session, e.g. the project's test command or the line a bug is on. Use get_var or list_vars later instead of re-deriving it.",
+	InputSchema:      schema.GenerateSchema[SetVarInput](),
+	Function:         SetVar,
+	ReadOnly:         true,
+	SkipConfirmation: true,
+}
+
+// GetVarDefinition provides the get_var tool definition
+var GetVarDefinition = agent.ToolDefinition{
+	Name:             "get_var",
+	Description:      "Recall a value previously stored with set_var.",
+	InputSchema:      schema.GenerateSchema[GetVarInput](),
+	Function:         GetVar,
+	ReadOnly:         true,
+	SkipConfirmation: true,
+}
+
+// ListVarsDefinition provides the list_vars tool definition
+var ListVarsDefinition = agent.ToolDefinition{
+	Name:             "list_vars",
+	Description:      "List every value stored with set_var this session.",
+	InputSchema:      schema.GenerateSchema[ListVarsInput](),
+	Function:         ListVars,
+	ReadOnly:         true,
+	SkipConfirmation: true,
+}
+
+// SetVar stores a session variable
+func SetVar(ctx context.Context, input json.RawMessage) (string, error) {
+	var setVarInput SetVarInput
+	err := json.Unmarshal(input, &setVarInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if strings.TrimSpace(setVarInput.Name) == "" {
+		return "", fmt.Errorf("name cannot be empty")
+	}
+
+	vars, err := sessionVars(ctx)
+	if err != nil {
+		return "", err
+	}
+	vars.Set(setVarInput.Name, setVarInput.Value)
+
+	return fmt.Sprintf("OK. Stored %s.", setVarInput.Name), nil
+}
+
+// GetVar returns a session variable
+func GetVar(ctx context.Context, input json.RawMessage) (string, error) {
+	var getVarInput GetVarInput
+	err := json.Unmarshal(input, &getVarInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	vars, err := sessionVars(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, ok := vars.Get(getVarInput.Name)
+	if !ok {
+		return "", fmt.Errorf("variable %s is not set", getVarInput.Name)
+	}
+	return value, nil
+}
+
+// ListVars returns all session variables sorted by name
+func ListVars(ctx context.Context, input json.RawMessage) (string, error) {
+	vars, err := sessionVars(ctx)
+	if err != nil {
+		return "", err
+	}
+	values := vars.All()
+	if len(values) == 0 {
+		return "No variables set.", nil
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result strings.Builder
+	for _, name := range names {
+		result.WriteString(fmt.Sprintf("%s = %s\n", name, values[name]))
+	}
+	return strings.TrimSpace(result.String()), nil
+}
+
+// sessionVars returns the variables of the conversation the tool is running in
+func sessionVars(ctx context.Context) (*agent.SessionVars, error) {
+	vars := agent.SessionVarsFrom(ctx)
+	if vars == nil {
+		return nil, fmt.Errorf("no session is available to store variables")
+	}
+	return vars, nil
+}
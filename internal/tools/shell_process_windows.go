@@ -0,0 +1,9 @@
+//go:build windows
+
+package tools
+
+import "os/exec"
+
+// killProcessGroupOnCancel is a no-op on Windows, where cancellation kills only the
+// shell and WaitDelay bounds how long its children can hold the output pipes
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -1,22 +1,34 @@
 package tools
 
 import (
-	"context"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"agent/internal/agent"
 	"agent/internal/schema"
 	"runtime"
 )
 
+// defaultShellTimeoutSeconds applies when run_shell_command is called without a timeout
+const defaultShellTimeoutSeconds = 30
+
+// shellWaitDelay bounds how long a cancelled command may keep its output pipes open
+const shellWaitDelay = 2 * time.Second
+
 // RunShellCommandInput defines the input parameters for the run_shell_command tool
 type RunShellCommandInput struct {
 	Command   string `json:"command" jsonschema_description:"The shell command to execute."`
 	Directory string `json:"directory,omitempty" jsonschema_description:"The directory to run the command in. Defaults to the current directory."`
+
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" jsonschema_description:"Kill the command if it runs longer than this many seconds. Defaults to 30; raise it for slow builds or test suites."`
 }
 
 // RunShellCommandOutput defines the output of the run_shell_command tool
@@ -36,6 +48,7 @@ The command is executed within a bash shell.
 It returns the stdout, stderr, and exit code.`,
 	InputSchema: schema.GenerateSchema[RunShellCommandInput](),
 	Function:    RunShellCommand,
+	Precheck:    precheckShellCommand,
 }
 
 // RunShellCommand executes a shell command and returns its output.
@@ -49,6 +62,14 @@ func RunShellCommand(ctx context.Context, input json.RawMessage) (string, error)
 	if runShellCommandInput.Command == "" {
 		return "", fmt.Errorf("command cannot be empty")
 	}
+	if err := checkShellCommand(runShellCommandInput.Command); err != nil {
+		return "", err
+	}
+
+	// A command could change anything, so in a dry run it isn't run at all
+	if agent.IsDryRun(ctx) {
+		return dryRunNote("Would run `%s`.", runShellCommandInput.Command), nil
+	}
 
 	var shell, shellArg string
 	if runtime.GOOS == "windows" {
@@ -59,7 +80,18 @@ func RunShellCommand(ctx context.Context, input json.RawMessage) (string, error)
 		shellArg = "-c"
 	}
 
-	cmd := exec.Command(shell, shellArg, runShellCommandInput.Command)
+	timeoutSeconds := runShellCommandInput.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultShellTimeoutSeconds
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	// Cancelling the agent turn or hitting the timeout kills the command; WaitDelay
+	// stops a lingering child that still holds the output pipes from blocking the return
+	cmd := exec.CommandContext(cmdCtx, shell, shellArg, runShellCommandInput.Command)
+	cmd.WaitDelay = shellWaitDelay
+	killProcessGroupOnCancel(cmd)
 
 	if runShellCommandInput.Directory != "" {
 		cmd.Dir = runShellCommandInput.Directory
@@ -69,7 +101,24 @@ func RunShellCommand(ctx context.Context, input json.RawMessage) (string, error)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	// Stream lines to the UI as they arrive, while still collecting the full output
+	var outLines, errLines *lineWriter
+	if observe, ok := agent.ObservedOutput(ctx); ok {
+		var mu sync.Mutex
+		outLines = &lineWriter{mu: &mu, emit: observe}
+		errLines = &lineWriter{mu: &mu, emit: observe}
+		cmd.Stdout = io.MultiWriter(&stdout, outLines)
+		cmd.Stderr = io.MultiWriter(&stderr, errLines)
+	}
+
 	err = cmd.Run()
+	if outLines != nil {
+		outLines.flush()
+		errLines.flush()
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", fmt.Errorf("command cancelled: %w", ctxErr)
+	}
 
 	output := RunShellCommandOutput{
 		Stdout:   stdout.String(),
@@ -77,7 +126,11 @@ func RunShellCommand(ctx context.Context, input json.RawMessage) (string, error)
 		ExitCode: 0,
 	}
 
-	if err != nil {
+	if cmdCtx.Err() == context.DeadlineExceeded {
+		// Keep whatever output the command produced before it was killed
+		output.ExitCode = -1
+		output.Error = fmt.Sprintf("command timed out after %ds", timeoutSeconds)
+	} else if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
 				output.ExitCode = status.ExitStatus()
@@ -98,3 +151,47 @@ func RunShellCommand(ctx context.Context, input json.RawMessage) (string, error)
 
 	return string(resultJSON), nil
 }
+
+// lineWriter passes each complete line written to it to emit, holding back a partial
+// last line until more output or flush completes it
+type lineWriter struct {
+	mu      *sync.Mutex // shared by stdout and stderr so lines are emitted one at a time
+	emit    func(line string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.emit(lastRedraw(string(w.partial[:i])))
+		w.partial = w.partial[i+1:]
+	}
+}
+
+// flush emits any output left without a trailing newline
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.emit(lastRedraw(string(w.partial)))
+		w.partial = nil
+	}
+}
+
+// lastRedraw returns what a terminal would show of a line that uses carriage returns
+// to redraw itself, as progress bars do
+func lastRedraw(line string) string {
+	line = strings.TrimSuffix(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		return line[i+1:]
+	}
+	return line
+}
@@ -0,0 +1,90 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"agent/internal/agent"
+)
+
+func TestRunShellCommandCancelled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := runTool(t, ctx, RunShellCommand, RunShellCommandInput{Command: "sleep 10"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("returned after %s, want promptly after cancelling", elapsed)
+	}
+}
+
+func TestRunShellCommandTimesOut(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	start := time.Now()
+	result := mustRunTool(t, RunShellCommand, RunShellCommandInput{Command: "echo started; sleep 5", TimeoutSeconds: 1})
+	var output RunShellCommandOutput
+	if err := json.Unmarshal([]byte(result), &output); err != nil {
+		t.Fatalf("result isn't JSON: %v\n%s", err, result)
+	}
+	if output.Error != "command timed out after 1s" || output.ExitCode != -1 {
+		t.Errorf("output = %+v, want the timeout reported with exit code -1", output)
+	}
+	if output.Stdout != "started\n" {
+		t.Errorf("stdout = %q, want the output from before the timeout", output.Stdout)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("returned after %s, want soon after the 1s timeout", elapsed)
+	}
+}
+
+func TestRunShellCommandStreamsLines(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Record when each line arrives, to check they come while the command runs
+	type arrival struct {
+		line string
+		at   time.Time
+	}
+	var mu sync.Mutex
+	var arrivals []arrival
+	ctx := agent.WithOutputObserver(context.Background(), func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		arrivals = append(arrivals, arrival{line, time.Now()})
+	})
+
+	start := time.Now()
+	_, err := runTool(t, ctx, RunShellCommand, RunShellCommandInput{
+		Command: `for i in 1 2 3; do echo "line $i"; sleep 0.3; done; printf 'progress 50%%\rprogress 100%%'`,
+	})
+	if err != nil {
+		t.Fatalf("RunShellCommand: %v", err)
+	}
+	finished := time.Now()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"line 1", "line 2", "line 3", "progress 100%"}
+	if len(arrivals) != len(want) {
+		t.Fatalf("observed %d lines, want %v", len(arrivals), want)
+	}
+	for i, a := range arrivals {
+		if a.line != want[i] {
+			t.Errorf("line %d = %q, want %q", i, a.line, want[i])
+		}
+	}
+	if early := arrivals[0].at.Sub(start); early > finished.Sub(start)/2 {
+		t.Errorf("first line arrived after %s of %s, want it delivered before the command finished", early, finished.Sub(start))
+	}
+}
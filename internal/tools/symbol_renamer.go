@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// RenameSymbolInput defines the input parameters for the rename_symbol tool
+type RenameSymbolInput struct {
+	PackageDir string `json:"package_dir" jsonschema_description:"The relative path of the Go package directory."`
+	OldName    string `json:"old_name" jsonschema_description:"The current identifier name."`
+	NewName    string `json:"new_name" jsonschema_description:"The new identifier name."`
+	Line       int    `json:"line,omitempty" jsonschema_description:"Optional line number of the declaration, used to pick one symbol when several share old_name."`
+	File       string `json:"file,omitempty" jsonschema_description:"Optional file name of the declaration, used together with line to disambiguate."`
+}
+
+// RenameSymbolDefinition provides the rename_symbol tool definition
+var RenameSymbolDefinition = agent.ToolDefinition{
+	Name: "rename_symbol",
+	Description: `Rename a Go identifier across a single package using go/ast and go/types, updating its declaration and every reference.
+Safer than text replacement: only references that resolve to the same symbol are changed.
+Fails if several symbols share old_name (pass file and line of the declaration to choose one) or if new_name would conflict.
+References in other packages are not updated.`,
+	InputSchema: schema.GenerateSchema[RenameSymbolInput](),
+	Function:    RenameSymbol,
+	EditsFiles:  true,
+}
+
+// RenameSymbol renames a Go identifier and all its references within a package
+func RenameSymbol(ctx context.Context, input json.RawMessage) (string, error) {
+	var renameInput RenameSymbolInput
+	err := json.Unmarshal(input, &renameInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if renameInput.PackageDir == "" || renameInput.OldName == "" || renameInput.NewName == "" {
+		return "", fmt.Errorf("package_dir, old_name, and new_name must be provided")
+	}
+	if !token.IsIdentifier(renameInput.NewName) {
+		return "", fmt.Errorf("new_name %q is not a valid Go identifier", renameInput.NewName)
+	}
+	if renameInput.OldName == renameInput.NewName {
+		return "", fmt.Errorf("old_name and new_name must be different")
+	}
+
+	fset := token.NewFileSet()
+	files, err := parsePackageFiles(fset, renameInput.PackageDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Type-check leniently so unresolved imports don't block renaming local symbols
+	info := &types.Info{
+		Defs:   make(map[*ast.Ident]types.Object),
+		Uses:   make(map[*ast.Ident]types.Object),
+		Scopes: make(map[ast.Node]*types.Scope),
+		Types:  make(map[ast.Expr]types.TypeAndValue),
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, info)
+
+	target, err := findRenameTarget(fset, info, renameInput)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkRenameConflict(fset, pkg, files, info, target, renameInput.NewName); err != nil {
+		return "", err
+	}
+
+	// Collect every identifier offset that refers to the target, grouped by file
+	edits := make(map[string][]int)
+	for _, refs := range []map[*ast.Ident]types.Object{info.Defs, info.Uses} {
+		for ident, obj := range refs {
+			if obj == target {
+				pos := fset.Position(ident.Pos())
+				edits[pos.Filename] = append(edits[pos.Filename], pos.Offset)
+			}
+		}
+	}
+
+	if agent.IsDryRun(ctx) {
+		count := 0
+		for _, offsets := range edits {
+			count += len(offsets)
+		}
+		return dryRunNote("Would rename %s to %s: %d occurrence(s) in %d file(s).", renameInput.OldName, renameInput.NewName, count, len(edits)), nil
+	}
+
+	count := 0
+	for filename, offsets := range edits {
+		if err := renameInFile(filename, offsets, renameInput.OldName, renameInput.NewName); err != nil {
+			return "", err
+		}
+		count += len(offsets)
+	}
+
+	result := fmt.Sprintf("OK. Renamed %s to %s: %d occurrence(s) in %d file(s).", renameInput.OldName, renameInput.NewName, count, len(edits))
+	if target.Exported() {
+		result += " Note: the symbol is exported; references in other packages were not updated."
+	}
+	return result, nil
+}
+
+// parsePackageFiles parses the Go files of the package in dir, skipping external _test packages
+func parsePackageFiles(fset *token.FileSet, dir string) ([]*ast.File, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Go files: %w", err)
+	}
+
+	var files []*ast.File
+	for _, path := range matches {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if strings.HasSuffix(file.Name.Name, "_test") {
+			continue
+		}
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files found in %s", dir)
+	}
+	return files, nil
+}
+
+// findRenameTarget resolves old_name to exactly one declared object
+func findRenameTarget(fset *token.FileSet, info *types.Info, input RenameSymbolInput) (types.Object, error) {
+	var candidates []types.Object
+	for ident, obj := range info.Defs {
+		if obj == nil || ident.Name != input.OldName {
+			continue
+		}
+		if input.Line > 0 {
+			pos := fset.Position(ident.Pos())
+			if pos.Line != input.Line || (input.File != "" && filepath.Base(pos.Filename) != filepath.Base(input.File)) {
+				continue
+			}
+		}
+		candidates = append(candidates, obj)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no declaration of %s found in the package", input.OldName)
+	case 1:
+		return candidates[0], nil
+	}
+
+	var locations []string
+	for _, obj := range candidates {
+		locations = append(locations, fset.Position(obj.Pos()).String())
+	}
+	sort.Strings(locations)
+	return nil, fmt.Errorf("rename is ambiguous: %s is declared %d times (%s); pass file and line to choose one",
+		input.OldName, len(candidates), strings.Join(locations, ", "))
+}
+
+// checkRenameConflict reports an error if renaming target to newName would clash with
+// another declaration or change what an existing reference resolves to
+func checkRenameConflict(fset *token.FileSet, pkg *types.Package, files []*ast.File, info *types.Info, target types.Object, newName string) error {
+	// Fields and methods have no scope; they clash with other members of their type
+	if owner := memberOwner(info, target); owner != nil {
+		if conflict, _, _ := types.LookupFieldOrMethod(owner, true, pkg, newName); conflict != nil {
+			return fmt.Errorf("cannot rename: %s is already declared at %s", newName, fset.Position(conflict.Pos()))
+		}
+		return nil
+	}
+
+	scope := target.Parent()
+	if scope == nil {
+		return nil
+	}
+	if _, conflict := scope.LookupParent(newName, token.NoPos); conflict != nil {
+		return fmt.Errorf("cannot rename: %s is already declared at %s", newName, fset.Position(conflict.Pos()))
+	}
+
+	// Imports live in file scopes, which the package scope's lookup doesn't see
+	if scope == pkg.Scope() {
+		for _, file := range files {
+			if conflict := info.Scopes[file].Lookup(newName); conflict != nil {
+				return fmt.Errorf("cannot rename: %s is imported at %s", newName, fset.Position(conflict.Pos()))
+			}
+		}
+	}
+
+	// A newName declared between a reference and the target's scope would capture it
+	for ident, obj := range info.Uses {
+		if obj != target {
+			continue
+		}
+		inner := pkg.Scope().Innermost(ident.Pos())
+		if inner == nil {
+			continue
+		}
+		if found, conflict := inner.LookupParent(newName, ident.Pos()); conflict != nil && found != scope {
+			return fmt.Errorf("cannot rename: the reference at %s would refer to the %s declared at %s instead",
+				fset.Position(ident.Pos()), newName, fset.Position(conflict.Pos()))
+		}
+	}
+	return nil
+}
+
+// memberOwner returns the type holding target if it is a method or struct field, or nil
+func memberOwner(info *types.Info, target types.Object) types.Type {
+	switch obj := target.(type) {
+	case *types.Func:
+		if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+			return recv.Type()
+		}
+	case *types.Var:
+		if !obj.IsField() {
+			return nil
+		}
+		// Prefer the named type, whose methods can clash with the field too
+		for _, def := range info.Defs {
+			if typeName, ok := def.(*types.TypeName); ok && hasField(typeName.Type().Underlying(), obj) {
+				return typeName.Type()
+			}
+		}
+		for _, tv := range info.Types {
+			if hasField(tv.Type, obj) {
+				return tv.Type
+			}
+		}
+	}
+	return nil
+}
+
+// hasField reports whether t is a struct declaring field
+func hasField(t types.Type, field *types.Var) bool {
+	st, ok := t.(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := range st.NumFields() {
+		if st.Field(i) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// renameInFile replaces the identifier at each byte offset in the file
+func renameInFile(filename string, offsets []int, oldName, newName string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+
+	// Apply from the end so earlier offsets stay valid
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	for _, offset := range offsets {
+		if offset+len(oldName) > len(content) || string(content[offset:offset+len(oldName)]) != oldName {
+			return fmt.Errorf("unexpected content at %s offset %d; file changed during rename", filename, offset)
+		}
+		content = append(content[:offset], append([]byte(newName), content[offset+len(oldName):]...)...)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", filename, err)
+	}
+	if err := os.WriteFile(filename, content, info.Mode()); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRenameSymbolRefusesConflicts(t *testing.T) {
+	inTempDir(t)
+	// The import needn't resolve, since packages are type-checked leniently
+	source := `package calc
+
+import "example.com/missing/strings"
+
+var count = 1
+
+func f() int {
+	total := 2
+	return count + total
+}
+
+func g() int {
+	return count
+}
+
+func h() string {
+	return strings.TrimSpace(" x ")
+}
+
+type T struct {
+	name string
+	size int
+}
+
+func (T) A() int { return 1 }
+
+func (T) B() int { return 2 }
+`
+	writeFile(t, "calc/calc.go", source)
+
+	tests := []struct {
+		name    string
+		input   RenameSymbolInput
+		refused string
+	}{
+		{"local shadows a reference", RenameSymbolInput{PackageDir: "calc", OldName: "count", NewName: "total"}, "would refer to the total declared at"},
+		{"method clashes with a method", RenameSymbolInput{PackageDir: "calc", OldName: "A", NewName: "B"}, "B is already declared at"},
+		{"field clashes with a field", RenameSymbolInput{PackageDir: "calc", OldName: "name", NewName: "size"}, "size is already declared at"},
+		{"field clashes with a method", RenameSymbolInput{PackageDir: "calc", OldName: "name", NewName: "A"}, "A is already declared at"},
+		{"package name clashes with an import", RenameSymbolInput{PackageDir: "calc", OldName: "count", NewName: "strings"}, "strings is imported at"},
+	}
+	for _, tt := range tests {
+		_, err := runTool(t, context.Background(), RenameSymbol, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.refused) {
+			t.Errorf("%s: err = %v, want it refused with %q", tt.name, err, tt.refused)
+		}
+	}
+	if got := readFile(t, "calc/calc.go"); got != source {
+		t.Fatalf("a refused rename changed the file:\n%s", got)
+	}
+
+	// Shadowing is fine where the renamed symbol isn't referenced
+	mustRunTool(t, RenameSymbol, RenameSymbolInput{PackageDir: "calc", OldName: "A", NewName: "C"})
+	mustRunTool(t, RenameSymbol, RenameSymbolInput{PackageDir: "calc", OldName: "count", NewName: "limit"})
+	got := readFile(t, "calc/calc.go")
+	if !strings.Contains(got, "func (T) C() int") || !strings.Contains(got, "return limit + total") || strings.Contains(got, "count") {
+		t.Errorf("renamed file =\n%s", got)
+	}
+}
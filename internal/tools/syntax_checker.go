@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// CheckSyntaxInput defines the input parameters for the check_syntax tool
+type CheckSyntaxInput struct {
+	Path string `json:"path" jsonschema_description:"The relative path of the file to check."`
+}
+
+// CheckSyntaxDefinition provides the check_syntax tool definition
+var CheckSyntaxDefinition = agent.ToolDefinition{
+	Name: "check_syntax",
+	Description: `Parse a source file and report syntax errors with line numbers, without building the project.
+Supports Go (built in), JSON (built in), JavaScript (node --check), and Python (python -m py_compile).
+Call this after editing a file to verify the edit didn't break it.`,
+	InputSchema: schema.GenerateSchema[CheckSyntaxInput](),
+	Function:    CheckSyntax,
+	ReadOnly:    true,
+}
+
+// CheckSyntax reports syntax errors in a file
+func CheckSyntax(ctx context.Context, input json.RawMessage) (string, error) {
+	var checkSyntaxInput CheckSyntaxInput
+	err := json.Unmarshal(input, &checkSyntaxInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if checkSyntaxInput.Path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	problems, supported, err := syntaxErrors(ctx, checkSyntaxInput.Path)
+	if err != nil {
+		return "", err
+	}
+	if !supported {
+		return fmt.Sprintf("Syntax checking is not supported for %s files.", filepath.Ext(checkSyntaxInput.Path)), nil
+	}
+	if problems == "" {
+		return fmt.Sprintf("OK. %s parsed without syntax errors.", checkSyntaxInput.Path), nil
+	}
+	return fmt.Sprintf("Syntax errors in %s:\n%s", checkSyntaxInput.Path, problems), nil
+}
+
+// syntaxErrors parses path and returns a description of any syntax errors,
+// and whether the file type is supported at all
+func syntaxErrors(ctx context.Context, path string) (string, bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		_, err := parser.ParseFile(token.NewFileSet(), path, content, parser.AllErrors)
+		if err == nil {
+			return "", true, nil
+		}
+		if list, ok := err.(scanner.ErrorList); ok {
+			var lines []string
+			for _, e := range list {
+				lines = append(lines, fmt.Sprintf("- line %d, column %d: %s", e.Pos.Line, e.Pos.Column, e.Msg))
+			}
+			return strings.Join(lines, "\n"), true, nil
+		}
+		return "- " + err.Error(), true, nil
+	case ".json":
+		var v interface{}
+		if err := json.Unmarshal(content, &v); err != nil {
+			if syntaxErr, ok := err.(*json.SyntaxError); ok {
+				line := bytes.Count(content[:syntaxErr.Offset], []byte("\n")) + 1
+				return fmt.Sprintf("- line %d: %s", line, syntaxErr.Error()), true, nil
+			}
+			return "- " + err.Error(), true, nil
+		}
+		return "", true, nil
+	case ".js", ".mjs", ".cjs":
+		return externalSyntaxCheck(ctx, "node", "--check", path)
+	case ".py":
+		return externalSyntaxCheck(ctx, "python3", "-m", "py_compile", path)
+	}
+	return "", false, nil
+}
+
+// externalSyntaxCheck runs a language's own checker and returns its output on failure
+func externalSyntaxCheck(ctx context.Context, name string, args ...string) (string, bool, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", false, nil
+	}
+
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return strings.TrimSpace(string(output)), true, nil
+		}
+		return "", true, fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	return "", true, nil
+}
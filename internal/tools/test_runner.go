@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// testRunTimeout bounds a single test run so a hanging test can't stall the agent
+const testRunTimeout = 5 * time.Minute
+
+// RunTestInput defines the input parameters for the run_test tool
+type RunTestInput struct {
+	PathOrPackage string `json:"path_or_package" jsonschema_description:"The test file, directory or Go package containing the test, e.g. './internal/tools', 'tests/test_api.py' or 'src/app.test.ts'."`
+	TestName      string `json:"test_name" jsonschema_description:"The test to run. Go subtests use 'TestX/sub'; pytest class methods use 'TestClass::test_method'."`
+	Framework     string `json:"framework,omitempty" jsonschema_description:"One of 'go', 'pytest' or 'jest'. Detected from the path when omitted."`
+}
+
+// RunTestDefinition provides the run_test tool definition
+var RunTestDefinition = agent.ToolDefinition{
+	Name:        "run_test",
+	Description: "Run a single test by name (go test -run, pytest path::name, jest -t) and return its output. Prefer this over running the whole suite while iterating on one failing test.",
+	InputSchema: schema.GenerateSchema[RunTestInput](),
+	Function:    RunTest,
+}
+
+// RunTest runs one named test with the framework that owns the path
+func RunTest(ctx context.Context, input json.RawMessage) (string, error) {
+	var runTestInput RunTestInput
+	err := json.Unmarshal(input, &runTestInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if strings.TrimSpace(runTestInput.TestName) == "" {
+		return "", fmt.Errorf("test_name cannot be empty")
+	}
+	if runTestInput.PathOrPackage == "" {
+		runTestInput.PathOrPackage = "."
+	}
+
+	framework := runTestInput.Framework
+	if framework == "" {
+		framework = detectTestFramework(runTestInput.PathOrPackage)
+	}
+
+	var name string
+	var args []string
+	switch framework {
+	case "go":
+		name, args = "go", goTestArgs(runTestInput.PathOrPackage, runTestInput.TestName)
+	case "pytest":
+		name, args = "python3", []string{"-m", "pytest", "-q", runTestInput.PathOrPackage + "::" + runTestInput.TestName}
+	case "jest":
+		name, args = "npx", []string{"jest", runTestInput.PathOrPackage, "-t", regexp.QuoteMeta(runTestInput.TestName)}
+	default:
+		return "", fmt.Errorf("unsupported test framework %q: expected go, pytest or jest", framework)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, testRunTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err = cmd.Run()
+
+	status := "PASS"
+	if ctx.Err() == context.DeadlineExceeded {
+		status = fmt.Sprintf("TIMEOUT after %s", testRunTimeout)
+	} else if exitErr, ok := err.(*exec.ExitError); ok {
+		status = fmt.Sprintf("FAIL (exit code %d)", exitErr.ExitCode())
+	} else if err != nil {
+		return "", fmt.Errorf("failed to run %s: %w", name, err)
+	}
+
+	return fmt.Sprintf("$ %s %s\n%s\n\n%s", name, strings.Join(args, " "), status, strings.TrimSpace(output.String())), nil
+}
+
+// detectTestFramework guesses the test framework from a path's extension or project files
+func detectTestFramework(path string) string {
+	switch strings.ToLower(filepath.Ext(strings.SplitN(path, "::", 2)[0])) {
+	case ".go":
+		return "go"
+	case ".py":
+		return "pytest"
+	case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs":
+		return "jest"
+	}
+
+	dir := strings.TrimSuffix(path, "/...")
+	for _, marker := range []struct{ file, framework string }{
+		{"go.mod", "go"},
+		{"package.json", "jest"},
+		{"pytest.ini", "pytest"},
+		{"pyproject.toml", "pytest"},
+		{"setup.py", "pytest"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			return marker.framework
+		}
+	}
+
+	// Go packages rarely carry a marker of their own, so fall back to Go
+	return "go"
+}
+
+// goTestArgs builds go test arguments that match exactly one test (and optional subtest)
+func goTestArgs(path, testName string) []string {
+	pkg := path
+	if strings.HasSuffix(pkg, ".go") {
+		pkg = filepath.Dir(pkg)
+	}
+	if !filepath.IsAbs(pkg) && !strings.HasPrefix(pkg, ".") && !strings.Contains(pkg, ".") {
+		// Relative directories need a ./ prefix or go treats them as import paths
+		if info, err := os.Stat(pkg); err == nil && info.IsDir() {
+			pkg = "./" + pkg
+		}
+	}
+
+	parts := strings.Split(testName, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+
+	return []string{"test", "-count=1", "-v", "-run", strings.Join(parts, "/"), pkg}
+}
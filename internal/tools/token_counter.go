@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// CountTokensInput defines the input parameters for the count_tokens tool
+type CountTokensInput struct {
+	Text           string `json:"text,omitempty" jsonschema_description:"Text to count. Provide either text or path."`
+	Path           string `json:"path,omitempty" jsonschema_description:"The relative path of a file to count. Provide either text or path."`
+	AllowSensitive bool   `json:"allow_sensitive,omitempty" jsonschema_description:"Set to true to count a file that looks like it holds secrets (.env, keys, credentials). Only do this when the user explicitly asks."`
+}
+
+// CountTokensDefinition provides the count_tokens tool definition
+var CountTokensDefinition = agent.ToolDefinition{
+	Name:        "count_tokens",
+	Description: "Count how many tokens a piece of text or a file would consume for the current model. Use it to decide whether to read a large file whole or page through it with read_file ranges and read_more.",
+	InputSchema: schema.GenerateSchema[CountTokensInput](),
+	Function:    CountTokens,
+	ReadOnly:    true,
+}
+
+// CountTokens reports the token count of text or a file's contents
+func CountTokens(ctx context.Context, input json.RawMessage) (string, error) {
+	var countTokensInput CountTokensInput
+	err := json.Unmarshal(input, &countTokensInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if (countTokensInput.Text == "") == (countTokensInput.Path == "") {
+		return "", fmt.Errorf("provide exactly one of text or path")
+	}
+
+	text, subject := countTokensInput.Text, "text"
+	if countTokensInput.Path != "" {
+		if err := checkSensitiveFile(countTokensInput.Path, countTokensInput.AllowSensitive); err != nil {
+			return "", err
+		}
+		content, err := os.ReadFile(countTokensInput.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		text, subject = string(content), countTokensInput.Path
+	}
+
+	tokens, err := agent.CountTokens(ctx, text)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: %d tokens (%d chars)", subject, tokens, len(text)), nil
+}
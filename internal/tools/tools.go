@@ -6,11 +6,38 @@ import "agent/internal/agent"
 func GetAllTools() []agent.ToolDefinition {
 	return []agent.ToolDefinition{
 		ReadFileDefinition,
+		ReadMoreDefinition,
 		ListFilesDefinition,
 		EditFileDefinition,
 		WriteFileDefinition,
 		SearchFileDefinition,
 		RunShellCommandDefinition,
 		GlobDefinition,
+		OpenInEditorDefinition,
+		WorkspaceInfoDefinition,
+		AskUserDefinition,
+		RenameSymbolDefinition,
+		ScopedEditDefinition,
+		CheckSyntaxDefinition,
+		SetVarDefinition,
+		GetVarDefinition,
+		ListVarsDefinition,
+		DiffDirsDefinition,
+		ChangedSymbolsDefinition,
+		ConfigGetDefinition,
+		RunTestDefinition,
+		CheckFormatDefinition,
+		UpdateSectionDefinition,
+		CountTokensDefinition,
+		DeleteFileDefinition,
+		UndoEditDefinition,
+		SearchProjectDefinition,
+		ApplyPatchDefinition,
+		GitStatusDefinition,
+		GitDiffDefinition,
+		FetchURLDefinition,
+		GitBlameDefinition,
+		ReadManyFilesDefinition,
+		FileStatsDefinition,
 	}
 }
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/schema"
+)
+
+// WorkspaceInfoInput defines the input parameters for the workspace_info tool
+type WorkspaceInfoInput struct{}
+
+// WorkspaceInfoOutput defines the output of the workspace_info tool
+type WorkspaceInfoOutput struct {
+	WorkingDirectory string `json:"working_directory"`
+	GitRoot          string `json:"git_root,omitempty"`
+	GitBranch        string `json:"git_branch,omitempty"`
+	DetachedHead     string `json:"detached_head,omitempty"`
+}
+
+// WorkspaceInfoDefinition provides the workspace_info tool definition
+var WorkspaceInfoDefinition = agent.ToolDefinition{
+	Name:        "workspace_info",
+	Description: "Return the current working directory, the git repository root (if any), and the current git branch. Call this at the start of a task to ground relative paths.",
+	InputSchema: schema.GenerateSchema[WorkspaceInfoInput](),
+	Function:    WorkspaceInfo,
+	ReadOnly:    true,
+}
+
+// WorkspaceInfo reports the working directory and git repository details
+func WorkspaceInfo(ctx context.Context, input json.RawMessage) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	output := WorkspaceInfoOutput{WorkingDirectory: cwd}
+
+	if root, gitDir, ok := findGitRoot(cwd); ok {
+		output.GitRoot = root
+		if head, err := os.ReadFile(filepath.Join(gitDir, "HEAD")); err == nil {
+			ref := strings.TrimSpace(string(head))
+			if strings.HasPrefix(ref, "ref: ") {
+				output.GitBranch = strings.TrimPrefix(strings.TrimPrefix(ref, "ref: "), "refs/heads/")
+			} else {
+				output.DetachedHead = ref
+			}
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal workspace info: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
+
+// findGitRoot walks up from dir looking for a .git directory or gitfile, returning
+// the repository root and the resolved git directory
+func findGitRoot(dir string) (string, string, bool) {
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			if info.IsDir() {
+				return dir, gitPath, true
+			}
+
+			// Worktrees and submodules use a file pointing at the real git directory
+			if data, err := os.ReadFile(gitPath); err == nil {
+				line := strings.TrimSpace(string(data))
+				if strings.HasPrefix(line, "gitdir: ") {
+					gitDir := strings.TrimPrefix(line, "gitdir: ")
+					if !filepath.IsAbs(gitDir) {
+						gitDir = filepath.Join(dir, gitDir)
+					}
+					return dir, gitDir, true
+				}
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,553 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent/internal/agent"
+	"agent/internal/config"
+	"agent/internal/models"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleSlashCommand dispatches input starting with "/" to the matching command
+func (m *model) handleSlashCommand(input string) tea.Cmd {
+	fields := strings.Fields(input)
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case "/context":
+		m.handleContextCommand(args)
+	case "/new":
+		m.startNewConversation()
+	case "/clear":
+		m.clearConversation()
+	case "/help":
+		m.addSystemMessage("Keybindings and commands:\n"+config.CommandHelp, false)
+	case "/reload":
+		m.reloadPreferences()
+	case "/settings":
+		m.openSettings()
+	case "/plan":
+		return m.startPlan(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	case "/apply":
+		return m.applyPlan(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	case "/ref":
+		m.handleRefCommand(args)
+	case "/image":
+		m.handleImageCommand(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	case "/tab":
+		return m.handleTabCommand(args)
+	case "/save":
+		m.saveSession(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	case "/load":
+		m.restoreSession(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	case "/export":
+		m.exportMarkdown(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	case "/", "/search":
+		return m.startSearch(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	case "/count":
+		return m.countTokens(strings.TrimSpace(strings.TrimPrefix(input, command)))
+	default:
+		m.addSystemMessage(fmt.Sprintf("Unknown command: %s", command), true)
+	}
+
+	return nil
+}
+
+// handleContextCommand manages files added to the conversation as context
+func (m *model) handleContextCommand(args []string) {
+	if len(args) == 0 {
+		m.addSystemMessage("Usage: /context add <path> | /context list | /context clear", true)
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			m.addSystemMessage("Usage: /context add <path>", true)
+			return
+		}
+		var added []string
+		for _, path := range args[1:] {
+			if err := m.config.agent.AddContextFile(path); err != nil {
+				m.addSystemMessage(fmt.Sprintf("📎 Failed to add context: %v", err), true)
+				continue
+			}
+			added = append(added, path)
+		}
+		if len(added) > 0 {
+			m.addSystemMessage(fmt.Sprintf("📎 Added to context: %s", strings.Join(added, ", ")), false)
+		}
+	case "list":
+		files := m.config.agent.ContextFiles()
+		if len(files) == 0 {
+			m.addSystemMessage("📎 No context files added", false)
+			return
+		}
+		m.addSystemMessage("📎 Context files:\n- "+strings.Join(files, "\n- "), false)
+	case "clear":
+		m.config.agent.ClearContextFiles()
+		m.addSystemMessage("📎 Context files cleared", false)
+	default:
+		m.addSystemMessage(fmt.Sprintf("Unknown /context subcommand: %s", args[0]), true)
+	}
+}
+
+// handleRefCommand queues a tool call's output to be quoted into the next prompt
+func (m *model) handleRefCommand(args []string) {
+	if len(args) > 0 && args[0] == "clear" {
+		m.ui.pendingToolRefs = nil
+		m.addSystemMessage("📌 Tool references cleared", false)
+		return
+	}
+
+	number := m.countToolMessages()
+	if len(args) > 0 {
+		n, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			m.addSystemMessage("Usage: /ref [n] | /ref clear", true)
+			return
+		}
+		number = n
+	}
+
+	toolMsg := m.findToolMessage(number)
+	if toolMsg == nil {
+		m.addSystemMessage(fmt.Sprintf("📌 No tool call #%d in this conversation", number), true)
+		return
+	}
+
+	m.ui.pendingToolRefs = append(m.ui.pendingToolRefs, number)
+	m.addSystemMessage(fmt.Sprintf("📌 Output of tool call #%d (%s) will be quoted in your next message", number, toolMessageName(*toolMsg)), false)
+}
+
+// handleImageCommand attaches an image file to the next prompt, for models that accept images
+func (m *model) handleImageCommand(path string) {
+	switch path {
+	case "":
+		m.addSystemMessage("Usage: /image <path> | /image clear", true)
+		return
+	case "clear":
+		m.ui.pendingImages = nil
+		m.addSystemMessage("🖼 Attached images cleared", false)
+		return
+	}
+
+	if !models.SupportsVision(m.config.agent.Model) {
+		m.addSystemMessage(fmt.Sprintf("🖼 %s doesn't accept images", m.config.agent.Model), true)
+		return
+	}
+	if _, err := agent.LoadImage(path); err != nil {
+		m.addSystemMessage(fmt.Sprintf("🖼 Failed to attach image: %v", err), true)
+		return
+	}
+
+	m.ui.pendingImages = append(m.ui.pendingImages, path)
+	m.addSystemMessage(fmt.Sprintf("🖼 %s will be attached to your next message", path), false)
+}
+
+// countToolMessages returns the number of tool calls shown in the conversation
+func (m *model) countToolMessages() int {
+	count := 0
+	for _, msg := range m.messages {
+		if msg.mType == toolMessage {
+			count++
+		}
+	}
+	return count
+}
+
+// findToolMessage returns the tool message with the given /ref number, if any
+func (m *model) findToolMessage(number int) *message {
+	for i := range m.messages {
+		if m.messages[i].mType == toolMessage && m.messages[i].toolNumber == number {
+			return &m.messages[i]
+		}
+	}
+	return nil
+}
+
+// toolMessageName extracts the tool name from a tool message's first line
+func toolMessageName(msg message) string {
+	firstLine := strings.SplitN(msg.content, "\n", 2)[0]
+	return strings.TrimPrefix(firstLine, "🔧 Tool Call: ")
+}
+
+// quoteToolMessage formats a tool call and its result for inclusion in a prompt
+func quoteToolMessage(msg message) string {
+	arguments, result := parseToolContent(msg.content)
+	fence := "```"
+	for strings.Contains(result, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("Tool call #%d: %s\nArguments: %s\nOutput:\n%s\n%s\n%s", msg.toolNumber, toolMessageName(msg), arguments, fence, result, fence)
+}
+
+// countTokens counts a draft's tokens for the current model off the UI thread
+func (m *model) countTokens(draft string) tea.Cmd {
+	if draft == "" {
+		m.addSystemMessage("Usage: /count <text>", true)
+		return nil
+	}
+
+	currentAgent := m.config.agent
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		tokens, err := currentAgent.CountTextTokens(ctx, draft)
+		return tokenCountMsg{tokens: tokens, chars: len(draft), err: err}
+	}
+}
+
+// handleTokenCount reports the result of /count
+func (m *model) handleTokenCount(msg tokenCountMsg) {
+	if msg.err != nil {
+		m.addSystemMessage(fmt.Sprintf("🪙 Failed to count tokens: %v", msg.err), true)
+		return
+	}
+	m.addSystemMessage(fmt.Sprintf("🪙 %d tokens (%d chars) for %s", msg.tokens, msg.chars, m.config.agent.Model), false)
+}
+
+// startNewConversation begins a fresh conversation while keeping the current
+// model, thinking mode, and confirmation settings
+func (m *model) startNewConversation() {
+	saved := m.autoSaveSession()
+	m.config.agent.ClearConversation()
+	m.messages = []message{}
+	m.stream.streamingMsg = nil
+	m.stream.streamingMsgIndex = -1
+	m.stream.thoughtMsgIndex = -1
+	m.stream.streamingWasInterrupted = false
+	m.ui.pendingToolRefs = nil
+	m.ui.pendingImages = nil
+
+	note := fmt.Sprintf("✨ Started a new conversation with %s", m.config.agent.Model)
+	if saved {
+		note += fmt.Sprintf(" (previous conversation saved to %s)", config.DefaultSessionFile)
+	}
+	m.addSystemMessage(note, false)
+}
+
+// clearConversation wipes the conversation, transcript and session state, returning
+// to the welcome screen. Unlike /new it never auto-saves and also leaves plan mode.
+func (m *model) clearConversation() {
+	if m.ui.showSpinner {
+		m.addSystemMessage("Can't clear the conversation while the agent is responding", true)
+		return
+	}
+
+	m.config.agent.ClearConversation()
+	m.config.agent.SetPlanMode(false)
+	m.messages = []message{}
+	m.stream.streamingMsg = nil
+	m.stream.streamingMsgIndex = -1
+	m.stream.thoughtMsgIndex = -1
+	m.stream.streamingWasInterrupted = false
+	m.ui.pendingToolRefs = nil
+	m.ui.pendingImages = nil
+	m.ui.viewport.SetContent(m.renderConversation())
+	m.ui.viewport.GotoTop()
+}
+
+// copyLastResponse copies the agent's most recent reply to the system clipboard
+func (m *model) copyLastResponse() tea.Cmd {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		msg := m.messages[i]
+		if msg.mType != agentMessage || msg.isSystem || msg.isError || strings.TrimSpace(msg.content) == "" {
+			continue
+		}
+		if err := clipboard.WriteAll(msg.content); err != nil {
+			m.addSystemMessage(fmt.Sprintf("📋 Couldn't copy to the clipboard: %v", err), true)
+			return nil
+		}
+		return m.flashStatus(fmt.Sprintf("📋 Copied %d chars to clipboard", len(msg.content)))
+	}
+	return m.flashStatus("📋 No response to copy yet")
+}
+
+// regenerateResponse discards the agent's last response and sends the message that
+// prompted it again, e.g. after switching models
+func (m *model) regenerateResponse() tea.Cmd {
+	if m.ui.showSpinner || m.ui.askUserMode {
+		m.addSystemMessage("🔁 Wait for the current response to finish (or press Esc) before regenerating", true)
+		return nil
+	}
+	prompt, images, ok := m.config.agent.PopLastTurn()
+	if !ok {
+		return m.flashStatus("🔁 No response to regenerate yet")
+	}
+
+	// Keep the user's message on screen and drop everything shown after it
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].mType == userMessage && !m.messages[i].isAnswer {
+			m.messages = m.messages[:i+1]
+			break
+		}
+	}
+	m.stream.streamingMsg = nil
+	m.stream.streamingMsgIndex = -1
+	m.stream.thoughtMsgIndex = -1
+	m.stream.streamingWasInterrupted = false
+	m.ui.viewport.SetContent(m.renderConversation())
+	m.ui.viewport.GotoBottom()
+	m.ui.showSpinner = true
+	m.ui.textarea.Blur()
+
+	return tea.Batch(m.ui.spinner.Tick, m.streamingCommand(prompt, images))
+}
+
+// flashStatus shows a notice in the status bar for a few seconds, or as a message
+// when the status bar is hidden
+func (m *model) flashStatus(note string) tea.Cmd {
+	if !m.ui.showStatusBar {
+		m.addSystemMessage(note, false)
+		return nil
+	}
+	m.ui.statusNoteID++
+	m.ui.statusNote = note
+	id := m.ui.statusNoteID
+	return tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+		return clearStatusNoteMsg(id)
+	})
+}
+
+// saveSession writes the active conversation to path, or to the default session file
+func (m *model) saveSession(path string) {
+	if path == "" {
+		path = config.DefaultSessionFile
+	}
+	if err := m.config.agent.SaveSession(path); err != nil {
+		m.addSystemMessage(fmt.Sprintf("💾 Failed to save session: %v", err), true)
+		return
+	}
+	m.addSystemMessage(fmt.Sprintf("💾 Saved session to %s", path), false)
+}
+
+// restoreSession replaces the active conversation with one saved at path, or at the
+// default session file, and rebuilds the transcript from it
+func (m *model) restoreSession(path string) {
+	if m.ui.showSpinner {
+		m.addSystemMessage("Can't load a session while the agent is responding", true)
+		return
+	}
+	if path == "" {
+		path = config.DefaultSessionFile
+	}
+	if err := m.config.agent.LoadSession(path); err != nil {
+		m.addSystemMessage(fmt.Sprintf("📂 Failed to load session: %v", err), true)
+		return
+	}
+
+	m.messages = m.transcriptMessages()
+	m.stream.streamingMsg = nil
+	m.stream.streamingMsgIndex = -1
+	m.stream.thoughtMsgIndex = -1
+	m.stream.streamingWasInterrupted = false
+	m.ui.pendingToolRefs = nil
+	m.ui.pendingImages = nil
+	m.addSystemMessage(fmt.Sprintf("📂 Loaded session from %s (%d messages)", path, len(m.config.agent.Conversation)), false)
+}
+
+// exportMarkdown writes the active conversation as Markdown to path, or to
+// session-<timestamp>.md in the working directory
+func (m *model) exportMarkdown(path string) {
+	if len(m.config.agent.Conversation) == 0 {
+		m.addSystemMessage("Nothing to export yet", true)
+		return
+	}
+	if path == "" {
+		path = fmt.Sprintf("session-%s.md", time.Now().Format("20060102-150405"))
+	}
+	if err := os.WriteFile(path, []byte(m.config.agent.ExportMarkdown()), 0600); err != nil {
+		m.addSystemMessage(fmt.Sprintf("📝 Failed to export conversation: %v", err), true)
+		return
+	}
+	m.addSystemMessage(fmt.Sprintf("📝 Exported conversation to %s", path), false)
+}
+
+// autoSaveSession saves a non-empty conversation to the default session file when the
+// user has opted in, reporting whether it did
+func (m *model) autoSaveSession() bool {
+	prefs, err := config.LoadPreferences()
+	if err != nil || prefs == nil || !prefs.AutoSaveSession || len(m.config.agent.Conversation) == 0 {
+		return false
+	}
+	return m.config.agent.SaveSession(config.DefaultSessionFile) == nil
+}
+
+// transcriptMessages converts the agent's rebuilt transcript into display messages
+func (m *model) transcriptMessages() []message {
+	var messages []message
+	toolNumber := 0
+	for _, msg := range m.config.agent.Transcript() {
+		switch msg.Type {
+		case agent.UserMessage:
+			messages = append(messages, message{mType: userMessage, content: msg.Content})
+		case agent.AgentMessage:
+			messages = append(messages, message{mType: agentMessage, content: msg.Content})
+		case agent.ToolMessage:
+			toolNumber++
+			messages = append(messages, message{
+				mType:       toolMessage,
+				content:     msg.Content,
+				isCollapsed: !m.config.expandToolMessages,
+				isError:     msg.IsError,
+				toolNumber:  toolNumber,
+				summary:     summarizeToolCall(msg),
+			})
+		}
+	}
+	return messages
+}
+
+// startPlan switches the agent to read-only plan mode and optionally sends a request to plan
+func (m *model) startPlan(request string) tea.Cmd {
+	m.config.agent.SetPlanMode(true)
+	if request == "" {
+		m.addSystemMessage("📋 Plan mode enabled: the agent will only read files and reply with a plan. Use /apply to proceed.", false)
+		return nil
+	}
+	return m.sendMessage(request)
+}
+
+// applyPlan leaves plan mode and tells the agent to carry out its plan
+func (m *model) applyPlan(extra string) tea.Cmd {
+	if !m.config.agent.PlanMode() {
+		m.addSystemMessage("Not in plan mode. Use /plan <request> first.", true)
+		return nil
+	}
+
+	m.config.agent.SetPlanMode(false)
+	instruction := "Proceed with the plan above."
+	if extra != "" {
+		instruction += " " + extra
+	}
+	return m.sendMessage(instruction)
+}
+
+// reloadPreferences re-reads the preferences file and applies any changed settings
+func (m *model) reloadPreferences() {
+	prefs, err := config.LoadPreferences()
+	if err != nil {
+		m.addSystemMessage(fmt.Sprintf("🔄 Failed to reload preferences: %v", err), true)
+		return
+	}
+
+	var changes []string
+	if prefs.RequireToolConfirmation != m.config.requireToolConfirmation {
+		m.config.requireToolConfirmation = prefs.RequireToolConfirmation
+		changes = append(changes, fmt.Sprintf("tool confirmation: %s", onOff(prefs.RequireToolConfirmation)))
+	}
+	if !maps.Equal(prefs.ToolConfirmationPolicy, m.config.toolPolicy) {
+		m.config.toolPolicy = prefs.ToolConfirmationPolicy
+		changes = append(changes, fmt.Sprintf("tool confirmation policy: %d tool(s)", len(prefs.ToolConfirmationPolicy)))
+	}
+	if prefs.EnableThinkingMode != m.config.enableThinkingMode {
+		m.config.enableThinkingMode = prefs.EnableThinkingMode
+		changes = append(changes, fmt.Sprintf("thinking mode: %s", onOff(prefs.EnableThinkingMode)))
+	}
+	if prefs.CompactMode != m.config.compactMode {
+		m.config.compactMode = prefs.CompactMode
+		applyCompactMode(prefs.CompactMode)
+		changes = append(changes, fmt.Sprintf("compact mode: %s", onOff(prefs.CompactMode)))
+	}
+	if theme := findTheme(prefs.SelectedTheme); theme.Name != activeTheme.Name {
+		applyTheme(theme)
+		m.ui.spinner.Style = spinnerStyle
+		changes = append(changes, fmt.Sprintf("theme: %s", theme.Name))
+	}
+	if !prefs.HideStatusBar != m.ui.showStatusBar {
+		m.ui.showStatusBar = !prefs.HideStatusBar
+		changes = append(changes, fmt.Sprintf("status bar: %s", onOff(m.ui.showStatusBar)))
+	}
+	if prefs.AllowOutsideReads != m.config.allowOutsideReads {
+		m.config.allowOutsideReads = prefs.AllowOutsideReads
+		changes = append(changes, fmt.Sprintf("reads outside workspace without asking: %s", onOff(prefs.AllowOutsideReads)))
+	}
+	if prefs.ExpandThoughts != m.config.expandThoughts {
+		m.config.expandThoughts = prefs.ExpandThoughts
+		changes = append(changes, fmt.Sprintf("expand new thoughts: %s", onOff(prefs.ExpandThoughts)))
+	}
+	if prefs.ExpandToolMessages != m.config.expandToolMessages {
+		m.config.expandToolMessages = prefs.ExpandToolMessages
+		changes = append(changes, fmt.Sprintf("expand new tool messages: %s", onOff(prefs.ExpandToolMessages)))
+	}
+	if prefs.MaxStreamedMessageChars != m.config.maxStreamedMessageChars {
+		m.config.maxStreamedMessageChars = prefs.MaxStreamedMessageChars
+		changes = append(changes, fmt.Sprintf("split streamed messages after: %d chars", prefs.MaxStreamedMessageChars))
+	}
+	if warning, critical := prefs.GetTokenThresholds(); warning != m.config.tokenWarningPercent || critical != m.config.tokenCriticalPercent {
+		m.config.tokenWarningPercent, m.config.tokenCriticalPercent = warning, critical
+		changes = append(changes, fmt.Sprintf("token warnings: %d%% / %d%% of context", warning, critical))
+	}
+	if prefs.PostEditCommand != m.config.agent.PostEditCommand() {
+		m.config.agent.SetPostEditCommand(prefs.PostEditCommand)
+		changes = append(changes, fmt.Sprintf("post-edit check: %s", orOff(prefs.PostEditCommand)))
+	}
+	if prompt, path, err := config.LoadSystemPromptOverride(prefs); err != nil {
+		changes = append(changes, fmt.Sprintf("system prompt: %v", err))
+	} else if prompt != m.config.agent.SystemPrompt() && (prompt != "" || m.config.agent.SystemPrompt() != config.SystemPrompt) {
+		m.config.agent.SetSystemPromptOverride(prompt)
+		source := "built-in"
+		if path != "" {
+			source = path
+		}
+		changes = append(changes, fmt.Sprintf("system prompt: %s (%d chars)", source, len(m.config.agent.SystemPrompt())))
+	}
+	if contextLines := prefs.GetDiffContextLines(); contextLines != m.config.diffContextLines {
+		m.config.diffContextLines = contextLines
+		changes = append(changes, fmt.Sprintf("diff context lines: %d", contextLines))
+	}
+	if prefs.SelectedModel != "" && prefs.SelectedModel != m.config.agent.Model {
+		m.config.agent.SwitchModel(prefs.SelectedModel)
+		for i, name := range m.config.availableModels {
+			if name == prefs.SelectedModel {
+				m.ui.selectedModelIndex = i
+			}
+		}
+		changes = append(changes, fmt.Sprintf("model: %s", prefs.SelectedModel))
+	}
+
+	m.handleWindowResize(tea.WindowSizeMsg{Width: m.ui.width, Height: m.ui.height})
+	if len(changes) == 0 {
+		m.addSystemMessage("🔄 Preferences reloaded: no changes", false)
+		return
+	}
+	m.addSystemMessage("🔄 Preferences reloaded:\n- "+strings.Join(changes, "\n- "), false)
+}
+
+// orOff formats an optional command setting for feedback messages
+func orOff(command string) string {
+	if command == "" {
+		return "off"
+	}
+	return "`" + command + "`"
+}
+
+// onOff formats a boolean setting for feedback messages
+func onOff(enabled bool) string {
+	if enabled {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// addSystemMessage shows a feedback message in the conversation view
+func (m *model) addSystemMessage(content string, isError bool) {
+	m.messages = append(m.messages, message{
+		mType:    agentMessage,
+		content:  content,
+		isError:  isError,
+		isSystem: true,
+	})
+	m.ui.viewport.SetContent(m.renderConversation())
+	m.ui.viewport.GotoBottom()
+}
@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// submit types input into the textarea and sends it as if Enter was pressed
+func submit(m *model, input string) {
+	m.ui.textarea.SetValue(input)
+	m.handleUserInput()
+}
+
+func TestSlashCommandParsing(t *testing.T) {
+	m := newTestModel(t)
+	m.messages = append(m.messages, message{mType: userMessage, content: "hello"})
+	m.config.agent.Conversation = append(m.config.agent.Conversation, genai.NewContentFromText("hello", genai.RoleUser))
+
+	// Arguments after a command are ignored rather than making it unknown
+	submit(m, "/clear   now")
+	if len(m.messages) != 0 || len(m.config.agent.Conversation) != 0 {
+		t.Fatalf("/clear left %d messages and %d contents", len(m.messages), len(m.config.agent.Conversation))
+	}
+
+	submit(m, "/help")
+	if len(m.messages) != 1 || m.messages[0].mType != agentMessage || !strings.Contains(m.messages[0].content, "Keybindings and commands") {
+		t.Fatalf("/help messages = %+v, want the keybinding help", m.messages)
+	}
+
+	submit(m, "/clearall")
+	last := m.messages[len(m.messages)-1]
+	if !last.isError || last.content != "Unknown command: /clearall" {
+		t.Errorf("/clearall = %+v, want an unknown command error", last)
+	}
+
+	// Anything else goes to the agent, even text with a slash later on
+	before := len(m.messages)
+	m.ui.textarea.SetValue("what does a/b mean?")
+	if cmd := m.handleUserInput(); cmd == nil {
+		t.Error("a normal message started no command")
+	}
+	if len(m.messages) != before+1 || m.messages[before].mType != userMessage || m.messages[before].content != "what does a/b mean?" {
+		t.Errorf("a normal message was not shown as a user message: %+v", m.messages[before:])
+	}
+}
@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxCompletions is how many commands the completion popup shows at once
+const maxCompletions = 8
+
+// slashCommand describes a command handleSlashCommand understands, for completion
+type slashCommand struct {
+	name        string
+	alias       string // another name that runs the command
+	args        string // argument synopsis; commands without one run as soon as they're picked
+	description string
+}
+
+// slashCommands lists the slash commands, in the order completion offers them
+var slashCommands = []slashCommand{
+	{name: "/help", description: "Show keybindings and commands"},
+	{name: "/new", description: "Start a new conversation with the same settings"},
+	{name: "/clear", description: "Clear the conversation, transcript and session state"},
+	{name: "/context", args: "add <path> | list | clear", description: "Manage files added as context"},
+	{name: "/plan", args: "<request>", description: "Draft a plan without editing"},
+	{name: "/apply", args: "[notes]", description: "Carry out the plan"},
+	{name: "/ref", args: "[n]", description: "Quote a tool call into your next message"},
+	{name: "/image", args: "<path> | clear", description: "Attach an image to your next message"},
+	{name: "/search", alias: "/", args: "[query]", description: "Search the conversation"},
+	{name: "/count", args: "<text>", description: "Count tokens"},
+	{name: "/save", args: "[path]", description: "Save the conversation"},
+	{name: "/load", args: "[path]", description: "Resume a saved conversation"},
+	{name: "/export", args: "[path]", description: "Export the conversation to Markdown"},
+	{name: "/tab", args: "new | close | <n>", description: "Manage conversation tabs"},
+	{name: "/settings", description: "Adjust generation settings"},
+	{name: "/reload", description: "Reload preferences"},
+}
+
+// matchCommands returns the commands whose name starts with prefix, in registry order
+func matchCommands(prefix string) []slashCommand {
+	var matches []slashCommand
+	for _, command := range slashCommands {
+		if strings.HasPrefix(command.name, prefix) {
+			matches = append(matches, command)
+		}
+	}
+	return matches
+}
+
+// commonPrefix returns the longest prefix shared by the commands' names
+func commonPrefix(commands []slashCommand) string {
+	if len(commands) == 0 {
+		return ""
+	}
+	prefix := commands[0].name
+	for _, command := range commands[1:] {
+		for !strings.HasPrefix(command.name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// isCommandName reports whether text names a command exactly, or by its alias
+func isCommandName(text string) bool {
+	for _, command := range slashCommands {
+		if text == command.name || (command.alias != "" && text == command.alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// completions returns the commands matching the input while a command name is being
+// typed, i.e. the input is a "/" word with nothing after it
+func (m *model) completions() []slashCommand {
+	if m.ui.completionDismissed || !m.ui.textarea.Focused() || m.ui.showSpinner || m.ui.askUserMode || m.searchActive() {
+		return nil
+	}
+	input := m.ui.textarea.Value()
+	if !strings.HasPrefix(input, "/") || strings.ContainsAny(input, " \t\n") {
+		return nil
+	}
+	return matchCommands(input)
+}
+
+// handleCompletionKey drives the completion popup: Up/Down pick a command, Tab
+// completes the common prefix of the matches, Enter accepts the picked command and
+// Esc hides the popup. It reports whether it used the key.
+func (m *model) handleCompletionKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	matches := m.completions()
+	if len(matches) == 0 {
+		return nil, false
+	}
+	m.ui.completionIndex = min(m.ui.completionIndex, len(matches)-1)
+
+	switch msg.Type {
+	case tea.KeyUp:
+		m.ui.completionIndex = (m.ui.completionIndex - 1 + len(matches)) % len(matches)
+	case tea.KeyDown:
+		m.ui.completionIndex = (m.ui.completionIndex + 1) % len(matches)
+	case tea.KeyTab:
+		prefix := commonPrefix(matches)
+		if len(matches) == 1 {
+			prefix = matches[0].name + " "
+		}
+		m.ui.textarea.SetValue(prefix)
+		m.ui.completionIndex = 0
+	case tea.KeyEsc:
+		m.ui.completionDismissed = true
+	case tea.KeyEnter:
+		// A complete command name runs as typed, so "/" alone still opens search
+		if isCommandName(m.ui.textarea.Value()) {
+			return nil, false
+		}
+		command := matches[m.ui.completionIndex]
+		m.ui.completionIndex = 0
+		if command.args == "" {
+			m.ui.textarea.SetValue(command.name)
+			return m.handleUserInput(), true
+		}
+		m.ui.textarea.SetValue(command.name + " ")
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// renderCompletions draws the completion popup over the bottom of the conversation,
+// keeping the layout's height unchanged
+func (m *model) renderCompletions(conversation string) string {
+	matches := m.completions()
+	if len(matches) == 0 {
+		return conversation
+	}
+	selected := min(m.ui.completionIndex, len(matches)-1)
+
+	// Scroll the list to keep the selected command visible
+	first := max(0, min(selected-maxCompletions/2, len(matches)-maxCompletions))
+	shown := matches[first:min(first+maxCompletions, len(matches))]
+
+	nameWidth := 0
+	for _, command := range shown {
+		nameWidth = max(nameWidth, lipgloss.Width(command.name+" "+command.args))
+	}
+	var items []string
+	for i, command := range shown {
+		item := fmt.Sprintf(" %-*s  %s ", nameWidth, strings.TrimSpace(command.name+" "+command.args), command.description)
+		item = truncateSummary(item, max(m.ui.width-6, 10))
+		if first+i == selected {
+			item = completionSelectedStyle.Render(item)
+		} else {
+			item = lipgloss.NewStyle().Foreground(textPrimary).Render(item)
+		}
+		items = append(items, item)
+	}
+	hint := lipgloss.NewStyle().Foreground(textMuted).Render(" ↑↓ Select • Tab Complete • Enter Accept • Esc Hide")
+	popup := strings.Split(completionStyle.Render(strings.Join(append(items, hint), "\n")), "\n")
+
+	lines := strings.Split(conversation, "\n")
+	if len(popup) > len(lines) {
+		return conversation
+	}
+	copy(lines[len(lines)-len(popup):], popup)
+	return strings.Join(lines, "\n")
+}
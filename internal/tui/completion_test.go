@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandNames returns the names of commands, in order
+func commandNames(commands []slashCommand) []string {
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.name)
+	}
+	return names
+}
+
+func TestMatchCommandsByPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []string
+		common string
+	}{
+		{"/c", []string{"/clear", "/context", "/count"}, "/c"},
+		{"/co", []string{"/context", "/count"}, "/co"},
+		{"/se", []string{"/search", "/settings"}, "/se"},
+		{"/sa", []string{"/save"}, "/save"},
+		{"/re", []string{"/ref", "/reload"}, "/re"},
+		{"/x", nil, ""},
+	}
+	for _, tt := range tests {
+		matches := matchCommands(tt.prefix)
+		if got := commandNames(matches); !slices.Equal(got, tt.want) {
+			t.Errorf("matchCommands(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+		if got := commonPrefix(matches); got != tt.common {
+			t.Errorf("commonPrefix(%q matches) = %q, want %q", tt.prefix, got, tt.common)
+		}
+	}
+
+	if got := len(matchCommands("/")); got != len(slashCommands) {
+		t.Errorf("\"/\" matched %d commands, want all %d", got, len(slashCommands))
+	}
+}
+
+func TestTabCompletesCommands(t *testing.T) {
+	m := newTestModel(t)
+
+	m.ui.textarea.SetValue("/exp")
+	if _, used := m.handleCompletionKey(tea.KeyMsg{Type: tea.KeyTab}); !used || m.ui.textarea.Value() != "/export " {
+		t.Errorf("Tab on a unique prefix = %q, want the full command and a space", m.ui.textarea.Value())
+	}
+
+	m.ui.textarea.SetValue("/l")
+	m.handleCompletionKey(tea.KeyMsg{Type: tea.KeyTab})
+	if m.ui.textarea.Value() != "/load " {
+		t.Errorf("Tab on /l = %q, want /load", m.ui.textarea.Value())
+	}
+
+	// Once arguments are being typed, completion stays out of the way
+	m.ui.textarea.SetValue("/export notes.md")
+	if _, used := m.handleCompletionKey(tea.KeyMsg{Type: tea.KeyTab}); used {
+		t.Error("completion took Tab while typing arguments")
+	}
+	m.ui.textarea.SetValue("no slash")
+	if matches := m.completions(); matches != nil {
+		t.Errorf("completions without a slash = %v", commandNames(matches))
+	}
+}
@@ -1,20 +1,18 @@
 package tui
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
 	"strings"
-)
 
-// formatToolContent converts raw tool call content into structured markdown
-func formatToolContent(content string) string {
-	lines := strings.Split(content, "\n")
-	if len(lines) < 3 {
-		return content
-	}
+	"agent/internal/agent"
+)
 
-	var arguments, result string
+// parseToolContent extracts the arguments and result sections from raw tool call content
+func parseToolContent(content string) (arguments, result string) {
 	var inResult bool
-
-	for i, line := range lines {
+	for i, line := range strings.Split(content, "\n") {
 		if strings.HasPrefix(line, "Arguments:") {
 			arguments = strings.TrimPrefix(line, "Arguments: ")
 		} else if strings.HasPrefix(line, "Result:") {
@@ -24,16 +22,50 @@ func formatToolContent(content string) string {
 			result += "\n" + line
 		}
 	}
+	return arguments, result
+}
 
-	// Build markdown
+// formatToolArguments renders the arguments section of a tool call as markdown
+func formatToolArguments(arguments string) string {
 	var formatted strings.Builder
 	formatted.WriteString("**Arguments:**\n")
-	
+
 	if arguments != "" && arguments != "{}" {
 		formatted.WriteString("```json\n" + arguments + "\n```\n")
 	} else {
 		formatted.WriteString("`None`\n")
 	}
+	return formatted.String()
+}
+
+// isUnifiedDiff reports whether text looks like a unified diff (---/+++ headers and @@ hunks)
+func isUnifiedDiff(text string) bool {
+	var hasOld, hasNew, hasHunk bool
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			hasOld = true
+		case strings.HasPrefix(line, "+++ "):
+			hasNew = true
+		case strings.HasPrefix(line, "@@"):
+			hasHunk = true
+		}
+	}
+	return hasOld && hasNew && hasHunk
+}
+
+// formatToolContent converts raw tool call content into structured markdown
+func formatToolContent(content string) string {
+	lines := strings.Split(content, "\n")
+	if len(lines) < 3 {
+		return content
+	}
+
+	arguments, result := parseToolContent(content)
+
+	// Build markdown
+	var formatted strings.Builder
+	formatted.WriteString(formatToolArguments(arguments))
 
 	formatted.WriteString("\n**Result:**\n")
 	if result != "" {
@@ -54,3 +86,172 @@ func formatToolContent(content string) string {
 
 	return formatted.String()
 }
+
+// hasUnclosedCodeFence reports whether content has an odd number of ``` fences,
+// which happens while the model is midway through emitting a code block
+func hasUnclosedCodeFence(content string) bool {
+	return strings.Count(content, "```")%2 == 1
+}
+
+// summarySubjectKeys are the argument names that best identify what a tool call acted on
+var summarySubjectKeys = []string{"path", "file", "package_dir", "directory", "pattern", "command", "query", "name", "test_name"}
+
+// replacementCount matches the replacement tally reported by the edit tools
+var replacementCount = regexp.MustCompile(`Made (\d+) replacement\(s\)`)
+
+// summarizeToolCall builds a one-line "tool subject → outcome" summary for a collapsed tool header
+func summarizeToolCall(msg agent.Message) string {
+	if msg.ToolName == "" {
+		return ""
+	}
+
+	summary := msg.ToolName
+	for _, key := range summarySubjectKeys {
+		if value, ok := msg.ToolArgs[key].(string); ok && value != "" {
+			summary += " " + truncateSummary(firstLine(value), 40)
+			break
+		}
+	}
+
+	return summary + " → " + summarizeToolResult(msg)
+}
+
+// summarizeToolResult condenses a tool result into a few words
+func summarizeToolResult(msg agent.Message) string {
+	if strings.HasPrefix(msg.Content, "🚫") {
+		return "denied"
+	}
+	if msg.IsError {
+		return truncateSummary(strings.TrimPrefix(firstLine(msg.ToolResult), "Error: "), 50)
+	}
+
+	result := strings.TrimSpace(msg.ToolResult)
+	switch msg.ToolName {
+	case "read_file", "read_more":
+		return fmt.Sprintf("%d lines", strings.Count(result, "\n")+1)
+	case "run_shell_command":
+		var output struct {
+			ExitCode int `json:"exit_code"`
+		}
+		if json.Unmarshal([]byte(result), &output) == nil {
+			return fmt.Sprintf("exit %d", output.ExitCode)
+		}
+	case "read_many_files":
+		var files map[string]struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal([]byte(result), &files) == nil {
+			failed := 0
+			for _, file := range files {
+				if file.Error != "" {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Sprintf("%d files, %d failed", len(files), failed)
+			}
+			return fmt.Sprintf("%d files", len(files))
+		}
+	case "file_stats":
+		var stats struct {
+			Lines int `json:"lines"`
+			Files int `json:"files"`
+		}
+		if json.Unmarshal([]byte(result), &stats) == nil {
+			if stats.Files > 0 {
+				return fmt.Sprintf("%d files, %d lines", stats.Files, stats.Lines)
+			}
+			return fmt.Sprintf("%d lines", stats.Lines)
+		}
+	case "run_test":
+		// The first line echoes the command and the second is the outcome
+		if lines := strings.SplitN(result, "\n", 3); len(lines) > 1 {
+			return lines[1]
+		}
+	}
+
+	if match := replacementCount.FindStringSubmatch(result); match != nil {
+		return match[1] + " replacement(s)"
+	}
+	if strings.HasPrefix(result, "No occurrences") {
+		return "no matches"
+	}
+	var items []json.RawMessage
+	if json.Unmarshal([]byte(result), &items) == nil {
+		return fmt.Sprintf("%d items", len(items))
+	}
+	if result == "" {
+		return "no output"
+	}
+	return truncateSummary(firstLine(result), 50)
+}
+
+// maxArgPreviewRunes is how much of a long argument value the confirmation overlay shows
+const maxArgPreviewRunes = 500
+
+// truncateArgs returns a copy of a tool argument value with strings longer than
+// limit runes cut short, noting how many runes were left out
+func truncateArgs(value interface{}, limit int) interface{} {
+	switch value := value.(type) {
+	case string:
+		runes := []rune(value)
+		if len(runes) <= limit {
+			return value
+		}
+		return fmt.Sprintf("%s… (%d more)", string(runes[:limit]), len(runes)-limit)
+	case map[string]interface{}:
+		truncated := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			truncated[key] = truncateArgs(item, limit)
+		}
+		return truncated
+	case []interface{}:
+		truncated := make([]interface{}, len(value))
+		for i, item := range value {
+			truncated[i] = truncateArgs(item, limit)
+		}
+		return truncated
+	}
+	return value
+}
+
+// wrapLines hard-wraps each line of text to width runes, indenting continuations
+// two spaces past the line's own indentation
+func wrapLines(text string, width int) []string {
+	var wrapped []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		prefix := ""
+		for len(prefix)+len(runes) > width {
+			wrapped = append(wrapped, prefix+string(runes[:width-len(prefix)]))
+			runes = runes[width-len(prefix):]
+			// Continuations that can't fit any text past the indent aren't indented
+			if prefix = strings.Repeat(" ", indent+2); len(prefix) >= width {
+				prefix = ""
+			}
+		}
+		wrapped = append(wrapped, prefix+string(runes))
+	}
+	return wrapped
+}
+
+// firstLine returns text up to the first newline
+func firstLine(text string) string {
+	return strings.SplitN(text, "\n", 2)[0]
+}
+
+// lastLine returns the last non-blank line of text, trimmed
+func lastLine(text string) string {
+	text = strings.TrimRight(text, " \t\n")
+	return strings.TrimSpace(text[strings.LastIndex(text, "\n")+1:])
+}
+
+// truncateSummary shortens text to at most limit runes, marking the cut with an ellipsis
+func truncateSummary(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateArgsShortensLargeContent(t *testing.T) {
+	content := strings.Repeat("é", maxArgPreviewRunes) + strings.Repeat("x", 9500)
+	args := map[string]interface{}{
+		"path":    "internal/big.go",
+		"content": content,
+		"edits":   []interface{}{map[string]interface{}{"new_text": content}},
+		"dry_run": true,
+	}
+
+	truncated := truncateArgs(args, maxArgPreviewRunes).(map[string]interface{})
+
+	want := strings.Repeat("é", maxArgPreviewRunes) + "… (9500 more)"
+	if got := truncated["content"]; got != want {
+		t.Errorf("content = %.40q…, want its first %d runes and a count of the rest", got, maxArgPreviewRunes)
+	}
+	nested := truncated["edits"].([]interface{})[0].(map[string]interface{})
+	if got := nested["new_text"]; got != want {
+		t.Error("a long string nested in a list wasn't truncated")
+	}
+	if truncated["path"] != "internal/big.go" || truncated["dry_run"] != true {
+		t.Errorf("short values changed: %v, %v", truncated["path"], truncated["dry_run"])
+	}
+	// The original arguments, which are what runs, are left whole
+	if args["content"] != content {
+		t.Error("truncateArgs modified its input")
+	}
+}
+
+func TestWrapLinesFitsWidth(t *testing.T) {
+	lines := wrapLines(`  "content": "`+strings.Repeat("a", 30)+`"`, 16)
+	want := []string{
+		`  "content": "aa`,
+		`    aaaaaaaaaaaa`,
+		`    aaaaaaaaaaaa`,
+		`    aaaa"`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrapLines =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
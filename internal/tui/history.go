@@ -0,0 +1,87 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxInputHistory caps how many submitted inputs are remembered
+const maxInputHistory = 200
+
+// inputHistory holds submitted inputs for recall with Up and Down, oldest first.
+// index is the entry being shown, or len(entries) when not browsing, in which case
+// draft holds the unsent input to return to.
+type inputHistory struct {
+	entries []string
+	index   int
+	draft   string
+}
+
+// add records a submitted input, skipping a repeat of the latest, and stops browsing
+func (h *inputHistory) add(input string) {
+	if len(h.entries) == 0 || h.entries[len(h.entries)-1] != input {
+		h.entries = append(h.entries, input)
+		if len(h.entries) > maxInputHistory {
+			h.entries = h.entries[len(h.entries)-maxInputHistory:]
+		}
+	}
+	h.index = len(h.entries)
+	h.draft = ""
+}
+
+// previous steps back to an older input, saving current as the draft when browsing
+// starts. It reports false at the oldest entry.
+func (h *inputHistory) previous(current string) (string, bool) {
+	if h.index == 0 || len(h.entries) == 0 {
+		return "", false
+	}
+	if h.index >= len(h.entries) {
+		h.index = len(h.entries)
+		h.draft = current
+	}
+	h.index--
+	return h.entries[h.index], true
+}
+
+// next steps forward to a newer input, or back to the draft after the newest. It
+// reports false when not browsing.
+func (h *inputHistory) next() (string, bool) {
+	if h.index >= len(h.entries) {
+		return "", false
+	}
+	h.index++
+	if h.index == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.index], true
+}
+
+// handleHistoryKey recalls inputs with Up on the first line of the input and Down on
+// its last, leaving other arrow presses to move within a multi-line input. It reports
+// whether it used the key.
+func (m *model) handleHistoryKey(msg tea.KeyMsg) bool {
+	if !m.ui.textarea.Focused() || m.ui.showSpinner || m.ui.askUserMode || m.searchActive() {
+		return false
+	}
+
+	var recalled string
+	var ok bool
+	switch msg.Type {
+	case tea.KeyUp:
+		if m.ui.textarea.Line() != 0 || m.ui.textarea.LineInfo().RowOffset != 0 {
+			return false
+		}
+		recalled, ok = m.ui.history.previous(m.ui.textarea.Value())
+	case tea.KeyDown:
+		info := m.ui.textarea.LineInfo()
+		if m.ui.textarea.Line() != m.ui.textarea.LineCount()-1 || info.RowOffset != info.Height-1 {
+			return false
+		}
+		recalled, ok = m.ui.history.next()
+	}
+	if ok {
+		m.ui.textarea.SetValue(recalled)
+		// Keep completion from taking over the arrows for a recalled slash command
+		m.ui.completionDismissed = true
+	}
+	return ok
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestInputHistoryCycling(t *testing.T) {
+	var h inputHistory
+	if _, ok := h.previous("draft"); ok {
+		t.Fatal("previous on an empty history reported an entry")
+	}
+
+	h.add("first")
+	h.add("second")
+	h.add("second") // a repeat of the latest isn't recorded twice
+	h.add("third")
+
+	var steps []string
+	step := func(entry string, ok bool) {
+		if !ok {
+			entry = "<none>"
+		}
+		steps = append(steps, entry)
+	}
+	step(h.previous("unsent draft"))
+	step(h.previous("third"))
+	step(h.previous("second"))
+	step(h.previous("first"))
+	step(h.next())
+	step(h.next())
+	step(h.next())
+	step(h.next())
+
+	want := []string{"third", "second", "first", "<none>", "second", "third", "unsent draft", "<none>"}
+	for i := range want {
+		if steps[i] != want[i] {
+			t.Fatalf("history steps = %q, want %q", steps, want)
+		}
+	}
+
+	// A new submission stops browsing and starts again from the newest entry
+	h.previous("")
+	h.previous("")
+	h.add("fourth")
+	if entry, _ := h.previous(""); entry != "fourth" {
+		t.Errorf("previous after add = %q, want fourth", entry)
+	}
+}
+
+func TestHistoryKeysLeaveMultilineNavigationAlone(t *testing.T) {
+	m := newTestModel(t)
+	m.ui.history.add("earlier prompt")
+
+	// The cursor ends up on the second line, so Up moves within the input
+	m.ui.textarea.SetValue("line one\nline two")
+	if m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyUp}) {
+		t.Fatal("Up on the last line of a multi-line input recalled history")
+	}
+
+	m.ui.textarea.Reset()
+	if !m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyUp}) || m.ui.textarea.Value() != "earlier prompt" {
+		t.Errorf("Up in an empty input = %q, want the earlier prompt", m.ui.textarea.Value())
+	}
+	if !m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyDown}) || m.ui.textarea.Value() != "" {
+		t.Errorf("Down after recalling = %q, want the empty draft back", m.ui.textarea.Value())
+	}
+}
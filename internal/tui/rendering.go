@@ -6,7 +6,9 @@ import (
 	"os"
 	"strings"
 
+	"agent/internal/agent"
 	"agent/internal/config"
+	"agent/internal/models"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -18,6 +20,11 @@ func (m *model) renderConversation() string {
 	}
 
 	m.ui.clickableLines = make(map[int]int)
+	m.ui.messageLines = make([]int, len(m.messages))
+	current := -1
+	if m.ui.searchQuery != "" {
+		current = m.currentMatch()
+	}
 	var lines []string
 	var currentLine int
 
@@ -30,6 +37,7 @@ func (m *model) renderConversation() string {
 
 	// Render messages
 	for i, msg := range m.messages {
+		m.ui.messageLines[i] = currentLine
 		var renderedBlock string
 		switch msg.mType {
 		case userMessage:
@@ -39,6 +47,13 @@ func (m *model) renderConversation() string {
 		case toolMessage, thoughtMessage:
 			renderedBlock = m.renderCollapsibleMessage(msg, i, &currentLine)
 		}
+		if m.ui.searchQuery != "" {
+			style := searchMatchStyle
+			if i == current {
+				style = searchCurrentStyle
+			}
+			renderedBlock = highlightMatches(renderedBlock, m.ui.searchQuery, style)
+		}
 		lines = append(lines, renderedBlock)
 		currentLine += lipgloss.Height(renderedBlock)
 	}
@@ -62,21 +77,35 @@ func (m *model) renderUserMessage(msg message) string {
 
 // renderAgentMessage renders an agent message
 func (m *model) renderAgentMessage(msg message) string {
+	color, label := secondaryColor, agentIcon+" Assistant"
+	if msg.isPlan {
+		color, label = warningColor, planIcon+" Plan"
+	}
+
 	header := labelStyle.Copy().
-		Foreground(secondaryColor).
-		Render(agentIcon + " Assistant")
+		Foreground(color).
+		Render(label)
 
 	if msg.isStreaming {
 		header += lipgloss.NewStyle().
-			Foreground(secondaryColor).
+			Foreground(color).
 			Blink(true).
 			Render(" ●")
 	}
 
-	content := m.renderMarkdown(msg.content)
+	// Glamour garbles partially streamed code blocks, so show raw text until the fence closes
+	content := msg.content
+	if !msg.isStreaming || !hasUnclosedCodeFence(msg.content) {
+		content = m.renderMarkdown(msg.content)
+	}
 	
-	return cardStyle.Copy().
-		BorderForeground(secondaryColor).
+	cardStyleToUse := cardStyle.Copy()
+	if msg.isPlan {
+		cardStyleToUse = cardStyleToUse.BorderStyle(lipgloss.ThickBorder())
+	}
+
+	return cardStyleToUse.
+		BorderForeground(color).
 		Width(m.ui.viewport.Width - 4).
 		Render(header + "\n" + content)
 }
@@ -91,12 +120,22 @@ func (m *model) renderCollapsibleMessage(msg message, index int, currentLine *in
 	if isThought {
 		icon = thoughtIcon
 		headerText = "Thinking..."
+		if msg.isStreaming && msg.isCollapsed {
+			// Follow a thought as it unfolds without expanding it
+			headerText += " " + truncateSummary(lastLine(strings.TrimPrefix(msg.content, "💭 Thinking: ")), max(m.ui.viewport.Width-24, 10))
+		}
 	} else if strings.Contains(msg.content, "Tool Call:") {
 		lines := strings.Split(msg.content, "\n")
 		if len(lines) > 0 {
 			headerText = strings.TrimPrefix(lines[0], "🔧 Tool Call: ")
 		}
 	}
+	if !isThought && msg.summary != "" {
+		headerText = msg.summary
+	}
+	if !isThought && msg.toolNumber > 0 {
+		headerText = fmt.Sprintf("#%d %s", msg.toolNumber, headerText)
+	}
 
 	// Create header
 	eIcon := collapseIcon
@@ -145,6 +184,8 @@ func (m *model) renderCollapsibleMessage(msg message, index int, currentLine *in
 	if isThought {
 		content = strings.TrimPrefix(msg.content, "💭 Thinking: ")
 		content = m.renderMarkdown(content)
+	} else if arguments, result := parseToolContent(msg.content); isUnifiedDiff(result) {
+		content = m.renderMarkdown(formatToolArguments(arguments)+"\n**Result:**") + "\n\n" + renderDiff(result)
 	} else {
 		content = m.renderMarkdown(formatToolContent(msg.content))
 	}
@@ -163,6 +204,29 @@ func (m *model) renderCollapsibleMessage(msg message, index int, currentLine *in
 		Render(header + "\n" + styledContent)
 }
 
+// renderDiff colors a unified diff line by line: additions green, removals red, hunks blue
+func renderDiff(diff string) string {
+	addStyle := lipgloss.NewStyle().Foreground(accentColor)
+	removeStyle := lipgloss.NewStyle().Foreground(errorColor)
+	hunkStyle := lipgloss.NewStyle().Foreground(secondaryColor)
+	headerStyle := lipgloss.NewStyle().Foreground(textMuted).Bold(true)
+
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "diff "):
+			lines[i] = headerStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = hunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = addStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = removeStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 // renderMarkdown renders markdown content
 func (m *model) renderMarkdown(content string) string {
 	if m.config.markdownRenderer == nil {
@@ -189,7 +253,12 @@ func (m *model) renderWelcomeHeader() string {
 		Bold(true).
 		Render("🎉 Welcome to CLI Code Assistant")
 
-	welcomeContent := fmt.Sprintf(config.WelcomeMessage, len(config.SystemPrompt))
+	welcomeContent := fmt.Sprintf(config.WelcomeMessage, len(m.config.agent.SystemPrompt()))
+	if config.SystemPromptFallback && m.config.agent.SystemPrompt() == config.SystemPrompt {
+		welcomeContent += lipgloss.NewStyle().
+			Foreground(warningColor).
+			Render("\n⚠ SYSTEM.md was empty at build time; using a minimal built-in prompt")
+	}
 	
 	// Apply word wrapping to content before rendering
 	contentStyle := lipgloss.NewStyle().
@@ -224,14 +293,39 @@ func (m *model) statusBarView() string {
 		fmt.Sprintf("🔮 %s", m.config.agent.Model),
 		fmt.Sprintf("📁 %s", cwd),
 	}
+	if len(m.tabs.sessions) > 1 {
+		items = append(items, fmt.Sprintf("🗂 %d/%d", m.tabs.active+1, len(m.tabs.sessions)))
+	}
+	if m.config.agent.PlanMode() {
+		items = append(items, lipgloss.NewStyle().Foreground(warningColor).Bold(true).Render(planIcon+" PLAN"))
+	}
+	if m.config.agent.DryRun() {
+		items = append(items, lipgloss.NewStyle().Foreground(warningColor).Bold(true).Render("🧪 DRY RUN"))
+	}
 
 	// Token usage
 	tokenUsage := m.config.agent.GetTokenUsage()
 	tokenText := fmt.Sprintf("🪙 %d/%d", tokenUsage.InputTokens, tokenUsage.OutputTokens)
-	if tokenUsage.TotalTokens > 500000 {
-		tokenText = lipgloss.NewStyle().Foreground(errorColor).Render(tokenText)
+	if window := m.config.agent.ContextWindow(); window > 0 && tokenUsage.ContextTokens > 0 {
+		tokenText += fmt.Sprintf(" (%d%% ctx)", tokenUsage.ContextTokens*100/window)
+	}
+	if _, ok := models.Lookup(m.config.agent.Model); ok {
+		tokenText += fmt.Sprintf(" $%.4f", models.EstimateCost(tokenUsage, m.config.agent.Model))
+	}
+	switch m.tokenUsageLevel(tokenUsage) {
+	case tokenCritical:
+		tokenText = lipgloss.NewStyle().Foreground(errorColor).Bold(true).Render(tokenText)
+	case tokenWarning:
+		tokenText = lipgloss.NewStyle().Foreground(warningColor).Render(tokenText)
 	}
 	items = append(items, tokenText)
+	if m.ui.searchQuery != "" {
+		matches := findMatches(m.messages, m.ui.searchQuery)
+		items = append(items, fmt.Sprintf("🔍 %q %d/%d", m.ui.searchQuery, min(m.ui.searchIndex+1, len(matches)), len(matches)))
+	}
+	if m.ui.statusNote != "" {
+		items = append(items, lipgloss.NewStyle().Foreground(accentColor).Render(m.ui.statusNote))
+	}
 
 	// Help text based on mode
 	var helpText string
@@ -239,9 +333,19 @@ func (m *model) statusBarView() string {
 		helpText = lipgloss.NewStyle().
 			Foreground(warningColor).
 			Bold(true).
-			Render("Y: Confirm | N/Esc: Deny")
+			Render("Y: Confirm | A: Always allow | N/Esc: Deny | E: Edit arguments | V: View all arguments")
+	} else if m.ui.toolArgsEditMode {
+		helpText = "Enter Run with these arguments • Esc Back to confirmation"
 	} else if m.ui.modelSelectionMode {
 		helpText = "↑↓ Navigate • Enter Select • Esc Cancel"
+	} else if m.ui.settingsMode {
+		helpText = "↑↓ Navigate • ←→ Adjust • Enter Save • Esc Cancel"
+	} else if m.ui.searchPrompt {
+		helpText = "Enter Search • Esc Cancel"
+	} else if m.ui.searchQuery != "" {
+		helpText = "n/Enter Next • N Previous • Esc End search"
+	} else if m.ui.showSpinner {
+		helpText = "Esc/Ctrl+C Interrupt • Ctrl+S Stop after this step"
 	} else {
 		confirmStatus := "OFF"
 		if m.config.requireToolConfirmation {
@@ -251,7 +355,10 @@ func (m *model) statusBarView() string {
 		if m.config.enableThinkingMode {
 			thinkStatus = "ON"
 		}
-		helpText = fmt.Sprintf("F2 Model • F3 Confirm:%s • F4 Think:%s • Ctrl+C Exit", confirmStatus, thinkStatus)
+		if !m.config.agent.IsThinkingSupported() {
+			thinkStatus = "N/A"
+		}
+		helpText = fmt.Sprintf("F2 Model • F3 Confirm:%s • F4 Think:%s • F5 Status • F6 Compact • F7/F8 Tabs • F9 Export • F10 Theme • Ctrl+C Exit", confirmStatus, thinkStatus)
 	}
 
 	// Join items
@@ -271,6 +378,43 @@ func (m *model) statusBarView() string {
 		Render(leftStatus + spacer + helpText)
 }
 
+// Token usage severity levels for the status bar
+const (
+	tokenOK = iota
+	tokenWarning
+	tokenCritical
+)
+
+// Fallback thresholds on total tokens for models whose context window is unknown
+const (
+	fallbackTokenWarning  = 500000
+	fallbackTokenCritical = 1000000
+)
+
+// tokenUsageLevel rates how full the context window is against the configured
+// thresholds, or total usage against fixed ones when the window isn't known yet
+func (m *model) tokenUsageLevel(usage agent.TokenUsage) int {
+	window := m.config.agent.ContextWindow()
+	if window <= 0 || usage.ContextTokens == 0 {
+		switch {
+		case usage.TotalTokens > fallbackTokenCritical:
+			return tokenCritical
+		case usage.TotalTokens > fallbackTokenWarning:
+			return tokenWarning
+		}
+		return tokenOK
+	}
+
+	percent := usage.ContextTokens * 100 / window
+	switch {
+	case percent >= m.config.tokenCriticalPercent:
+		return tokenCritical
+	case percent >= m.config.tokenWarningPercent:
+		return tokenWarning
+	}
+	return tokenOK
+}
+
 // renderModelSelector renders the model selection overlay
 func (m *model) renderModelSelector(background string) string {
 	title := lipgloss.NewStyle().
@@ -330,28 +474,52 @@ func (m *model) renderToolConfirmation(background string) string {
 		Align(lipgloss.Center).
 		Render("⚠️  Tool Execution Request")
 
-	// Tool info
+	// Tool info, showing the change an editing tool would make when it can be previewed
+	width, height := m.toolConfirmationLayout()
 	toolInfo := fmt.Sprintf("Tool: %s\n\nArguments:\n", m.ui.toolConfirmationName)
-	argsJSON, _ := json.MarshalIndent(m.ui.toolConfirmationArgs, "", "  ")
-	
+	var details string
+	if summary, diff := splitPreview(m.ui.toolConfirmationPreview); diff != "" && !m.ui.toolConfirmationExpanded {
+		toolInfo = fmt.Sprintf("Tool: %s\n\n%s\n", m.ui.toolConfirmationName, summary)
+		details = renderDiff(fitDiff(diff, width-10, height))
+	} else {
+		lines := m.toolConfirmationArgLines()
+		offset := min(m.ui.toolConfirmationScroll, max(len(lines)-height, 0))
+		details = strings.Join(lines[offset:min(offset+height, len(lines))], "\n")
+		if len(lines) > height {
+			details += fmt.Sprintf("\n… lines %d-%d of %d (↑/↓ to scroll)", offset+1, min(offset+height, len(lines)), len(lines))
+		}
+	}
+
 	argsBox := lipgloss.NewStyle().
 		Foreground(secondaryColor).
 		Background(bgDark).
 		Padding(1).
 		Border(lipgloss.NormalBorder()).
 		BorderForeground(bgLight).
-		Render(string(argsJSON))
+		Render(details)
 
 	// Buttons
 	buttons := lipgloss.JoinHorizontal(
 		lipgloss.Top,
 		lipgloss.NewStyle().Background(accentColor).Foreground(bgDark).Bold(true).Padding(0, 2).Render("Y - Yes"),
 		"  ",
+		lipgloss.NewStyle().Background(secondaryColor).Foreground(bgDark).Bold(true).Padding(0, 2).Render("A - Always"),
+		"  ",
 		lipgloss.NewStyle().Background(errorColor).Foreground(textPrimary).Bold(true).Padding(0, 2).Render("N - No"),
 		"  ",
+		lipgloss.NewStyle().Background(warningColor).Foreground(bgDark).Bold(true).Padding(0, 2).Render("E - Edit"),
+		"  ",
 		lipgloss.NewStyle().Background(bgLight).Foreground(textPrimary).Padding(0, 2).Render("Esc - Cancel"),
 	)
 
+	footer := "\n🔒 Tool execution requires your permission"
+	if m.ui.toolConfirmationNote != "" {
+		footer = "\n" + lipgloss.NewStyle().
+			Foreground(errorColor).
+			Bold(true).
+			Render("📂 "+m.ui.toolConfirmationNote)
+	}
+
 	content := lipgloss.JoinVertical(
 		lipgloss.Center,
 		title,
@@ -359,7 +527,7 @@ func (m *model) renderToolConfirmation(background string) string {
 		argsBox,
 		"\nDo you want to execute this tool?\n",
 		buttons,
-		"\n🔒 Tool execution requires your permission",
+		footer,
 	)
 
 	return lipgloss.Place(
@@ -367,7 +535,58 @@ func (m *model) renderToolConfirmation(background string) string {
 		lipgloss.Center, lipgloss.Center,
 		modalStyle.Copy().
 			BorderForeground(warningColor).
-			Width(60).
+			Width(width).
 			Render(content),
 	)
 }
+
+// toolConfirmationLayout returns the confirmation overlay's width and how many lines
+// of arguments or diff fit in it. Diffs and expanded arguments get a wider overlay;
+// otherwise it's just wide enough for the buttons.
+func (m *model) toolConfirmationLayout() (width, height int) {
+	width = 82
+	if m.ui.toolConfirmationExpanded || strings.Contains(m.ui.toolConfirmationPreview, "\n\n") {
+		width = max(width, min(m.ui.width-4, 100))
+	}
+	// Leave room for the rest of the overlay around the box
+	return width, max(m.ui.height-24, 5)
+}
+
+// toolConfirmationArgLines returns the pending tool call's arguments as indented JSON
+// wrapped to the overlay, with long values cut short unless the user expanded them
+func (m *model) toolConfirmationArgLines() []string {
+	args := m.ui.toolConfirmationArgs
+	if !m.ui.toolConfirmationExpanded {
+		args = truncateArgs(args, maxArgPreviewRunes).(map[string]interface{})
+	}
+	argsJSON, _ := json.MarshalIndent(args, "", "  ")
+	width, _ := m.toolConfirmationLayout()
+	return wrapLines(string(argsJSON), width-10)
+}
+
+// splitPreview separates an editing tool's dry-run preview into its summary, without
+// the dry-run wording, and its diff, which is empty when there's no change to show
+func splitPreview(preview string) (summary, diff string) {
+	summary, diff, _ = strings.Cut(preview, "\n\n")
+	summary = strings.TrimPrefix(summary, "[DRY RUN] ")
+	summary = strings.TrimSuffix(summary, " No files were changed.")
+	return summary, diff
+}
+
+// fitDiff shortens diff lines to width runes and keeps at most height lines,
+// noting how many were left out
+func fitDiff(diff string, width, height int) string {
+	lines := strings.Split(diff, "\n")
+	var omitted int
+	if len(lines) > height {
+		omitted = len(lines) - height + 1
+		lines = lines[:height-1]
+	}
+	for i, line := range lines {
+		lines[i] = truncateSummary(strings.ReplaceAll(line, "\t", "    "), width)
+	}
+	if omitted > 0 {
+		lines = append(lines, fmt.Sprintf("… %d more lines", omitted))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// startSearch opens the search prompt, or searches right away when a query is given
+func (m *model) startSearch(query string) tea.Cmd {
+	if m.ui.showSpinner {
+		m.addSystemMessage("🔍 Wait for the current response to finish (or press Esc) before searching", true)
+		return nil
+	}
+	if query != "" {
+		return m.runSearch(query)
+	}
+
+	m.ui.searchPrompt = true
+	m.ui.textarea.Reset()
+	m.ui.textarea.Placeholder = "Search the conversation and press Enter..."
+	m.ui.textarea.Focus()
+	return nil
+}
+
+// runSearch highlights every match of query and scrolls to the first one below the
+// top of the viewport, wrapping around to the start of the conversation
+func (m *model) runSearch(query string) tea.Cmd {
+	m.ui.searchPrompt = false
+	matches := findMatches(m.messages, query)
+	if len(matches) == 0 {
+		m.endSearch()
+		return m.flashStatus(fmt.Sprintf("🔍 No matches for %q", query))
+	}
+
+	m.ui.searchQuery = query
+	m.ui.searchIndex = 0
+	m.ui.textarea.Reset()
+	m.ui.textarea.Placeholder = "n/Enter: next match • N: previous match • Esc: end search"
+	m.ui.textarea.Blur()
+
+	// Render first so messageLines reflects the current layout
+	m.ui.viewport.SetContent(m.renderConversation())
+	for i, index := range matches {
+		if index < len(m.ui.messageLines) && m.ui.messageLines[index] >= m.ui.viewport.YOffset {
+			m.ui.searchIndex = i
+			break
+		}
+	}
+	m.showMatch(matches)
+	return nil
+}
+
+// handleSearchKey handles keys while the search prompt is open or matches are shown
+func (m *model) handleSearchKey(msg tea.KeyMsg) tea.Cmd {
+	if m.ui.searchPrompt {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m.endSearch()
+		case tea.KeyEnter:
+			query := strings.TrimSpace(m.ui.textarea.Value())
+			if query == "" {
+				m.endSearch()
+				return nil
+			}
+			return m.runSearch(query)
+		}
+		return nil
+	}
+
+	switch msg.String() {
+	case "n", "enter":
+		m.moveSearch(1)
+	case "N":
+		m.moveSearch(-1)
+	case "esc", "q":
+		m.endSearch()
+	}
+	return nil
+}
+
+// moveSearch steps delta matches forward or back, wrapping around at either end
+func (m *model) moveSearch(delta int) {
+	// Messages can change between steps, e.g. after switching tabs, so match afresh
+	matches := findMatches(m.messages, m.ui.searchQuery)
+	if len(matches) == 0 {
+		m.endSearch()
+		return
+	}
+	m.ui.searchIndex = ((m.ui.searchIndex+delta)%len(matches) + len(matches)) % len(matches)
+	m.showMatch(matches)
+}
+
+// showMatch expands the current match if it's collapsed and scrolls it into view
+func (m *model) showMatch(matches []int) {
+	m.ui.searchIndex = min(m.ui.searchIndex, len(matches)-1)
+	index := matches[m.ui.searchIndex]
+	m.messages[index].isCollapsed = false
+	m.ui.viewport.SetContent(m.renderConversation())
+	if index < len(m.ui.messageLines) {
+		m.ui.viewport.SetYOffset(m.ui.messageLines[index])
+	}
+}
+
+// endSearch closes the search and removes its highlighting
+func (m *model) endSearch() {
+	m.ui.searchPrompt = false
+	m.ui.searchQuery = ""
+	m.ui.searchIndex = 0
+	m.ui.textarea.Reset()
+	m.ui.textarea.Placeholder = "Enter your message here..."
+	m.ui.textarea.Focus()
+	m.ui.viewport.SetContent(m.renderConversation())
+}
+
+// searchActive reports whether search is taking keyboard input
+func (m *model) searchActive() bool {
+	return m.ui.searchPrompt || m.ui.searchQuery != ""
+}
+
+// currentMatch returns the index of the message holding the current match, or -1
+func (m *model) currentMatch() int {
+	matches := findMatches(m.messages, m.ui.searchQuery)
+	if len(matches) == 0 {
+		return -1
+	}
+	return matches[min(m.ui.searchIndex, len(matches)-1)]
+}
+
+// findMatches returns the indices of the messages whose content contains query,
+// ignoring case
+func findMatches(messages []message, query string) []int {
+	if query == "" {
+		return nil
+	}
+	query = strings.ToLower(query)
+	var matches []int
+	for i, msg := range messages {
+		if strings.Contains(strings.ToLower(msg.content), query) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// highlightMatches styles each case-insensitive occurrence of query in rendered
+// output, skipping over ANSI escape sequences. Matches split by an escape
+// sequence, such as across a markdown style change, aren't highlighted.
+func highlightMatches(rendered, query string, style lipgloss.Style) string {
+	if query == "" {
+		return rendered
+	}
+	query = strings.ToLower(query)
+
+	var b strings.Builder
+	// active holds the styling in effect, restored after each highlight resets it
+	var active string
+	for len(rendered) > 0 {
+		if rendered[0] == '\x1b' {
+			seq := escapeSequence(rendered)
+			if strings.HasSuffix(seq, "m") && strings.HasPrefix(seq, "\x1b[") {
+				if seq == "\x1b[0m" || seq == "\x1b[m" {
+					active = ""
+				} else {
+					active += seq
+				}
+			}
+			b.WriteString(seq)
+			rendered = rendered[len(seq):]
+			continue
+		}
+
+		end := strings.IndexByte(rendered, '\x1b')
+		if end < 0 {
+			end = len(rendered)
+		}
+		b.WriteString(highlightText(rendered[:end], query, style, active))
+		rendered = rendered[end:]
+	}
+	return b.String()
+}
+
+// highlightText styles occurrences of the lowercase query in plain text
+func highlightText(text, query string, style lipgloss.Style, active string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Lowercasing changed byte offsets, so fall back to an exact match
+		lower = text
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, query)
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:i])
+		b.WriteString(style.Render(text[i : i+len(query)]))
+		b.WriteString(active)
+		text, lower = text[i+len(query):], lower[i+len(query):]
+	}
+}
+
+// escapeSequence returns the ANSI escape sequence at the start of s
+func escapeSequence(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameters and intermediates end at a final byte in @ to ~
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return s[:i+1]
+			}
+		}
+		return s
+	case ']':
+		// OSC, e.g. hyperlinks: ends at BEL or ESC \
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return s[:i+1]
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return s[:i+2]
+			}
+		}
+		return s
+	}
+	return s[:2]
+}
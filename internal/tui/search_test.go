@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestFindMatches(t *testing.T) {
+	messages := []message{
+		{mType: userMessage, content: "Where is the Config loaded?"},
+		{mType: agentMessage, content: "It's read in main.go"},
+		{mType: toolMessage, content: "🔧 Tool Call: read_file\nconfig.LoadPreferences()"},
+		{mType: agentMessage, content: "CONFIG and config again"},
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"config", []int{0, 2, 3}},
+		{"MAIN.GO", []int{1}},
+		{"no such text", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := findMatches(messages, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("findMatches(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMoveSearchWrapsAround(t *testing.T) {
+	m := newTestModel(t)
+	m.messages = []message{
+		{mType: userMessage, content: "first match"},
+		{mType: agentMessage, content: "nothing here"},
+		{mType: agentMessage, content: "second match"},
+	}
+	m.ui.searchQuery = "match"
+
+	var visited []int
+	for range 3 {
+		m.moveSearch(1)
+		visited = append(visited, m.currentMatch())
+	}
+	if !slices.Equal(visited, []int{2, 0, 2}) {
+		t.Errorf("n visited messages %v, want 2, 0, 2", visited)
+	}
+	m.moveSearch(-1)
+	if got := m.currentMatch(); got != 0 {
+		t.Errorf("N went to message %d, want 0", got)
+	}
+}
+
+func TestHighlightMatchesSkipsEscapeSequences(t *testing.T) {
+	style := lipgloss.NewStyle().Bold(true)
+	bold := "\x1b[1m"
+
+	got := highlightMatches(bold+"Foo bar foo\x1b[0m \x1b[31mfoo\x1b[0m", "foo", style)
+	want := bold + style.Render("Foo") + bold + " bar " + style.Render("foo") + bold + "\x1b[0m \x1b[31m" + style.Render("foo") + "\x1b[31m\x1b[0m"
+	if got != want {
+		t.Errorf("highlightMatches =\n%q\nwant\n%q", got, want)
+	}
+
+	// The query never matches inside an escape sequence
+	if got := highlightMatches("\x1b[31mred\x1b[0m", "31m", style); got != "\x1b[31mred\x1b[0m" {
+		t.Errorf("highlighted inside an escape sequence: %q", got)
+	}
+}
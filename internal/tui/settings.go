@@ -0,0 +1,159 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"agent/internal/agent"
+	"agent/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// generationSetting is a generation parameter adjustable in the settings overlay
+type generationSetting struct {
+	label  string
+	format string
+	step   float64
+	lo, hi float64
+	get    func(*agent.AgentConfig) float64
+	set    func(*agent.AgentConfig, float64)
+}
+
+// generationSettings are the rows of the settings overlay, in display order
+var generationSettings = []generationSetting{
+	{
+		label: "Temperature", format: "%.2f", step: 0.1, lo: 0, hi: 2,
+		get: func(c *agent.AgentConfig) float64 { return float64(c.Temperature) },
+		set: func(c *agent.AgentConfig, v float64) { c.Temperature = float32(v) },
+	},
+	{
+		label: "Top P", format: "%.2f", step: 0.05, lo: 0, hi: 1,
+		get: func(c *agent.AgentConfig) float64 { return float64(c.TopP) },
+		set: func(c *agent.AgentConfig, v float64) { c.TopP = float32(v) },
+	},
+	{
+		label: "Max output tokens", format: "%.0f", step: 1024, lo: 1024, hi: 65536,
+		get: func(c *agent.AgentConfig) float64 { return float64(c.MaxOutputTokens) },
+		set: func(c *agent.AgentConfig, v float64) { c.MaxOutputTokens = int32(v) },
+	},
+}
+
+// openSettings shows the generation settings overlay
+func (m *model) openSettings() {
+	if m.ui.showSpinner {
+		m.addSystemMessage("⚙️ Wait for the current response to finish (or press Esc) before changing settings", true)
+		return
+	}
+
+	cfg := m.config.agent.GetConfig()
+	m.ui.settingsOriginal = make([]float64, len(generationSettings))
+	for i, setting := range generationSettings {
+		m.ui.settingsOriginal[i] = setting.get(cfg)
+	}
+	m.ui.settingsMode = true
+	m.ui.selectedSetting = 0
+	m.ui.textarea.Blur()
+}
+
+// handleSettingsKey handles keys in the settings overlay. Changes apply as they're
+// made; Enter saves them and Esc puts the previous values back.
+func (m *model) handleSettingsKey(msg tea.KeyMsg) tea.Cmd {
+	cfg := m.config.agent.GetConfig()
+	switch msg.Type {
+	case tea.KeyUp:
+		if m.ui.selectedSetting > 0 {
+			m.ui.selectedSetting--
+		}
+	case tea.KeyDown:
+		if m.ui.selectedSetting < len(generationSettings)-1 {
+			m.ui.selectedSetting++
+		}
+	case tea.KeyLeft, tea.KeyRight:
+		setting := generationSettings[m.ui.selectedSetting]
+		delta := setting.step
+		if msg.Type == tea.KeyLeft {
+			delta = -delta
+		}
+		setting.set(cfg, adjustSetting(setting, setting.get(cfg)+delta))
+	case tea.KeyEsc:
+		for i, setting := range generationSettings {
+			setting.set(cfg, m.ui.settingsOriginal[i])
+		}
+		m.closeSettings()
+	case tea.KeyEnter:
+		m.closeSettings()
+		return m.saveSettings()
+	}
+	return nil
+}
+
+// adjustSetting rounds value to the setting's step, which float32 storage drifts
+// from, and keeps it in range
+func adjustSetting(setting generationSetting, value float64) float64 {
+	value = math.Round(value/setting.step) * setting.step
+	return math.Max(setting.lo, math.Min(setting.hi, value))
+}
+
+// closeSettings hides the settings overlay
+func (m *model) closeSettings() {
+	m.ui.settingsMode = false
+	m.ui.settingsOriginal = nil
+	m.ui.textarea.Focus()
+}
+
+// saveSettings persists the current generation settings to preferences
+func (m *model) saveSettings() tea.Cmd {
+	cfg := m.config.agent.GetConfig()
+	prefs, _ := config.LoadPreferences()
+	if prefs == nil {
+		prefs = &config.UserPreferences{}
+	}
+	temperature, topP, maxOutputTokens := cfg.Temperature, cfg.TopP, cfg.MaxOutputTokens
+	prefs.Temperature = &temperature
+	prefs.TopP = &topP
+	prefs.MaxOutputTokens = &maxOutputTokens
+	if err := config.SavePreferences(prefs); err != nil {
+		m.addSystemMessage(fmt.Sprintf("⚙️ Settings applied but not saved: %v", err), true)
+		return nil
+	}
+	return m.flashStatus(fmt.Sprintf("⚙️ Saved temperature %.2f, top P %.2f, max output tokens %d", temperature, topP, maxOutputTokens))
+}
+
+// renderSettings renders the generation settings overlay
+func (m *model) renderSettings(background string) string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(2).
+		Render("⚙️ Generation Settings")
+
+	cfg := m.config.agent.GetConfig()
+	var items []string
+	for i, setting := range generationSettings {
+		style := normalItemStyle
+		if i == m.ui.selectedSetting {
+			style = selectedItemStyle
+		}
+		value := fmt.Sprintf(setting.format, setting.get(cfg))
+		items = append(items, style.Render(fmt.Sprintf("%-18s ◀ %s ▶", setting.label, value)))
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		strings.Join(items, "\n"),
+		"\n↑/↓ Navigate • ←/→ Adjust • Enter Save • Esc Cancel",
+	)
+
+	return lipgloss.Place(
+		m.ui.width, m.ui.height,
+		lipgloss.Center, lipgloss.Center,
+		modalStyle.Copy().
+			BorderForeground(primaryColor).
+			Width(60).
+			Render(content),
+	)
+}
@@ -4,19 +4,83 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Core colors - improved visibility
+// Theme bundles a color palette with the glamour style that suits it
+type Theme struct {
+	Name    string
+	Glamour string // glamour style used for markdown
+
+	Primary   lipgloss.Color
+	Secondary lipgloss.Color
+	Accent    lipgloss.Color
+	Error     lipgloss.Color
+	Warning   lipgloss.Color
+
+	BgDark  lipgloss.Color // status bar, modals, text on colored backgrounds
+	BgLight lipgloss.Color // collapsible headers, muted buttons
+
+	TextPrimary lipgloss.Color
+	TextMuted   lipgloss.Color
+}
+
+// themes are the built-in themes, in the order F10 cycles through them
+var themes = []Theme{
+	{
+		Name:        "dark",
+		Glamour:     "dark",
+		Primary:     lipgloss.Color("87"),  // Light Cyan (more visible)
+		Secondary:   lipgloss.Color("75"),  // Light Blue (more visible)
+		Accent:      lipgloss.Color("120"), // Light Green (more visible)
+		Error:       lipgloss.Color("203"), // Light Red/Pink (softer)
+		Warning:     lipgloss.Color("221"), // Light Yellow/Orange
+		BgDark:      lipgloss.Color("236"), // Slightly lighter dark gray
+		BgLight:     lipgloss.Color("244"), // Medium gray (more visible)
+		TextPrimary: lipgloss.Color("15"),  // Bright White
+		TextMuted:   lipgloss.Color("250"), // Light gray (much more visible than 8)
+	},
+	{
+		Name:        "light",
+		Glamour:     "light",
+		Primary:     lipgloss.Color("25"),  // Deep Blue
+		Secondary:   lipgloss.Color("31"),  // Teal
+		Accent:      lipgloss.Color("28"),  // Green
+		Error:       lipgloss.Color("160"), // Red
+		Warning:     lipgloss.Color("130"), // Dark Orange
+		BgDark:      lipgloss.Color("254"), // Very light gray
+		BgLight:     lipgloss.Color("250"), // Light gray
+		TextPrimary: lipgloss.Color("16"),  // Black
+		TextMuted:   lipgloss.Color("240"), // Dark gray
+	},
+	{
+		Name:        "high-contrast",
+		Glamour:     "dark",
+		Primary:     lipgloss.Color("51"),  // Bright Cyan
+		Secondary:   lipgloss.Color("39"),  // Bright Blue
+		Accent:      lipgloss.Color("46"),  // Bright Green
+		Error:       lipgloss.Color("196"), // Bright Red
+		Warning:     lipgloss.Color("226"), // Bright Yellow
+		BgDark:      lipgloss.Color("16"),  // Black
+		BgLight:     lipgloss.Color("250"), // Light gray
+		TextPrimary: lipgloss.Color("231"), // White
+		TextMuted:   lipgloss.Color("255"), // Near white
+	},
+}
+
+// activeTheme is the theme the colors below were last set from
+var activeTheme = themes[0]
+
+// Core colors, set from the active theme
 var (
-	primaryColor   = lipgloss.Color("87")  // Light Cyan (more visible)
-	secondaryColor = lipgloss.Color("75")  // Light Blue (more visible)
-	accentColor    = lipgloss.Color("120") // Light Green (more visible)
-	errorColor     = lipgloss.Color("203") // Light Red/Pink (softer)
-	warningColor   = lipgloss.Color("221") // Light Yellow/Orange
-	
-	bgDark     = lipgloss.Color("236") // Slightly lighter dark gray
-	bgLight    = lipgloss.Color("244") // Medium gray (more visible)
-	
-	textPrimary = lipgloss.Color("15")  // Bright White
-	textMuted   = lipgloss.Color("250") // Light gray (much more visible than 8)
+	primaryColor   = activeTheme.Primary
+	secondaryColor = activeTheme.Secondary
+	accentColor    = activeTheme.Accent
+	errorColor     = activeTheme.Error
+	warningColor   = activeTheme.Warning
+
+	bgDark  = activeTheme.BgDark
+	bgLight = activeTheme.BgLight
+
+	textPrimary = activeTheme.TextPrimary
+	textMuted   = activeTheme.TextMuted
 )
 
 // Base styles
@@ -76,14 +140,98 @@ var (
 	normalItemStyle = lipgloss.NewStyle().
 		Padding(0, 2).
 		MarginBottom(1)
+
+	// Search match highlighting
+	searchMatchStyle = lipgloss.NewStyle().
+		Background(warningColor).
+		Foreground(bgDark)
+
+	searchCurrentStyle = lipgloss.NewStyle().
+		Background(accentColor).
+		Foreground(bgDark).
+		Bold(true)
+
+	// Slash command completion popup
+	completionStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Background(bgDark)
+
+	completionSelectedStyle = lipgloss.NewStyle().
+		Background(primaryColor).
+		Foreground(bgDark).
+		Bold(true)
 )
 
+// applyCompactMode shrinks card and input padding/margins to save vertical space
+func applyCompactMode(compact bool) {
+	if compact {
+		cardStyle = cardStyle.Padding(0, 1).MarginBottom(0)
+		collapsibleCardStyle = collapsibleCardStyle.MarginBottom(0)
+		collapsibleContentStyle = collapsibleContentStyle.Padding(0, 1)
+		textInputStyle = textInputStyle.Padding(0, 1).MarginTop(0)
+		return
+	}
+
+	cardStyle = cardStyle.Padding(1, 2).MarginBottom(1)
+	collapsibleCardStyle = collapsibleCardStyle.MarginBottom(1)
+	collapsibleContentStyle = collapsibleContentStyle.Padding(1, 2)
+	textInputStyle = textInputStyle.Padding(1, 2).MarginTop(1)
+}
+
+// findTheme returns the built-in theme with the given name, or the dark theme
+func findTheme(name string) Theme {
+	for _, theme := range themes {
+		if theme.Name == name {
+			return theme
+		}
+	}
+	return themes[0]
+}
+
+// nextTheme returns the theme after the active one, wrapping around
+func nextTheme() Theme {
+	for i, theme := range themes {
+		if theme.Name == activeTheme.Name {
+			return themes[(i+1)%len(themes)]
+		}
+	}
+	return themes[0]
+}
+
+// applyTheme switches the core colors to theme and recolors the shared styles.
+// Message rendering reads the colors directly, so it picks them up on the next render.
+func applyTheme(theme Theme) {
+	activeTheme = theme
+	primaryColor = theme.Primary
+	secondaryColor = theme.Secondary
+	accentColor = theme.Accent
+	errorColor = theme.Error
+	warningColor = theme.Warning
+	bgDark = theme.BgDark
+	bgLight = theme.BgLight
+	textPrimary = theme.TextPrimary
+	textMuted = theme.TextMuted
+
+	collapsibleHeaderStyle = collapsibleHeaderStyle.Background(bgLight)
+	textInputStyle = textInputStyle.BorderForeground(primaryColor)
+	spinnerStyle = spinnerStyle.Foreground(secondaryColor)
+	statusBarStyle = statusBarStyle.Background(bgDark)
+	modalStyle = modalStyle.Background(bgDark)
+	selectedItemStyle = selectedItemStyle.Background(primaryColor).Foreground(bgDark)
+	searchMatchStyle = searchMatchStyle.Background(warningColor).Foreground(bgDark)
+	searchCurrentStyle = searchCurrentStyle.Background(accentColor).Foreground(bgDark)
+	completionStyle = completionStyle.BorderForeground(primaryColor).Background(bgDark)
+	completionSelectedStyle = completionSelectedStyle.Background(primaryColor).Foreground(bgDark)
+}
+
 // Icons
 const (
 	userIcon     = "👤"
 	agentIcon    = "🤖"
 	toolIcon     = "🔧"
 	thoughtIcon  = "💭"
+	planIcon     = "📋"
 	expandIcon   = "▼"
 	collapseIcon = "▶"
 )
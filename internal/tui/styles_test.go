@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"testing"
+
+	"agent/internal/config"
+)
+
+func TestCycleThemeChangesActiveColors(t *testing.T) {
+	m := newTestModel(t)
+	t.Cleanup(func() { applyTheme(themes[0]) })
+	applyTheme(findTheme("dark"))
+
+	m.cycleTheme()
+	if activeTheme.Name != "light" {
+		t.Fatalf("active theme = %q after one cycle, want light", activeTheme.Name)
+	}
+	light := findTheme("light")
+	if primaryColor != light.Primary || bgDark != light.BgDark || textPrimary != light.TextPrimary {
+		t.Errorf("colors = %v/%v/%v, want the light theme's %v/%v/%v", primaryColor, bgDark, textPrimary, light.Primary, light.BgDark, light.TextPrimary)
+	}
+	if primaryColor == findTheme("dark").Primary {
+		t.Error("primary color unchanged from the dark theme")
+	}
+	// Shared styles are recolored too, not just the color variables
+	if got := statusBarStyle.GetBackground(); got != light.BgDark {
+		t.Errorf("status bar background = %v, want %v", got, light.BgDark)
+	}
+
+	prefs, err := config.LoadPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefs.SelectedTheme != "light" {
+		t.Errorf("saved theme = %q, want light", prefs.SelectedTheme)
+	}
+
+	// Cycling past the last theme wraps around
+	m.cycleTheme()
+	m.cycleTheme()
+	if activeTheme.Name != "dark" || primaryColor != findTheme("dark").Primary {
+		t.Errorf("after a full cycle the theme is %q, want dark", activeTheme.Name)
+	}
+}
@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+
+	"agent/internal/agent"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// session is one tab: an agent with its own conversation, model and token usage
+type session struct {
+	agent           *agent.Agent
+	messages        []message
+	pendingToolRefs []int
+	pendingImages   []string
+	yOffset         int
+}
+
+// TabState groups the open sessions. The active session's agent and transcript
+// live in model.config.agent and model.messages; its slot here is refreshed on switch.
+type TabState struct {
+	sessions []session
+	active   int
+}
+
+// newTab opens a fresh session with the current model and switches to it
+func (m *model) newTab() tea.Cmd {
+	if m.tabBusy() {
+		return nil
+	}
+
+	m.saveActiveSession()
+	m.tabs.sessions = append(m.tabs.sessions, session{agent: m.config.agent.NewSession()})
+	m.loadSession(len(m.tabs.sessions) - 1)
+	return nil
+}
+
+// handleTabCommand opens, closes or switches tabs
+func (m *model) handleTabCommand(args []string) tea.Cmd {
+	if len(args) == 0 {
+		m.addSystemMessage("Usage: /tab new | /tab close | /tab <n>", true)
+		return nil
+	}
+
+	switch args[0] {
+	case "new":
+		return m.newTab()
+	case "close":
+		return m.closeTab()
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		m.addSystemMessage(fmt.Sprintf("Unknown /tab subcommand: %s", args[0]), true)
+		return nil
+	}
+	return m.switchTab(n - 1)
+}
+
+// switchTab makes the session at index the active one
+func (m *model) switchTab(index int) tea.Cmd {
+	if index < 0 || index >= len(m.tabs.sessions) {
+		m.addSystemMessage(fmt.Sprintf("🗂 No tab %d (%d open)", index+1, len(m.tabs.sessions)), true)
+		return nil
+	}
+	if index == m.tabs.active || m.tabBusy() {
+		return nil
+	}
+
+	m.saveActiveSession()
+	m.loadSession(index)
+	return nil
+}
+
+// closeTab discards the active session and switches to its neighbour
+func (m *model) closeTab() tea.Cmd {
+	if len(m.tabs.sessions) == 1 {
+		m.addSystemMessage("🗂 Can't close the last tab; use /new to start over", true)
+		return nil
+	}
+	if m.tabBusy() {
+		return nil
+	}
+
+	closed := m.tabs.active
+	m.tabs.sessions = append(m.tabs.sessions[:closed], m.tabs.sessions[closed+1:]...)
+	m.loadSession(min(closed, len(m.tabs.sessions)-1))
+	m.addSystemMessage(fmt.Sprintf("🗂 Closed tab %d", closed+1), false)
+	return nil
+}
+
+// tabBusy reports, with a notice, whether the active tab is still responding.
+// Streaming state is tied to the visible transcript, so tabs can't change mid-response.
+func (m *model) tabBusy() bool {
+	if !m.ui.showSpinner {
+		return false
+	}
+	m.addSystemMessage("🗂 Wait for the current response to finish (or press Esc) before switching tabs", true)
+	return true
+}
+
+// saveActiveSession stores the visible conversation back into its tab
+func (m *model) saveActiveSession() {
+	m.tabs.sessions[m.tabs.active] = session{
+		agent:           m.config.agent,
+		messages:        m.messages,
+		pendingToolRefs: m.ui.pendingToolRefs,
+		pendingImages:   m.ui.pendingImages,
+		yOffset:         m.ui.viewport.YOffset,
+	}
+}
+
+// loadSession makes the tab at index visible
+func (m *model) loadSession(index int) {
+	s := m.tabs.sessions[index]
+	m.tabs.active = index
+	m.config.agent = s.agent
+	m.messages = s.messages
+	m.ui.pendingToolRefs = s.pendingToolRefs
+	m.ui.pendingImages = s.pendingImages
+	m.stream.streamingMsg = nil
+	m.stream.streamingMsgIndex = -1
+	m.stream.thoughtMsgIndex = -1
+	m.stream.streamingWasInterrupted = false
+
+	for i, name := range m.config.availableModels {
+		if name == m.config.agent.Model {
+			m.ui.selectedModelIndex = i
+		}
+	}
+
+	m.ui.viewport.SetContent(m.renderConversation())
+	m.ui.viewport.SetYOffset(s.yOffset)
+}
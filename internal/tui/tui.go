@@ -2,14 +2,18 @@ package tui
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
 	"agent/internal/agent"
 	"agent/internal/config"
+	"agent/internal/models"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
@@ -17,6 +21,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"google.golang.org/genai"
 )
 
 type (
@@ -27,6 +32,11 @@ type (
 		isCollapsed bool
 		isError     bool
 		isStreaming bool
+		isPlan      bool
+		toolNumber  int    // 1-based position among tool calls, used by /ref
+		summary     string // one-line result shown in the collapsed tool header
+		isSystem    bool   // a notice from the app itself rather than the agent
+		isAnswer    bool   // the user's reply to an ask_user question
 	}
 )
 
@@ -51,9 +61,51 @@ type UIState struct {
 	// Modal states
 	modelSelectionMode   bool
 	selectedModelIndex   int
+	settingsMode         bool
+	selectedSetting      int
+	settingsOriginal     []float64 // values to restore if the settings overlay is cancelled
 	toolConfirmationMode bool
 	toolConfirmationName string
 	toolConfirmationArgs map[string]interface{}
+	toolConfirmationNote string
+	// toolConfirmationPreview is an editing tool's dry-run description of its change
+	toolConfirmationPreview string
+	// toolConfirmationExpanded shows the full arguments, scrolled down toolConfirmationScroll lines
+	toolConfirmationExpanded bool
+	toolConfirmationScroll   int
+	// toolArgsEditMode hands the input box to the user to correct the pending tool call's
+	// arguments; toolArgsDraft keeps what they had typed there before
+	toolArgsEditMode bool
+	toolArgsDraft    string
+	askUserMode      bool
+
+	// Tool loop progress
+	iteration     int
+	stopRequested bool
+	toolOutput    string    // latest line of output from a running tool
+	interruptedAt time.Time // when Ctrl+C last interrupted a response
+
+	// Submitted inputs, recalled with Up and Down
+	history inputHistory
+
+	// Slash command completion: the picked match, and whether Esc hid the popup
+	completionIndex     int
+	completionDismissed bool
+
+	// Tool calls whose output is quoted into the next prompt
+	pendingToolRefs []int
+	// Image files attached to the next prompt
+	pendingImages []string
+
+	// Short-lived notice shown in the status bar, e.g. after copying
+	statusNote   string
+	statusNoteID int
+
+	// In-conversation search; messageLines holds each message's first line in the viewport
+	searchPrompt bool
+	searchQuery  string
+	searchIndex  int
+	messageLines []int
 }
 
 // StreamState groups streaming-related state
@@ -61,17 +113,19 @@ type StreamState struct {
 	streamingMsg            *message
 	streamingMsgIndex       int
 	streamingWasInterrupted bool
+	thoughtMsgIndex         int // the thought message being streamed, or -1
 
 	// Context management
 	cancelFunc context.CancelFunc
 
 	// Channels
-	streamChunkChan          chan streamChunkMsg
-	toolMessageChan          chan toolMessageMsg
-	thoughtMessageChan       chan thoughtMessageMsg
-	streamCompleteChan       chan streamCompleteMsg
+	streamEventChan          chan tea.Msg // chunks, tool and thought messages, then completion, in order
 	toolConfirmationChan     chan toolConfirmationRequestMsg
-	confirmationResponseChan chan bool
+	confirmationResponseChan chan toolConfirmationResponse
+	execRequestChan          chan execRequestMsg
+	askUserChan              chan askUserRequestMsg
+	askUserResponseChan      chan string
+	iterationChan            chan iterationMsg
 }
 
 // AppConfig groups application configuration
@@ -80,7 +134,26 @@ type AppConfig struct {
 	availableModels         []string
 	markdownRenderer        *glamour.TermRenderer
 	requireToolConfirmation bool
+	toolPolicy              config.ToolConfirmationPolicy // per-tool overrides of requireToolConfirmation
 	enableThinkingMode      bool
+	compactMode             bool
+	diffContextLines        int
+	allowOutsideReads       bool
+	expandThoughts          bool
+	expandToolMessages      bool
+	persistAutoApprovals    bool
+	maxStreamedMessageChars int
+	tokenWarningPercent     int
+	tokenCriticalPercent    int
+
+	// Tools the user chose to always allow; read from the streaming goroutine
+	approvedTools *toolApprovals
+}
+
+// toolApprovals is the set of tools the user chose to always allow
+type toolApprovals struct {
+	mu    sync.Mutex
+	tools map[string]bool
 }
 
 // model represents the main application model
@@ -88,11 +161,18 @@ type model struct {
 	ui       UIState
 	stream   StreamState
 	config   AppConfig
+	tabs     TabState
 	messages []message
 	err      error
 }
 
 func InitialModel(agent *agent.Agent) *model {
+	// Load user preferences; the theme has to be set before any styles are used
+	prefs, _ := config.LoadPreferences()
+	if prefs != nil {
+		applyTheme(findTheme(prefs.SelectedTheme))
+	}
+
 	// Initialize text area
 	ta := textarea.New()
 	ta.Placeholder = "Enter your message here..."
@@ -111,7 +191,7 @@ func InitialModel(agent *agent.Agent) *model {
 
 	// Initialize markdown renderer with auto-style (dark/light) and appropriate width
 	markdownRenderer, err := glamour.NewTermRenderer(
-		glamour.WithStylePath("dark"),
+		glamour.WithStylePath(activeTheme.Glamour),
 		glamour.WithWordWrap(78), // Slightly less than viewport width for padding
 	)
 	if err != nil {
@@ -119,16 +199,8 @@ func InitialModel(agent *agent.Agent) *model {
 		markdownRenderer, _ = glamour.NewTermRenderer()
 	}
 
-	// Available Gemini models based on the documentation
-	availableModels := []string{
-		"gemini-2.5-pro",
-		"gemini-2.5-flash",
-		"gemini-2.5-flash-lite",
-		"gemini-2.0-flash",
-		"gemini-2.0-flash-lite",
-		"gemini-1.5-pro",
-		"gemini-1.5-flash",
-	}
+	// Available Gemini models based on the documentation, replaced by the live list once fetched
+	availableModels := models.IDs(models.Models)
 
 	// Find current model index
 	currentModelIndex := 1 // Default to gemini-2.5-flash
@@ -139,14 +211,22 @@ func InitialModel(agent *agent.Agent) *model {
 		}
 	}
 
-	// Load user preferences
-	prefs, _ := config.LoadPreferences()
 	requireConfirmation := true // Default to true
 	enableThinking := false     // Default to false
+	showStatusBar := true       // Default to true
+	compactMode := false        // Default to false
+	maxStreamedMessageChars := 0
+	var toolPolicy config.ToolConfirmationPolicy
 	if prefs != nil {
 		requireConfirmation = prefs.RequireToolConfirmation
+		toolPolicy = prefs.ToolConfirmationPolicy
 		enableThinking = prefs.EnableThinkingMode
+		showStatusBar = !prefs.HideStatusBar
+		compactMode = prefs.CompactMode
+		maxStreamedMessageChars = prefs.MaxStreamedMessageChars
 	}
+	applyCompactMode(compactMode)
+	tokenWarningPercent, tokenCriticalPercent := prefs.GetTokenThresholds()
 
 	m := &model{
 		ui: UIState{
@@ -154,7 +234,7 @@ func InitialModel(agent *agent.Agent) *model {
 			viewport:             vp,
 			spinner:              s,
 			showSpinner:          false,
-			showStatusBar:        true,
+			showStatusBar:        showStatusBar,
 			clickableLines:       make(map[int]int),
 			modelSelectionMode:   false,
 			selectedModelIndex:   currentModelIndex,
@@ -164,24 +244,56 @@ func InitialModel(agent *agent.Agent) *model {
 		},
 		stream: StreamState{
 			streamingMsgIndex:        -1,
+			thoughtMsgIndex:          -1,
 			streamingWasInterrupted:  false,
-			streamChunkChan:          make(chan streamChunkMsg, 100),
-			toolMessageChan:          make(chan toolMessageMsg, 10),
-			thoughtMessageChan:       make(chan thoughtMessageMsg, 10),
-			streamCompleteChan:       make(chan streamCompleteMsg, 1),
+			streamEventChan:          make(chan tea.Msg, 100),
 			toolConfirmationChan:     make(chan toolConfirmationRequestMsg, 1),
-			confirmationResponseChan: make(chan bool, 1),
+			confirmationResponseChan: make(chan toolConfirmationResponse, 1),
+			execRequestChan:          make(chan execRequestMsg, 1),
+			askUserChan:              make(chan askUserRequestMsg, 1),
+			iterationChan:            make(chan iterationMsg, 10),
 		},
 		config: AppConfig{
 			agent:                   agent,
 			availableModels:         availableModels,
 			markdownRenderer:        markdownRenderer,
 			requireToolConfirmation: requireConfirmation,
+			toolPolicy:              toolPolicy,
 			enableThinkingMode:      enableThinking,
+			compactMode:             compactMode,
+			diffContextLines:        prefs.GetDiffContextLines(),
+			allowOutsideReads:       prefs != nil && prefs.AllowOutsideReads,
+			expandThoughts:          prefs != nil && prefs.ExpandThoughts,
+			expandToolMessages:      prefs != nil && prefs.ExpandToolMessages,
+			persistAutoApprovals:    prefs != nil && prefs.PersistAutoApprovals,
+			maxStreamedMessageChars: maxStreamedMessageChars,
+			tokenWarningPercent:     tokenWarningPercent,
+			tokenCriticalPercent:    tokenCriticalPercent,
+			approvedTools:           &toolApprovals{tools: make(map[string]bool)},
+		},
+		tabs: TabState{
+			sessions: []session{{agent: agent}},
 		},
 		messages: []message{}, // Start with empty messages
 	}
 
+	// Restore persisted auto-approvals, but only ever for read-only tools
+	if prefs != nil && prefs.PersistAutoApprovals {
+		for _, name := range prefs.AutoApprovedTools {
+			if agent.IsReadOnlyTool(name) {
+				m.config.approvedTools.tools[name] = true
+			}
+		}
+	}
+
+	// Show the transcript of a session resumed on startup
+	if len(agent.Conversation) > 0 {
+		m.messages = append(m.transcriptMessages(), message{
+			mType:   agentMessage,
+			content: fmt.Sprintf("📂 Resumed session (%d messages)", len(agent.Conversation)),
+		})
+	}
+
 	// Don't set initial content - wait for window size
 	// m.ui.viewport.SetContent(m.renderConversation())
 
@@ -193,9 +305,24 @@ func (m *model) Init() tea.Cmd {
 		tea.WindowSize(), // Request initial window size first
 		textarea.Blink,
 		m.ui.spinner.Tick,
+		m.fetchModels(),
 	)
 }
 
+// fetchModels asks the API which models are available, for the model selector
+func (m *model) fetchModels() tea.Cmd {
+	client := m.config.agent.Client()
+	if client == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		available, err := models.FetchAvailable(ctx, client.Models)
+		return modelsFetchedMsg{ids: models.IDs(available), err: err}
+	}
+}
+
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		tiCmd tea.Cmd
@@ -203,8 +330,28 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		sCmd  tea.Cmd
 	)
 
+	// Editing a tool's arguments, the completion popup, then recalling an earlier
+	// input, take the keys they use before the input and conversation see them
+	if key, ok := msg.(tea.KeyMsg); ok {
+		if cmd, used := m.handleToolArgsEditKey(key); used {
+			return m, cmd
+		}
+		if cmd, used := m.handleCompletionKey(key); used {
+			return m, cmd
+		}
+		if m.handleHistoryKey(key) {
+			return m, nil
+		}
+	}
+
 	// Update sub-components
+	input := m.ui.textarea.Value()
 	m.ui.textarea, tiCmd = m.ui.textarea.Update(msg)
+	if m.ui.textarea.Value() != input {
+		// Typing picks from the new matches, and brings back a hidden popup
+		m.ui.completionIndex = 0
+		m.ui.completionDismissed = false
+	}
 	m.ui.viewport, vpCmd = m.ui.viewport.Update(msg)
 	m.ui.spinner, sCmd = m.ui.spinner.Update(msg)
 
@@ -220,14 +367,43 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.handleStreamStart(msg)
 	case toolMessageMsg:
 		return m, m.handleToolMessage(msg)
-	case thoughtMessageMsg:
-		return m, m.handleThoughtMessage(msg)
+	case thoughtChunkMsg:
+		return m, m.handleThoughtChunk(msg)
 	case streamChunkMsg:
 		return m, m.handleStreamChunk(msg)
+	case toolOutputMsg:
+		m.ui.toolOutput = string(msg)
+		return m, waitForStreamEvent(m.stream.streamEventChan)
 	case streamCompleteMsg:
 		return m, m.handleStreamComplete(msg)
 	case toolConfirmationRequestMsg:
 		return m, m.handleToolConfirmationRequest(msg)
+	case execRequestMsg:
+		return m, m.handleExecRequest(msg)
+	case askUserRequestMsg:
+		return m, m.handleAskUserRequest(msg)
+	case tokenCountMsg:
+		m.handleTokenCount(msg)
+		return m, nil
+	case modelsFetchedMsg:
+		// The static list stays in place if the API couldn't be reached
+		if msg.err == nil {
+			m.config.availableModels = msg.ids
+			for i, name := range msg.ids {
+				if name == m.config.agent.Model {
+					m.ui.selectedModelIndex = i
+				}
+			}
+		}
+		return m, nil
+	case clearStatusNoteMsg:
+		if int(msg) == m.ui.statusNoteID {
+			m.ui.statusNote = ""
+		}
+		return m, nil
+	case iterationMsg:
+		m.ui.iteration = int(msg)
+		return m, waitForIteration(m.stream.iterationChan)
 	case error:
 		m.err = msg
 		return m, nil
@@ -248,7 +424,7 @@ func (m *model) handleWindowResize(msg tea.WindowSizeMsg) tea.Cmd {
 	// Update markdown renderer width to match viewport width
 	if m.config.markdownRenderer != nil {
 		newRenderer, err := glamour.NewTermRenderer(
-			glamour.WithStylePath("dark"),
+			glamour.WithStylePath(activeTheme.Glamour),
 			glamour.WithWordWrap(m.ui.width-8), // Account for "Agent: " prefix and padding
 		)
 		if err == nil {
@@ -286,23 +462,43 @@ func (m *model) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 	if m.ui.modelSelectionMode {
 		return m.handleModelSelectionKey(msg)
 	}
+	if m.ui.settingsMode {
+		return m.handleSettingsKey(msg)
+	}
+
+	// Esc while answering a question declines to answer instead of quitting
+	if m.ui.askUserMode && msg.Type == tea.KeyEsc {
+		m.ui.textarea.Reset()
+		return m.answerUserQuestion()
+	}
+
+	// Search takes the keyboard until it's closed, except for quitting
+	if m.searchActive() && msg.Type != tea.KeyCtrlC {
+		return m.handleSearchKey(msg)
+	}
 
 	// Handle normal mode keys
 	switch msg.Type {
 	case tea.KeyCtrlC:
-		// Cancel any ongoing streaming
+		// The first Ctrl+C interrupts a response; another one quits
+		now := time.Now()
+		if !ctrlCQuits(m.ui.showSpinner && m.stream.cancelFunc != nil, m.ui.interruptedAt, now) {
+			m.ui.interruptedAt = now
+			m.interruptResponse()
+			return m.flashStatus("⏹ Response interrupted • Ctrl+C again to quit")
+		}
 		if m.stream.cancelFunc != nil {
 			m.stream.cancelFunc()
 		}
+		m.autoSaveSession()
 		return tea.Quit
 	case tea.KeyEsc:
 		// If streaming, cancel it; otherwise quit
 		if m.ui.showSpinner && m.stream.cancelFunc != nil {
-			m.stream.cancelFunc()
-			m.ui.showSpinner = false
-			m.ui.textarea.Focus()
+			m.interruptResponse()
 			return nil
 		}
+		m.autoSaveSession()
 		return tea.Quit
 	case tea.KeyF2:
 		return m.toggleModelSelection()
@@ -310,9 +506,38 @@ func (m *model) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 		return m.toggleToolConfirmation()
 	case tea.KeyF4:
 		return m.toggleThinkingMode()
+	case tea.KeyF5:
+		return m.toggleStatusBar()
+	case tea.KeyF6:
+		return m.toggleCompactMode()
+	case tea.KeyF7:
+		return m.newTab()
+	case tea.KeyF8:
+		return m.switchTab((m.tabs.active + 1) % len(m.tabs.sessions))
+	case tea.KeyF9:
+		m.exportMarkdown("")
+		return nil
+	case tea.KeyF10:
+		return m.cycleTheme()
+	case tea.KeyF12:
+		return m.toggleDryRun()
+	case tea.KeyCtrlY:
+		return m.copyLastResponse()
+	case tea.KeyCtrlR:
+		return m.regenerateResponse()
 	case tea.KeyCtrlT:
 		return m.toggleCollapsedMessages()
+	case tea.KeyCtrlS:
+		// Stop the tool loop after the current iteration without discarding its work
+		if m.ui.showSpinner && !m.ui.stopRequested {
+			m.config.agent.RequestStop()
+			m.ui.stopRequested = true
+		}
+		return nil
 	case tea.KeyEnter:
+		if m.ui.askUserMode {
+			return m.answerUserQuestion()
+		}
 		return m.handleUserInput()
 	}
 
@@ -324,18 +549,145 @@ func (m *model) handleToolConfirmationKey(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
 	case "y", "Y":
 		// User confirmed
-		m.stream.confirmationResponseChan <- true
+		m.stream.confirmationResponseChan <- toolConfirmationResponse{approved: true}
+		m.ui.toolConfirmationMode = false
+		m.ui.textarea.Focus()
+	case "a", "A":
+		// User confirmed and wants to skip the prompt for this tool from now on
+		m.approveTool(m.ui.toolConfirmationName)
+		m.stream.confirmationResponseChan <- toolConfirmationResponse{approved: true}
 		m.ui.toolConfirmationMode = false
 		m.ui.textarea.Focus()
 	case "n", "N", "esc":
 		// User denied
-		m.stream.confirmationResponseChan <- false
+		m.stream.confirmationResponseChan <- toolConfirmationResponse{}
 		m.ui.toolConfirmationMode = false
 		m.ui.textarea.Focus()
+	case "e", "E":
+		return m.editToolArgs()
+	case "v", "V":
+		// Switch between the preview and the full arguments
+		m.ui.toolConfirmationExpanded = !m.ui.toolConfirmationExpanded
+		m.ui.toolConfirmationScroll = 0
+	case "up":
+		m.ui.toolConfirmationScroll = max(m.ui.toolConfirmationScroll-1, 0)
+	case "down":
+		_, height := m.toolConfirmationLayout()
+		m.ui.toolConfirmationScroll = min(m.ui.toolConfirmationScroll+1, max(len(m.toolConfirmationArgLines())-height, 0))
 	}
 	return nil
 }
 
+// editToolArgs puts the pending tool call's arguments in the input box as JSON for the
+// user to correct before running it
+func (m *model) editToolArgs() tea.Cmd {
+	argsJSON, _ := json.MarshalIndent(m.ui.toolConfirmationArgs, "", "  ")
+	m.ui.toolConfirmationMode = false
+	m.ui.toolArgsEditMode = true
+	m.ui.toolArgsDraft = m.ui.textarea.Value()
+	m.ui.textarea.SetValue(string(argsJSON))
+	m.ui.textarea.Placeholder = "Edit the tool arguments as JSON..."
+	m.ui.textarea.Focus()
+	m.stream.confirmationResponseChan <- toolConfirmationResponse{editing: true}
+	return nil
+}
+
+// handleToolArgsEditKey runs the tool with the edited arguments on Enter, or goes back
+// to the confirmation on Esc, and reports whether it used the key
+func (m *model) handleToolArgsEditKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if !m.ui.toolArgsEditMode {
+		return nil, false
+	}
+	switch msg.Type {
+	case tea.KeyEnter:
+		args, err := parseToolArgs(m.ui.textarea.Value())
+		if err != nil {
+			m.addSystemMessage(fmt.Sprintf("Invalid arguments for %s: %v\nFix them and press Enter, or press Esc to go back.", m.ui.toolConfirmationName, err), true)
+			return nil, true
+		}
+		m.endToolArgsEdit()
+		m.stream.confirmationResponseChan <- toolConfirmationResponse{approved: true, args: args}
+		return nil, true
+	case tea.KeyEsc:
+		m.endToolArgsEdit()
+		m.ui.toolConfirmationMode = true
+		m.ui.textarea.Blur()
+		return nil, true
+	}
+	return nil, false
+}
+
+// endToolArgsEdit gives the input box back with whatever the user had typed in it
+func (m *model) endToolArgsEdit() {
+	m.ui.toolArgsEditMode = false
+	m.ui.textarea.SetValue(m.ui.toolArgsDraft)
+	m.ui.toolArgsDraft = ""
+	m.ui.textarea.Placeholder = "Enter your message here..."
+}
+
+// parseToolArgs reads tool arguments the user edited, which must be a JSON object
+func parseToolArgs(text string) (map[string]interface{}, error) {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &args); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		return nil, fmt.Errorf("arguments must be a JSON object")
+	}
+	return args, nil
+}
+
+// quitConfirmWindow is how soon after interrupting a response with Ctrl+C another
+// Ctrl+C quits, even if the response hasn't wound down yet
+const quitConfirmWindow = 2 * time.Second
+
+// ctrlCQuits reports whether Ctrl+C should quit rather than interrupt: when no response
+// is streaming, or within quitConfirmWindow of the Ctrl+C that interrupted one
+func ctrlCQuits(streaming bool, interruptedAt, now time.Time) bool {
+	return !streaming || (!interruptedAt.IsZero() && now.Sub(interruptedAt) <= quitConfirmWindow)
+}
+
+// interruptResponse cancels the response in progress and hands the input back
+func (m *model) interruptResponse() {
+	if m.ui.toolArgsEditMode {
+		m.endToolArgsEdit()
+	}
+	m.stream.cancelFunc()
+	m.ui.showSpinner = false
+	m.ui.textarea.Focus()
+}
+
+// isToolApproved reports whether the user chose to always allow a tool
+func (m *model) isToolApproved(name string) bool {
+	m.config.approvedTools.mu.Lock()
+	defer m.config.approvedTools.mu.Unlock()
+	return m.config.approvedTools.tools[name]
+}
+
+// approveTool always allows a tool for this session, and across sessions when the
+// user opted in and the tool is read-only. Mutating tools are never persisted.
+func (m *model) approveTool(name string) {
+	m.config.approvedTools.mu.Lock()
+	m.config.approvedTools.tools[name] = true
+	m.config.approvedTools.mu.Unlock()
+
+	if !m.config.persistAutoApprovals || !m.config.agent.IsReadOnlyTool(name) {
+		return
+	}
+
+	prefs, _ := config.LoadPreferences()
+	if prefs == nil {
+		prefs = &config.UserPreferences{}
+	}
+	for _, approved := range prefs.AutoApprovedTools {
+		if approved == name {
+			return
+		}
+	}
+	prefs.AutoApprovedTools = append(prefs.AutoApprovedTools, name)
+	config.SavePreferences(prefs)
+}
+
 // handleModelSelectionKey handles keys in model selection mode
 func (m *model) handleModelSelectionKey(msg tea.KeyMsg) tea.Cmd {
 	switch msg.Type {
@@ -424,6 +776,70 @@ func (m *model) toggleThinkingMode() tea.Cmd {
 	return nil
 }
 
+// toggleStatusBar shows or hides the status bar
+func (m *model) toggleStatusBar() tea.Cmd {
+	m.ui.showStatusBar = !m.ui.showStatusBar
+
+	// Save preference
+	prefs, _ := config.LoadPreferences()
+	if prefs == nil {
+		prefs = &config.UserPreferences{}
+	}
+	prefs.HideStatusBar = !m.ui.showStatusBar
+	config.SavePreferences(prefs)
+
+	return m.handleWindowResize(tea.WindowSizeMsg{Width: m.ui.width, Height: m.ui.height})
+}
+
+// toggleCompactMode toggles reduced padding and margins on message cards
+func (m *model) toggleCompactMode() tea.Cmd {
+	m.config.compactMode = !m.config.compactMode
+	applyCompactMode(m.config.compactMode)
+
+	// Save preference
+	prefs, _ := config.LoadPreferences()
+	if prefs == nil {
+		prefs = &config.UserPreferences{}
+	}
+	prefs.CompactMode = m.config.compactMode
+	config.SavePreferences(prefs)
+
+	return m.handleWindowResize(tea.WindowSizeMsg{Width: m.ui.width, Height: m.ui.height})
+}
+
+// toggleDryRun switches whether tools that change files only describe the change.
+// It's deliberately not saved, so every session starts making real changes.
+func (m *model) toggleDryRun() tea.Cmd {
+	m.config.agent.SetDryRun(!m.config.agent.DryRun())
+
+	content := "🧪 Dry run enabled: file changes, deletions and shell commands are previewed, not made"
+	if !m.config.agent.DryRun() {
+		content = "🧪 Dry run disabled: tools change files again"
+	}
+	m.addSystemMessage(content, false)
+	m.ui.viewport.GotoBottom()
+	return nil
+}
+
+// cycleTheme switches to the next built-in theme and saves the choice
+func (m *model) cycleTheme() tea.Cmd {
+	applyTheme(nextTheme())
+	m.ui.spinner.Style = spinnerStyle
+
+	// Save preference
+	prefs, _ := config.LoadPreferences()
+	if prefs == nil {
+		prefs = &config.UserPreferences{}
+	}
+	prefs.SelectedTheme = activeTheme.Name
+	config.SavePreferences(prefs)
+
+	return tea.Batch(
+		m.handleWindowResize(tea.WindowSizeMsg{Width: m.ui.width, Height: m.ui.height}),
+		m.flashStatus("🎨 Theme: "+activeTheme.Name),
+	)
+}
+
 // toggleCollapsedMessages toggles collapsed state of tool and thought messages
 func (m *model) toggleCollapsedMessages() tea.Cmd {
 	var anyExpanded bool
@@ -451,29 +867,78 @@ func (m *model) handleUserInput() tea.Cmd {
 		return nil
 	}
 
-	m.messages = append(m.messages, message{mType: userMessage, content: userInput})
-	m.ui.viewport.SetContent(m.renderConversation())
 	m.ui.textarea.Reset()
+	m.ui.history.add(userInput)
+	if strings.HasPrefix(userInput, "/") {
+		return m.handleSlashCommand(userInput)
+	}
+
+	return m.sendMessage(userInput)
+}
+
+// sendMessage shows a user message and starts streaming the agent's response to it
+func (m *model) sendMessage(userInput string) tea.Cmd {
+	displayed, prompt := userInput, userInput
+	if len(m.ui.pendingToolRefs) > 0 {
+		var quoted, labels []string
+		for _, number := range m.ui.pendingToolRefs {
+			if toolMsg := m.findToolMessage(number); toolMsg != nil {
+				quoted = append(quoted, quoteToolMessage(*toolMsg))
+				labels = append(labels, fmt.Sprintf("#%d", number))
+			}
+		}
+		m.ui.pendingToolRefs = nil
+		if len(quoted) > 0 {
+			prompt = "Regarding the output of these earlier tool calls:\n\n" + strings.Join(quoted, "\n\n") + "\n\n" + userInput
+			displayed = userInput + "\n\n📌 Referencing tool call " + strings.Join(labels, ", ")
+		}
+	}
+
+	var images []*genai.Part
+	if paths := m.ui.pendingImages; len(paths) > 0 {
+		// The model may have been switched since the images were attached
+		if !models.SupportsVision(m.config.agent.Model) {
+			m.ui.textarea.SetValue(userInput)
+			m.addSystemMessage(fmt.Sprintf("🖼 %s doesn't accept images; switch models or use /image clear", m.config.agent.Model), true)
+			return nil
+		}
+		for _, path := range paths {
+			image, err := agent.LoadImage(path)
+			if err != nil {
+				m.ui.textarea.SetValue(userInput)
+				m.addSystemMessage(fmt.Sprintf("🖼 Failed to attach image: %v", err), true)
+				return nil
+			}
+			images = append(images, image)
+		}
+		m.ui.pendingImages = nil
+		displayed += "\n\n🖼 Attached " + strings.Join(paths, ", ")
+	}
+
+	m.messages = append(m.messages, message{mType: userMessage, content: displayed})
+	m.ui.viewport.SetContent(m.renderConversation())
 	m.ui.showSpinner = true
 	m.ui.textarea.Blur()
 
 	// Reset the flag for the new conversation turn
 	m.stream.streamingWasInterrupted = false
 
-	return tea.Batch(m.ui.spinner.Tick, m.streamingCommand(userInput))
+	return tea.Batch(m.ui.spinner.Tick, m.streamingCommand(prompt, images))
 }
 
 // selectModel handles model selection
 func (m *model) selectModel() tea.Cmd {
-	// Update the agent's model
-	m.config.agent.Model = m.config.availableModels[m.ui.selectedModelIndex]
+	// Update the agent's model, noting the switch in the conversation
+	m.config.agent.SwitchModel(m.config.availableModels[m.ui.selectedModelIndex])
 	m.ui.modelSelectionMode = false
 	m.ui.textarea.Focus()
 
-	// Save the selected model to preferences
-	prefs := &config.UserPreferences{
-		SelectedModel: m.config.agent.Model,
+	// Save the selected model to preferences, keeping the other saved settings
+	prefs, _ := config.LoadPreferences()
+	if prefs == nil {
+		prefs = &config.UserPreferences{}
 	}
+	prefs.SelectedModel = m.config.agent.Model
 	if err := config.SavePreferences(prefs); err != nil {
 		// Log error but don't fail the operation
 		m.messages = append(m.messages, message{
@@ -505,115 +970,133 @@ func (m *model) handleStreamStart(msg streamStartMsg) tea.Cmd {
 	ctx, cancel := context.WithCancel(context.Background())
 	m.stream.cancelFunc = cancel
 
-	// Start the real-time streaming process
-	go func() {
-		defer cancel() // Ensure cleanup
+	// Let tools hand the terminal to interactive processes such as editors
+	ctx = agent.WithProcessRunner(ctx, func(cmd *exec.Cmd) error {
+		done := make(chan error, 1)
+		select {
+		case m.stream.execRequestChan <- execRequestMsg{cmd: cmd, done: done}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 
-		// Message queue to handle ordering
-		messageQueue := make([]interface{}, 0)
-		queueMutex := &sync.Mutex{}
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 
-		// Helper to safely queue messages
-		queueMessage := func(msg interface{}) {
-			queueMutex.Lock()
-			messageQueue = append(messageQueue, msg)
-			queueMutex.Unlock()
+	// Track tool loop progress for the spinner
+	m.ui.iteration = 0
+	m.ui.stopRequested = false
+	m.ui.toolOutput = ""
+	ctx = agent.WithIterationObserver(ctx, func(iteration int) {
+		select {
+		case m.stream.iterationChan <- iterationMsg(iteration):
+		case <-ctx.Done():
 		}
+	})
 
-		// Helper to send queued messages
-		sendQueuedMessages := func() {
-			queueMutex.Lock()
-			defer queueMutex.Unlock()
+	// Show running shell commands' latest output under the spinner
+	ctx = agent.WithOutputObserver(ctx, func(line string) {
+		sendStreamEvent(ctx, m.stream.streamEventChan, toolOutputMsg(line))
+	})
 
-			for _, qMsg := range messageQueue {
-				switch msg := qMsg.(type) {
-				case streamChunkMsg:
-					select {
-					case m.stream.streamChunkChan <- msg:
-					case <-ctx.Done():
-						return
-					}
-				case toolMessageMsg:
-					select {
-					case m.stream.toolMessageChan <- msg:
-					case <-ctx.Done():
-						return
-					}
-				case thoughtMessageMsg:
-					select {
-					case m.stream.thoughtMessageChan <- msg:
-					case <-ctx.Done():
-						return
-					}
-				}
-			}
-			messageQueue = messageQueue[:0] // Clear queue
+	// Let the ask_user tool pause for a typed reply
+	ctx = agent.WithUserQuestioner(ctx, func(question string) (string, error) {
+		responseChan := make(chan string, 1)
+		timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+
+		select {
+		case m.stream.askUserChan <- askUserRequestMsg{question: question, response: responseChan}:
+		case <-timeoutCtx.Done():
+			return "", fmt.Errorf("timeout waiting to send question")
+		}
+
+		select {
+		case answer := <-responseChan:
+			return answer, nil
+		case <-timeoutCtx.Done():
+			return "", fmt.Errorf("timeout waiting for user answer")
 		}
+	})
+
+	// Start the real-time streaming process
+	go func() {
+		defer cancel() // Ensure cleanup
 
 		// Call the agent's ProcessMessage for streaming with tool callback
-		response, err := m.config.agent.ProcessMessage(ctx, msg.userInput,
+		response, err := m.config.agent.ProcessMessage(ctx, msg.userInput, msg.images,
 			// Text callback for streaming chunks
 			func(chunk string) error {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-					queueMessage(streamChunkMsg(chunk))
-					sendQueuedMessages()
-					return nil
-				}
+				return sendStreamEvent(ctx, m.stream.streamEventChan, streamChunkMsg(chunk))
 			},
 			// Tool callback for immediate tool message display
 			func(toolMsg agent.Message) error {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-					queueMessage(toolMessageMsg(toolMsg))
-					sendQueuedMessages()
-					return nil
-				}
+				return sendStreamEvent(ctx, m.stream.streamEventChan, toolMessageMsg(toolMsg))
 			},
-			// Thought callback for immediate thought message display
-			func(thoughtMsg agent.Message) error {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-					queueMessage(thoughtMessageMsg(thoughtMsg))
-					sendQueuedMessages()
-					return nil
-				}
+			// Thought callback for streaming thought chunks
+			func(chunk string) error {
+				return sendStreamEvent(ctx, m.stream.streamEventChan, thoughtChunkMsg(chunk))
 			},
 			// Tool confirmation callback
-			func(toolName string, args map[string]interface{}) (bool, error) {
-				// If confirmation is not required, auto-approve
-				if !m.config.requireToolConfirmation {
-					return true, nil
+			func(toolName string, args map[string]interface{}) (bool, map[string]interface{}, error) {
+				// Reads outside the workspace always ask unless explicitly allowed
+				var note string
+				if outsidePath, outside := m.config.agent.ReadOutsideWorkspace(toolName, args); outside && !m.config.allowOutsideReads {
+					note = fmt.Sprintf("Reads outside the workspace: %s", outsidePath)
 				}
 
-				// Create a response channel with timeout
-				responseChan := make(chan bool, 1)
-				timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-				defer cancel()
+				// If confirmation is not required, by the tool's policy or the global
+				// setting, auto-approve
+				if !m.config.toolPolicy.RequiresConfirmation(toolName, m.config.requireToolConfirmation) && note == "" {
+					return true, nil, nil
+				}
+
+				// Tools the user chose to always allow skip the prompt
+				if note == "" && m.isToolApproved(toolName) {
+					return true, nil, nil
+				}
+
+				// Show editing tools' changes as a diff; other tools have no preview
+				preview, _ := m.config.agent.PreviewTool(ctx, toolName, args, m.config.diffContextLines)
+
+				// Create a response channel with timeout; it holds the note that editing
+				// started as well as the answer, so the UI never blocks on it
+				responseChan := make(chan toolConfirmationResponse, 2)
+				timeout := time.After(30 * time.Second)
 
 				// Send confirmation request to the UI
 				select {
 				case m.stream.toolConfirmationChan <- toolConfirmationRequestMsg{
 					toolName: toolName,
 					args:     args,
+					note:     note,
+					preview:  preview,
 					response: responseChan,
 				}:
-				case <-timeoutCtx.Done():
-					return false, fmt.Errorf("timeout waiting to send confirmation request")
+				case <-ctx.Done():
+					return false, nil, ctx.Err() // the response was cancelled
+				case <-timeout:
+					return false, nil, fmt.Errorf("timeout waiting to send confirmation request")
 				}
 
-				// Wait for user response with timeout
-				select {
-				case confirmed := <-responseChan:
-					return confirmed, nil
-				case <-timeoutCtx.Done():
-					return false, fmt.Errorf("timeout waiting for user confirmation")
+				// Wait for user response with timeout, allowing longer to edit the arguments
+				for {
+					select {
+					case response := <-responseChan:
+						if response.editing {
+							timeout = time.After(5 * time.Minute)
+							continue
+						}
+						return response.approved, response.args, nil
+					case <-ctx.Done():
+						return false, nil, ctx.Err() // the response was cancelled
+					case <-timeout:
+						return false, nil, fmt.Errorf("timeout waiting for user confirmation")
+					}
 				}
 			},
 			m.config.enableThinkingMode) // Pass thinking mode preference
@@ -622,41 +1105,53 @@ func (m *model) handleStreamStart(msg streamStartMsg) tea.Cmd {
 			// Check if it was a cancellation
 			if errors.Is(err, context.Canceled) {
 				// User cancelled, don't show error
-				m.stream.streamCompleteChan <- streamCompleteMsg{
+				m.stream.streamEventChan <- streamCompleteMsg{
 					finalMessages: []agent.Message{},
+					cancelled:     true,
 				}
 			} else {
-				m.stream.streamCompleteChan <- streamCompleteMsg{
+				content := fmt.Sprintf("Error: %v", err)
+				var rateLimitErr *agent.RateLimitError
+				if errors.As(err, &rateLimitErr) {
+					content = "⏳ " + rateLimitErr.Error()
+				}
+				m.stream.streamEventChan <- streamCompleteMsg{
 					finalMessages: []agent.Message{
-						{Type: agent.AgentMessage, Content: fmt.Sprintf("Error: %v", err), IsError: true},
+						{Type: agent.AgentMessage, Content: content, IsError: true},
 					},
 				}
 			}
 			return
 		}
 
-		// Send completion with all messages
-		m.stream.streamCompleteChan <- streamCompleteMsg{finalMessages: response}
+		// Send completion with all messages. It's sent even when cancelled, after
+		// everything already queued, so the UI always leaves the streaming state.
+		m.stream.streamEventChan <- streamCompleteMsg{finalMessages: response}
 	}()
 
 	// Start listening for chunks, tool messages, and completion
 	return tea.Batch(
-		waitForStreamChunk(m.stream.streamChunkChan),
-		waitForToolMessage(m.stream.toolMessageChan),
-		waitForThoughtMessage(m.stream.thoughtMessageChan),
-		waitForStreamComplete(m.stream.streamCompleteChan),
+		waitForStreamEvent(m.stream.streamEventChan),
 		waitForToolConfirmation(m.stream.toolConfirmationChan),
+		waitForExecRequest(m.stream.execRequestChan),
+		waitForAskUser(m.stream.askUserChan),
+		waitForIteration(m.stream.iterationChan),
 	)
 }
 
 // handleToolMessage handles incoming tool messages
 func (m *model) handleToolMessage(msg toolMessageMsg) tea.Cmd {
+	m.ui.toolOutput = ""
+	m.endThoughtStream()
+
 	// Defer expensive rendering to avoid blocking the event loop
 	newToolMsg := message{
 		mType:       toolMessage,
 		content:     msg.Content,
-		isCollapsed: true,
+		isCollapsed: !m.config.expandToolMessages,
 		isError:     msg.IsError,
+		toolNumber:  m.countToolMessages() + 1,
+		summary:     summarizeToolCall(agent.Message(msg)),
 	}
 
 	// Mark that streaming was interrupted only if we have an active streaming message
@@ -683,52 +1178,67 @@ func (m *model) handleToolMessage(msg toolMessageMsg) tea.Cmd {
 			m.ui.viewport.GotoBottom()
 			return nil
 		},
-		waitForToolMessage(m.stream.toolMessageChan),
+		waitForStreamEvent(m.stream.streamEventChan),
 	)
 }
 
-// handleThoughtMessage handles incoming thought messages
-func (m *model) handleThoughtMessage(msg thoughtMessageMsg) tea.Cmd {
-	// Handle thought message immediately
-	newThoughtMsg := message{
-		mType:       thoughtMessage,
-		content:     msg.Content,
-		isCollapsed: true,
-		isError:     msg.IsError,
-	}
+// handleThoughtChunk adds a chunk of the model's thinking to the thought being
+// streamed, starting a new thought message if there isn't one
+func (m *model) handleThoughtChunk(msg thoughtChunkMsg) tea.Cmd {
+	if m.stream.thoughtMsgIndex == -1 || m.stream.thoughtMsgIndex >= len(m.messages) {
+		newThoughtMsg := message{
+			mType:       thoughtMessage,
+			content:     "💭 Thinking: ",
+			isCollapsed: !m.config.expandThoughts,
+			isStreaming: true,
+		}
 
-	// Mark that streaming was interrupted only if we have an active streaming message
-	if m.stream.streamingMsg != nil && m.stream.streamingMsg.content != "" {
-		m.stream.streamingWasInterrupted = true
-	}
+		// Mark that streaming was interrupted only if we have an active streaming message
+		if m.stream.streamingMsg != nil && m.stream.streamingMsg.content != "" {
+			m.stream.streamingWasInterrupted = true
+		}
 
-	// If streaming has started, insert the thought message before the streaming message
-	if m.stream.streamingMsgIndex != -1 {
-		// Insert at the correct position
-		m.messages = append(m.messages[:m.stream.streamingMsgIndex], append([]message{newThoughtMsg}, m.messages[m.stream.streamingMsgIndex:]...)...)
-		// Update the index of the streaming message
-		m.stream.streamingMsgIndex++
-	} else {
-		// Otherwise, just append
-		m.messages = append(m.messages, newThoughtMsg)
+		// If streaming has started, insert the thought message before the streaming message
+		if m.stream.streamingMsgIndex != -1 {
+			m.messages = append(m.messages[:m.stream.streamingMsgIndex], append([]message{newThoughtMsg}, m.messages[m.stream.streamingMsgIndex:]...)...)
+			m.stream.thoughtMsgIndex = m.stream.streamingMsgIndex
+			m.stream.streamingMsgIndex++
+		} else {
+			m.messages = append(m.messages, newThoughtMsg)
+			m.stream.thoughtMsgIndex = len(m.messages) - 1
+		}
 	}
 
-	// Batch UI updates
+	// Update the content in place so expanding or collapsing the thought mid-stream sticks
+	m.messages[m.stream.thoughtMsgIndex].content += string(msg)
+
+	// Batch frequent updates, as for text chunks
 	return tea.Batch(
-		func() tea.Msg {
+		tea.Tick(time.Millisecond*50, func(t time.Time) tea.Msg {
 			m.ui.viewport.SetContent(m.renderConversation())
 			m.ui.viewport.GotoBottom()
 			return nil
-		},
-		waitForThoughtMessage(m.stream.thoughtMessageChan),
+		}),
+		waitForStreamEvent(m.stream.streamEventChan),
 	)
 }
 
+// endThoughtStream finishes the thought being streamed, once text, a tool call or the
+// end of the response follows it
+func (m *model) endThoughtStream() {
+	if m.stream.thoughtMsgIndex >= 0 && m.stream.thoughtMsgIndex < len(m.messages) {
+		m.messages[m.stream.thoughtMsgIndex].isStreaming = false
+	}
+	m.stream.thoughtMsgIndex = -1
+}
+
 // handleStreamChunk handles incoming stream chunks
 func (m *model) handleStreamChunk(msg streamChunkMsg) tea.Cmd {
+	m.endThoughtStream()
+
 	// Create streaming message if it doesn't exist yet
 	if m.stream.streamingMsg == nil {
-		m.stream.streamingMsg = &message{mType: agentMessage, content: "", isStreaming: true}
+		m.stream.streamingMsg = &message{mType: agentMessage, content: "", isStreaming: true, isPlan: m.config.agent.PlanMode()}
 		m.messages = append(m.messages, *m.stream.streamingMsg)
 		m.stream.streamingMsgIndex = len(m.messages) - 1 // Store the actual index
 	}
@@ -745,6 +1255,10 @@ func (m *model) handleStreamChunk(msg streamChunkMsg) tea.Cmd {
 		if m.stream.streamingMsgIndex < len(m.messages) {
 			m.messages[m.stream.streamingMsgIndex] = *m.stream.streamingMsg
 		}
+
+		if limit := m.config.maxStreamedMessageChars; limit > 0 && len(m.stream.streamingMsg.content) > limit {
+			m.splitStreamingMessage(limit)
+		}
 	}
 
 	// Batch frequent updates to avoid overwhelming the renderer
@@ -755,22 +1269,65 @@ func (m *model) handleStreamChunk(msg streamChunkMsg) tea.Cmd {
 			m.ui.viewport.GotoBottom()
 			return nil
 		}),
-		waitForStreamChunk(m.stream.streamChunkChan),
+		waitForStreamEvent(m.stream.streamEventChan),
 	)
 }
 
+// splitStreamingMessage finalizes the streaming message at the last line break past half
+// the limit and continues the rest in a new message, reopening any code block it cut
+func (m *model) splitStreamingMessage(limit int) {
+	content := m.stream.streamingMsg.content
+	cut := strings.LastIndex(content, "\n")
+	if cut < limit/2 {
+		// No good break yet; wait for more of the line
+		return
+	}
+
+	head, rest := content[:cut], content[cut+1:]
+	if hasUnclosedCodeFence(head) {
+		fenceStart := strings.LastIndex(head, "```")
+		opener := head[fenceStart:]
+		if end := strings.Index(opener, "\n"); end != -1 {
+			opener = opener[:end]
+		}
+		head += "\n```"
+		rest = opener + "\n" + rest
+	}
+
+	m.stream.streamingMsg.content = head
+	m.stream.streamingMsg.isStreaming = false
+	if m.stream.streamingMsgIndex < len(m.messages) {
+		m.messages[m.stream.streamingMsgIndex] = *m.stream.streamingMsg
+	}
+
+	m.stream.streamingMsg = &message{mType: agentMessage, content: rest, isStreaming: true, isPlan: m.stream.streamingMsg.isPlan}
+	m.messages = append(m.messages, *m.stream.streamingMsg)
+	m.stream.streamingMsgIndex = len(m.messages) - 1
+}
+
 // handleStreamComplete handles stream completion
 func (m *model) handleStreamComplete(msg streamCompleteMsg) tea.Cmd {
 	// Handle streaming completion
 	m.ui.showSpinner = false
+	m.ui.iteration = 0
+	m.ui.stopRequested = false
+	m.ui.toolOutput = ""
 	m.ui.textarea.Focus()
+	m.endThoughtStream()
 
-	// Finalize the streaming message
+	// Finalize the streaming message, marking a cancelled one as cut short
 	if m.stream.streamingMsg != nil {
 		m.stream.streamingMsg.isStreaming = false
+		if msg.cancelled && m.stream.streamingMsgIndex >= 0 && m.stream.streamingMsgIndex < len(m.messages) {
+			m.messages[m.stream.streamingMsgIndex].isStreaming = false
+			m.messages[m.stream.streamingMsgIndex].content += " …"
+		}
 		m.stream.streamingMsg = nil
 		m.stream.streamingMsgIndex = -1 // Reset the index
 	}
+	if msg.cancelled {
+		m.messages = append(m.messages, message{mType: agentMessage, content: "⏹ Response cancelled. Its partial output was kept in the conversation."})
+	}
 
 	// Reset the flag
 	m.stream.streamingWasInterrupted = false
@@ -779,9 +1336,6 @@ func (m *model) handleStreamComplete(msg streamCompleteMsg) tea.Cmd {
 	// We only need to process any remaining non-tool messages here
 	for _, agentMsg := range msg.finalMessages {
 		switch agentMsg.Type {
-		case agent.StreamChunk:
-			// Skip - these were already processed during streaming
-			continue
 		case agent.ToolMessage:
 			// Skip - these were already processed via callback
 			continue
@@ -810,12 +1364,74 @@ func (m *model) handleToolConfirmationRequest(msg toolConfirmationRequestMsg) te
 	m.ui.toolConfirmationMode = true
 	m.ui.toolConfirmationName = msg.toolName
 	m.ui.toolConfirmationArgs = msg.args
+	m.ui.toolConfirmationNote = msg.note
+	m.ui.toolConfirmationPreview = msg.preview
+	m.ui.toolConfirmationExpanded = false
+	m.ui.toolConfirmationScroll = 0
 	m.stream.confirmationResponseChan = msg.response
 	m.ui.textarea.Blur()
 	// Continue listening for more confirmation requests
 	return waitForToolConfirmation(m.stream.toolConfirmationChan)
 }
 
+// handleAskUserRequest shows the agent's question and hands the input box to the user
+func (m *model) handleAskUserRequest(msg askUserRequestMsg) tea.Cmd {
+	m.ui.askUserMode = true
+	m.ui.showSpinner = false
+	m.stream.askUserResponseChan = msg.response
+	m.endThoughtStream()
+
+	// Finalize any partial response so text after the answer starts a new message
+	if m.stream.streamingMsg != nil {
+		m.stream.streamingMsg.isStreaming = false
+		if m.stream.streamingMsgIndex < len(m.messages) {
+			m.messages[m.stream.streamingMsgIndex] = *m.stream.streamingMsg
+		}
+		m.stream.streamingMsg = nil
+		m.stream.streamingMsgIndex = -1
+	}
+
+	m.messages = append(m.messages, message{
+		mType:   agentMessage,
+		content: "❓ " + msg.question,
+	})
+	m.ui.textarea.Placeholder = "Type your answer and press Enter..."
+	m.ui.textarea.Focus()
+	m.ui.viewport.SetContent(m.renderConversation())
+	m.ui.viewport.GotoBottom()
+
+	return waitForAskUser(m.stream.askUserChan)
+}
+
+// answerUserQuestion sends the typed reply back to the waiting ask_user tool
+func (m *model) answerUserQuestion() tea.Cmd {
+	answer := m.ui.textarea.Value()
+	m.ui.askUserMode = false
+	m.ui.textarea.Reset()
+	m.ui.textarea.Placeholder = "Enter your message here..."
+	m.ui.textarea.Blur()
+	m.ui.showSpinner = true
+
+	m.messages = append(m.messages, message{mType: userMessage, content: answer, isAnswer: true})
+	m.ui.viewport.SetContent(m.renderConversation())
+	m.ui.viewport.GotoBottom()
+
+	m.stream.askUserResponseChan <- answer
+	return m.ui.spinner.Tick
+}
+
+// handleExecRequest suspends the TUI and hands the terminal to an interactive process
+func (m *model) handleExecRequest(msg execRequestMsg) tea.Cmd {
+	return tea.Batch(
+		tea.ExecProcess(msg.cmd, func(err error) tea.Msg {
+			msg.done <- err
+			return nil
+		}),
+		// The other listeners are still waiting; only this one needs re-arming
+		waitForExecRequest(m.stream.execRequestChan),
+	)
+}
+
 func (m *model) View() string {
 	if m.err != nil {
 		return fmt.Sprintf("Error: %v", m.err)
@@ -831,6 +1447,11 @@ func (m *model) View() string {
 		return m.renderModelSelector(m.renderMainView())
 	}
 
+	// Generation settings overlay
+	if m.ui.settingsMode {
+		return m.renderSettings(m.renderMainView())
+	}
+
 	return m.renderMainView()
 }
 
@@ -839,6 +1460,19 @@ func (m *model) renderMainView() string {
 	if m.ui.showSpinner {
 		// Create a centered spinner with modern styling
 		spinner := m.ui.spinner.View() + " Processing your request..."
+		if m.ui.stopRequested {
+			spinner += fmt.Sprintf(" (stopping after tool iteration %d)", m.ui.iteration)
+		} else if m.ui.iteration > 1 {
+			iteration := fmt.Sprint(m.ui.iteration)
+			if limit := m.config.agent.MaxToolIterations(); limit > 0 {
+				iteration = fmt.Sprintf("%d/%d", m.ui.iteration, limit)
+			}
+			spinner += fmt.Sprintf(" (tool iteration %s • Ctrl+S to stop after this step)", iteration)
+		}
+		if m.ui.toolOutput != "" {
+			output := truncateSummary(strings.ReplaceAll(m.ui.toolOutput, "\t", "    "), max(m.ui.width-16, 10))
+			spinner += "\n" + lipgloss.NewStyle().Foreground(textMuted).Render(output)
+		}
 		taView = textInputStyle.
 			Width(m.ui.width - 4).
 			Render(
@@ -856,7 +1490,7 @@ func (m *model) renderMainView() string {
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
-		m.ui.viewport.View(),
+		m.renderCompletions(m.ui.viewport.View()),
 		taView,
 		m.statusBarView(),
 	)
@@ -872,42 +1506,57 @@ func Start(agent *agent.Agent) {
 }
 
 // streamingCommand creates a command that starts real-time streaming
-func (m model) streamingCommand(userInput string) tea.Cmd {
+func (m model) streamingCommand(userInput string, images []*genai.Part) tea.Cmd {
 	return func() tea.Msg {
-		return streamStartMsg{userInput: userInput}
+		return streamStartMsg{userInput: userInput, images: images}
 	}
 }
 
-// waitForStreamChunk creates a command that waits for the next streaming chunk
-func waitForStreamChunk(ch <-chan streamChunkMsg) tea.Cmd {
+// sendStreamEvent queues a streaming event for the UI. It blocks while the UI is
+// behind rather than dropping the event, and gives up once ctx is cancelled.
+func sendStreamEvent(ctx context.Context, ch chan<- tea.Msg, event tea.Msg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case ch <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitForStreamEvent creates a command that waits for the next streaming event. Each
+// handler waits for the next one in turn, so events are handled in the order sent.
+func waitForStreamEvent(ch <-chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
 		return <-ch
 	}
 }
 
-// waitForToolMessage creates a command that waits for the next tool message
-func waitForToolMessage(ch <-chan toolMessageMsg) tea.Cmd {
+// waitForToolConfirmation creates a command that waits for tool confirmation requests
+func waitForToolConfirmation(ch <-chan toolConfirmationRequestMsg) tea.Cmd {
 	return func() tea.Msg {
 		return <-ch
 	}
 }
 
-// waitForStreamComplete creates a command that waits for streaming completion
-func waitForStreamComplete(ch <-chan streamCompleteMsg) tea.Cmd {
+// waitForExecRequest creates a command that waits for interactive process requests
+func waitForExecRequest(ch <-chan execRequestMsg) tea.Cmd {
 	return func() tea.Msg {
 		return <-ch
 	}
 }
 
-// waitForThoughtMessage creates a command that waits for the next thought message
-func waitForThoughtMessage(ch <-chan thoughtMessageMsg) tea.Cmd {
+// waitForAskUser creates a command that waits for questions from the ask_user tool
+func waitForAskUser(ch <-chan askUserRequestMsg) tea.Cmd {
 	return func() tea.Msg {
 		return <-ch
 	}
 }
 
-// waitForToolConfirmation creates a command that waits for tool confirmation requests
-func waitForToolConfirmation(ch <-chan toolConfirmationRequestMsg) tea.Cmd {
+// waitForIteration creates a command that waits for tool loop iteration updates
+func waitForIteration(ch <-chan iterationMsg) tea.Cmd {
 	return func() tea.Msg {
 		return <-ch
 	}
@@ -919,22 +1568,67 @@ type streamChunkMsg string
 // A message for tool messages during streaming
 type toolMessageMsg agent.Message
 
+// A message carrying a line of output from a running tool
+type toolOutputMsg string
+
 // A message for thought messages during streaming
-type thoughtMessageMsg agent.Message
+type thoughtChunkMsg string
 
 // A message for streaming completion
 type streamCompleteMsg struct {
 	finalMessages []agent.Message
+	cancelled     bool // the user cancelled the response before it finished
 }
 
 // A message for tool confirmation request
 type toolConfirmationRequestMsg struct {
 	toolName string
 	args     map[string]interface{}
-	response chan bool
+	note     string
+	preview  string
+	response chan toolConfirmationResponse
+}
+
+// The user's answer to a tool confirmation request
+type toolConfirmationResponse struct {
+	approved bool
+	args     map[string]interface{} // arguments the user edited the call to use, if any
+	editing  bool                   // the user started editing the arguments; the answer follows
+}
+
+// A message reporting which model/tool iteration the agent is on
+type iterationMsg int
+
+// A message carrying the model IDs the API offers
+type modelsFetchedMsg struct {
+	ids []string
+	err error
+}
+
+// A message that clears the status bar notice with the given ID, unless a newer one replaced it
+type clearStatusNoteMsg int
+
+// A message asking the TUI to run an interactive process in the terminal
+type execRequestMsg struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+// A message for a question the agent wants the user to answer
+type askUserRequestMsg struct {
+	question string
+	response chan string
+}
+
+// A message with the result of a /count request
+type tokenCountMsg struct {
+	tokens int
+	chars  int
+	err    error
 }
 
 // New message types for real-time streaming
 type streamStartMsg struct {
 	userInput string
+	images    []*genai.Part
 }
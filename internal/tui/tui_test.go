@@ -0,0 +1,174 @@
+package tui
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"agent/internal/agent"
+	"agent/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel returns a TUI with no API client, reading and saving preferences
+// under a temporary home directory
+func newTestModel(t *testing.T) *model {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return InitialModel(agent.NewWithConfig(nil, "gemini-2.5-flash", nil, agent.DefaultAgentConfig()))
+}
+
+func TestSelectModelKeepsOtherPreferences(t *testing.T) {
+	m := newTestModel(t)
+	if err := config.SavePreferences(&config.UserPreferences{DeniedCommands: []string{"rm -rf"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.ui.selectedModelIndex = slices.Index(m.config.availableModels, "gemini-2.5-pro")
+	m.selectModel()
+
+	prefs, err := config.LoadPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefs.SelectedModel != "gemini-2.5-pro" {
+		t.Errorf("SelectedModel = %q, want gemini-2.5-pro", prefs.SelectedModel)
+	}
+	if !slices.Equal(prefs.DeniedCommands, []string{"rm -rf"}) {
+		t.Errorf("DeniedCommands = %v, want the saved denylist kept", prefs.DeniedCommands)
+	}
+}
+
+func TestSendStreamEventDeliversEveryChunkInOrder(t *testing.T) {
+	ch := make(chan tea.Msg, 100)
+	const chunks = 1000
+	go func() {
+		for i := range chunks {
+			if err := sendStreamEvent(context.Background(), ch, streamChunkMsg(strconv.Itoa(i))); err != nil {
+				t.Errorf("sendStreamEvent: %v", err)
+				return
+			}
+		}
+	}()
+
+	// Read through the command the UI uses, after letting the channel fill up
+	time.Sleep(10 * time.Millisecond)
+	for i := range chunks {
+		msg := waitForStreamEvent(ch)()
+		if msg != streamChunkMsg(strconv.Itoa(i)) {
+			t.Fatalf("event %d = %v, want chunk %d", i, msg, i)
+		}
+	}
+}
+
+func TestSendStreamEventGivesUpWhenCancelled(t *testing.T) {
+	ch := make(chan tea.Msg, 1)
+	ch <- streamChunkMsg("unread")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	if err := sendStreamEvent(ctx, ch, streamChunkMsg("blocked")); err != context.Canceled {
+		t.Errorf("sendStreamEvent on a full channel = %v, want context.Canceled once cancelled", err)
+	}
+}
+
+func TestCtrlCQuits(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		streaming     bool
+		interruptedAt time.Time
+		want          bool
+	}{
+		{"idle", false, time.Time{}, true},
+		{"idle after an interrupt", false, now.Add(-time.Minute), true},
+		{"first press while streaming", true, time.Time{}, false},
+		{"second press within the window", true, now.Add(-time.Second), true},
+		{"second press at the window's edge", true, now.Add(-quitConfirmWindow), true},
+		{"press long after an earlier interrupt", true, now.Add(-quitConfirmWindow - time.Millisecond), false},
+	}
+	for _, tt := range tests {
+		if got := ctrlCQuits(tt.streaming, tt.interruptedAt, now); got != tt.want {
+			t.Errorf("%s: ctrlCQuits = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCtrlCInterruptsThenQuits(t *testing.T) {
+	m := newTestModel(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ui.showSpinner = true
+	m.stream.cancelFunc = cancel
+
+	m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if ctx.Err() == nil || m.ui.showSpinner {
+		t.Fatal("the first Ctrl+C didn't interrupt the response")
+	}
+	if m.ui.interruptedAt.IsZero() {
+		t.Error("the interrupt time wasn't recorded")
+	}
+
+	// The response may not have wound down before the second press
+	m.ui.showSpinner = true
+	cmd := m.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil {
+		t.Fatal("the second Ctrl+C returned no command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("the second Ctrl+C didn't quit")
+	}
+}
+
+func TestParseToolArgs(t *testing.T) {
+	args, err := parseToolArgs(`{"path": "main.go", "line": 3}`)
+	if err != nil || args["path"] != "main.go" || args["line"] != 3.0 {
+		t.Errorf("parseToolArgs(object) = %v, %v", args, err)
+	}
+	for _, text := range []string{`{"path": "main.go"`, `["main.go"]`, `null`, ``} {
+		if _, err := parseToolArgs(text); err == nil {
+			t.Errorf("parseToolArgs(%q) accepted arguments that aren't a JSON object", text)
+		}
+	}
+}
+
+func TestEditToolArgsThenRun(t *testing.T) {
+	m := newTestModel(t)
+	m.ui.toolConfirmationMode = true
+	m.ui.toolConfirmationName = "read_file"
+	m.ui.toolConfirmationArgs = map[string]interface{}{"path": "mian.go"}
+	m.ui.textarea.SetValue("half-typed message")
+
+	m.handleToolConfirmationKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	if response := <-m.stream.confirmationResponseChan; !response.editing {
+		t.Fatalf("editing sent %+v, want the agent told the user is editing", response)
+	}
+	if !m.ui.toolArgsEditMode || m.ui.textarea.Value() != "{\n  \"path\": \"mian.go\"\n}" {
+		t.Fatalf("edit mode = %v with input %q, want the arguments as JSON", m.ui.toolArgsEditMode, m.ui.textarea.Value())
+	}
+
+	// Invalid JSON is reported and leaves the user editing
+	m.ui.textarea.SetValue(`{"path": "main.go"`)
+	if _, used := m.handleToolArgsEditKey(tea.KeyMsg{Type: tea.KeyEnter}); !used {
+		t.Fatal("Enter wasn't handled while editing")
+	}
+	if last := m.messages[len(m.messages)-1]; !last.isError || !strings.Contains(last.content, "Invalid arguments for read_file") {
+		t.Errorf("last message = %+v, want the parse error", last)
+	}
+	if !m.ui.toolArgsEditMode || len(m.stream.confirmationResponseChan) != 0 {
+		t.Fatal("invalid arguments ended the edit or ran the tool")
+	}
+
+	m.ui.textarea.SetValue(`{"path": "main.go"}`)
+	m.handleToolArgsEditKey(tea.KeyMsg{Type: tea.KeyEnter})
+	response := <-m.stream.confirmationResponseChan
+	if !response.approved || response.args["path"] != "main.go" {
+		t.Errorf("response = %+v, want approval with the corrected path", response)
+	}
+	if m.ui.toolArgsEditMode || m.ui.textarea.Value() != "half-typed message" {
+		t.Errorf("after editing, edit mode = %v and input = %q, want the draft back", m.ui.toolArgsEditMode, m.ui.textarea.Value())
+	}
+}
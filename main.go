@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"log"
 	"os"
 
 	"agent/internal/agent"
@@ -12,6 +15,23 @@ import (
 )
 
 func main() {
+	printTools := flag.Bool("tools", false, "Print the registered tools and their input schemas as JSON, then exit")
+	debug := flag.Bool("debug", os.Getenv("AGENT_DEBUG") != "", "Log requests, tool calls and finish reasons to stderr (also enabled by AGENT_DEBUG)")
+	resume := flag.String("resume", os.Getenv("AGENT_RESUME"), "Resume a conversation saved with /save, e.g. "+config.DefaultSessionFile+" (also set by AGENT_RESUME)")
+	flag.Parse()
+
+	// Get all available tools
+	availableTools := tools.GetAllTools()
+
+	if *printTools {
+		data, err := json.MarshalIndent(availableTools, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to marshal tools: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
 
 	// Load configuration
 	cfg, err := config.Load()
@@ -28,10 +48,56 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Get all available tools
-	availableTools := tools.GetAllTools()
-
 	// Create and run the agent in TUI mode
-	tuiAgent := agent.New(client, cfg.Model, availableTools)
+	agentConfig := agent.DefaultAgentConfig()
+	applyGenerationParams(agentConfig, cfg.Generation)
+	prefs, err := config.LoadPreferences()
+	if err == nil && prefs != nil {
+		agentConfig.PostEditCommand = prefs.PostEditCommand
+		if prefs.MaxToolResultChars != nil {
+			agentConfig.MaxToolResultChars = *prefs.MaxToolResultChars
+		}
+		for name, limit := range prefs.ToolResultLimits {
+			agentConfig.ToolResultLimits[name] = limit
+		}
+	}
+	if prompt, _, err := config.LoadSystemPromptOverride(prefs); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s; using the built-in system prompt\n", err)
+	} else {
+		agentConfig.SystemPromptOverride = prompt
+	}
+	if *debug {
+		// The TUI owns stdout; redirect stderr to a file to keep the log, e.g. 2>agent.log
+		agentConfig.DebugLog = log.New(os.Stderr, "[agent] ", log.LstdFlags|log.Lmicroseconds)
+	}
+	tuiAgent := agent.NewWithConfig(client, cfg.Model, availableTools, agentConfig)
+	if *resume != "" {
+		if err := tuiAgent.LoadSession(*resume); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+	}
 	tui.Start(tuiAgent)
 }
+
+// applyGenerationParams overrides the agent's generation defaults with those set in the environment
+func applyGenerationParams(agentConfig *agent.AgentConfig, params config.GenerationParams) {
+	if params.Temperature != nil {
+		agentConfig.Temperature = *params.Temperature
+	}
+	if params.TopP != nil {
+		agentConfig.TopP = *params.TopP
+	}
+	if params.TopK != nil {
+		agentConfig.TopK = *params.TopK
+	}
+	if params.MaxOutputTokens != nil {
+		agentConfig.MaxOutputTokens = *params.MaxOutputTokens
+	}
+	if params.ThinkingBudget != nil {
+		agentConfig.ThinkingBudget = *params.ThinkingBudget
+	}
+	if params.MaxTotalTokens != nil {
+		agentConfig.MaxTotalTokens = int(*params.MaxTotalTokens)
+	}
+}